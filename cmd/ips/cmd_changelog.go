@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(changelogCmd)
+
+	// operate
+	changelogCmd.Flags().StringVarP(&dpFields, "fields", "f", "", UsageFields)
+	changelogCmd.Flags().StringVarP(&dpRewriterFiles, "rewrite-files", "r", "", UsageRewriteFiles)
+	changelogCmd.Flags().StringVarP(&lang, "lang", "", "", UsageLang)
+
+	// input & output
+	changelogCmd.Flags().StringSliceVarP(&inputFormat, "input-format", "", nil, UsageDiffInputFormat)
+	changelogCmd.Flags().StringVarP(&readerOption, "input-option", "", "", UsageReaderOption)
+	changelogCmd.Flags().StringVarP(&fieldAliasFile, "field-alias-file", "", "", UsageFieldAliasFile)
+	changelogCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", UsageChangelogOutput)
+	changelogCmd.Flags().StringVarP(&outputType, "output-type", "", "text", UsageChangelogType)
+	changelogCmd.Flags().StringVarP(&patchFile, "patch", "", "", UsageChangelogPatch)
+
+}
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog oldFile newFile [--input-format format,format] [-o outputFile] [--output-type text|json] [--patch patchFile]",
+	Short: "List the changes between two releases of an IP database",
+	Long: `The 'ips changelog' command compares two releases of an IP database range by range and lists what moved: ranges that were added, removed, reassigned to new values, split, merged or resized, with the ranges of both releases and the changes of the selected fields.
+
+Adjacent ranges with the same values are treated as one range, so a range stored differently by the two releases is not reported.
+
+With --patch, the changed ranges with the values of the new release are also written to a plain format file, which can be reviewed or packed into other formats. Ranges without data in the new release have empty values.
+
+For more detailed information and advanced configuration options, please refer to https://github.com/sjzar/ips/blob/main/docs/changelog.md
+`,
+	Example: `  # List the changes of a qqwry update
+  ips changelog qqwry_old.dat qqwry.dat
+
+  # Write the changes of the countries and ISPs as JSON, and the changed ranges as a patch
+  ips changelog GeoLite2-City_old.mmdb GeoLite2-City.mmdb --fields country,isp --output-type json -o changes.json --patch changes.txt`,
+	PreRun: PreRunInit,
+	Run:    Changelog,
+}
+
+func Changelog(cmd *cobra.Command, args []string) {
+
+	if len(args) != 2 {
+		_ = cmd.Help()
+		return
+	}
+
+	ctx, stop := SignalContext()
+	defer stop()
+
+	if err := manager.ChangelogContext(ctx, inputFormat, args, outputType, outputFile, patchFile); err != nil {
+		log.Fatal(err)
+	}
+}
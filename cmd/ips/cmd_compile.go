@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(compileCmd)
+
+	// operate
+	compileCmd.Flags().StringVarP(&fields, "fields", "f", "", UsageFields)
+	compileCmd.Flags().StringVarP(&rewriteFiles, "rewrite-files", "r", "", UsageRewriteFiles)
+	compileCmd.Flags().StringVarP(&lang, "lang", "", "", UsageLang)
+
+	// input & output
+	compileCmd.Flags().StringSliceVarP(&inputFile, "input-file", "i", nil, UsageDPInputFile)
+	compileCmd.Flags().StringSliceVarP(&inputFormat, "input-format", "", nil, UsageDPInputFormat)
+	compileCmd.Flags().StringVarP(&readerOption, "input-option", "", "", UsageReaderOption)
+	compileCmd.Flags().StringVarP(&fieldAliasFile, "field-alias-file", "", "", UsageFieldAliasFile)
+	compileCmd.Flags().StringVarP(&hybridMode, "hybrid-mode", "", "aggregation", UsageHybridMode)
+	compileCmd.Flags().StringVarP(&hybridPriority, "hybrid-priority", "", "", UsageHybridPriority)
+	compileCmd.Flags().StringVarP(&hybridWeights, "hybrid-weights", "", "", UsageHybridWeights)
+	compileCmd.Flags().StringVarP(&hybridTieBreak, "hybrid-tie-break", "", "", UsageHybridTieBreak)
+	compileCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", UsageCompileOutputFile)
+	compileCmd.Flags().IntVarP(&readerJobs, "reader-jobs", "", 0, UsageReaderJobs)
+
+}
+
+var compileCmd = &cobra.Command{
+	Use:   "compile -i inputFile [--input-format format] -o outputFile",
+	Short: "Compile IP database file into a memory snapshot",
+	Long: `The 'ips compile' command reads an IP database the same way as queries do, including field selection, data rewriting and translation, and saves the result as a compact memory snapshot (.ipsm).
+
+The snapshot is loaded entirely into memory as a flat index, so lookups are fast and predictable regardless of the original database format. Use it as a database file for queries and 'ips server'.
+
+For more detailed information and advanced configuration options, please refer to https://github.com/sjzar/ips/blob/main/docs/pack.md
+`,
+	Example: `  # Compile qqwry and zxinc databases into snapshots
+  ips compile -i qqwry.dat -o qqwry.ipsm
+  ips compile -i zxipv6wry.db -o zxipv6wry.ipsm
+
+  # Query with the snapshots
+  ips server --ipv4-file qqwry.ipsm --ipv6-file zxipv6wry.ipsm`,
+	PreRun: PreRunInit,
+	Run:    Compile,
+}
+
+func Compile(cmd *cobra.Command, args []string) {
+
+	if len(inputFile) == 0 || len(outputFile) == 0 {
+		_ = cmd.Help()
+		return
+	}
+
+	ctx, stop := SignalContext()
+	defer stop()
+
+	if err := manager.CompileContext(ctx, inputFormat, inputFile, outputFile); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	// operate
+	diffCmd.Flags().StringVarP(&dpFields, "fields", "f", "", UsageFields)
+	diffCmd.Flags().StringVarP(&dpRewriterFiles, "rewrite-files", "r", "", UsageRewriteFiles)
+	diffCmd.Flags().StringVarP(&lang, "lang", "", "", UsageLang)
+
+	// input & output
+	diffCmd.Flags().StringSliceVarP(&inputFormat, "input-format", "", nil, UsageDiffInputFormat)
+	diffCmd.Flags().StringVarP(&readerOption, "input-option", "", "", UsageReaderOption)
+	diffCmd.Flags().StringVarP(&fieldAliasFile, "field-alias-file", "", "", UsageFieldAliasFile)
+	diffCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", UsageDiffOutputFile)
+	diffCmd.Flags().StringVarP(&outputType, "output-type", "", "text", UsageDiffOutputType)
+
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff oldFile newFile [--input-format format,format] [-o outputFile] [--output-type text|csv|json]",
+	Short: "Compare two IP databases range by range",
+	Long: `The 'ips diff' command walks two IP databases of any formats over the whole address space, splits their ranges at the boundaries of both, and reports every range in which the selected fields differ, with the values of both databases.
+
+The summary counts the addresses with data in either database, the share of them whose values differ for each field, and the most frequent value changes of each field, e.g. the countries and ISPs the databases disagree on most.
+
+Text output lists the changed fields of each range followed by the summary, CSV output lists the ranges with the old and new values of all fields, and JSON output holds both the ranges and the summary.
+
+For more detailed information and advanced configuration options, please refer to https://github.com/sjzar/ips/blob/main/docs/diff.md
+`,
+	Example: `  # Compare two releases of qqwry
+  ips diff qqwry_old.dat qqwry.dat
+
+  # Compare the countries and ISPs of two databases as CSV
+  ips diff qqwry.dat ip2region.xdb --fields country,isp --output-type csv -o diff.csv`,
+	PreRun: PreRunInit,
+	Run:    Diff,
+}
+
+func Diff(cmd *cobra.Command, args []string) {
+
+	if len(args) != 2 {
+		_ = cmd.Help()
+		return
+	}
+
+	ctx, stop := SignalContext()
+	defer stop()
+
+	if err := manager.DiffContext(ctx, inputFormat, args, outputType, outputFile); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -29,13 +29,20 @@ func init() {
 	// operate
 	dumpCmd.Flags().StringVarP(&dpFields, "fields", "f", "", UsageDPFields)
 	dumpCmd.Flags().StringVarP(&dpRewriterFiles, "rewrite-files", "r", "", UsageRewriteFiles)
+	dumpCmd.Flags().BoolVarP(&dpSources, "sources", "", false, UsageDPSources)
 	dumpCmd.Flags().StringVarP(&lang, "lang", "", "", UsageLang)
+	dumpCmd.Flags().StringVarP(&dpOverlay, "overlay", "", "", UsageOverlay)
+	dumpCmd.Flags().StringVarP(&dpOverlayFields, "overlay-fields", "", "", UsageOverlayFields)
 
 	// input & output
 	dumpCmd.Flags().StringSliceVarP(&inputFile, "input-file", "i", nil, UsageDPInputFile)
 	dumpCmd.Flags().StringSliceVarP(&inputFormat, "input-format", "", nil, UsageDPInputFormat)
 	dumpCmd.Flags().StringVarP(&readerOption, "input-option", "", "", UsageReaderOption)
+	dumpCmd.Flags().StringVarP(&fieldAliasFile, "field-alias-file", "", "", UsageFieldAliasFile)
 	dumpCmd.Flags().StringVarP(&hybridMode, "hybrid-mode", "", "aggregation", UsageHybridMode)
+	dumpCmd.Flags().StringVarP(&hybridPriority, "hybrid-priority", "", "", UsageHybridPriority)
+	dumpCmd.Flags().StringVarP(&hybridWeights, "hybrid-weights", "", "", UsageHybridWeights)
+	dumpCmd.Flags().StringVarP(&hybridTieBreak, "hybrid-tie-break", "", "", UsageHybridTieBreak)
 	dumpCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", UsageDumpOutputFile)
 	dumpCmd.Flags().IntVarP(&readerJobs, "reader-jobs", "", 0, UsageReaderJobs)
 
@@ -68,7 +75,10 @@ func Dump(cmd *cobra.Command, args []string) {
 		inputFile = []string{args[0]}
 	}
 
-	if err := manager.Pack(inputFormat, inputFile, plain.DBFormat, outputFile); err != nil {
+	ctx, stop := SignalContext()
+	defer stop()
+
+	if err := manager.PackContext(ctx, inputFormat, inputFile, plain.DBFormat, outputFile); err != nil {
 		log.Fatal(err)
 	}
 }
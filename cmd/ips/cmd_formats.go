@@ -0,0 +1,173 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/format/option"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+var (
+	// formatsOptions defines whether to list the options of the formats.
+	formatsOptions bool
+)
+
+func init() {
+	rootCmd.AddCommand(formatsCmd)
+	formatsCmd.Flags().BoolVarP(&formatsOptions, "options", "", false, "List the reader and writer options of each format.")
+}
+
+var formatsCmd = &cobra.Command{
+	Use:   "formats [format...] [--options]",
+	Short: "List supported IP database formats",
+	Long: `The 'ips formats' command lists the supported IP database formats, including formats registered by third-party code, with whether they can be read or written, their file extensions, common file names and IP versions.
+
+With format names, it shows the details of the formats, including their native fields, the aliases from common fields to native fields and their options.
+
+With --options, it lists the options of each format, which are passed with --database-option / --input-option for readers and --output-option for writers in URL query format, e.g. "mmap=true".
+`,
+	Example: `  # List formats
+  ips formats
+
+  # Show the details of a format
+  ips formats mmdb
+
+  # List format options
+  ips formats --options`,
+	Run: Formats,
+}
+
+func Formats(cmd *cobra.Command, args []string) {
+	formats := format.Formats()
+	if len(args) > 0 {
+		selected := make([]format.Descriptor, 0, len(args))
+		for _, name := range args {
+			found := false
+			for _, d := range formats {
+				if d.Name == name {
+					selected = append(selected, d)
+					found = true
+					break
+				}
+			}
+			if !found {
+				log.Fatal(errors.ErrUnsupportedFormat, ": ", name)
+			}
+		}
+		formats = selected
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+
+	switch {
+	case formatsOptions:
+		table.SetHeader([]string{"Format", "Side", "Option", "Type", "Default", "Description"})
+		for _, d := range formats {
+			for _, spec := range d.ReaderOptions {
+				table.Append([]string{d.Name, "reader", spec.Name, spec.Type, spec.Default, spec.Description})
+			}
+			for _, spec := range d.WriterOptions {
+				table.Append([]string{d.Name, "writer", spec.Name, spec.Type, spec.Default, spec.Description})
+			}
+		}
+		if table.NumLines() == 0 {
+			fmt.Println("no format options")
+			return
+		}
+	case len(args) > 0:
+		for i, d := range formats {
+			if i > 0 {
+				table.Append([]string{"", ""})
+			}
+			table.AppendBulk([][]string{
+				{"Format", d.Name},
+				{"Read", yesNo(d.Read)},
+				{"Write", yesNo(d.Write)},
+				{"Extensions", strings.Join(d.Exts, ",")},
+				{"Common Names", strings.Join(d.CommonNames, ",")},
+				{"IP Version", ipVersionText(d.IPVersion)},
+				{"Fields", strings.Join(d.Fields, ",")},
+				{"Field Alias", fieldAliasText(d.FieldAlias)},
+				{"Reader Options", optionNames(d.ReaderOptions)},
+				{"Writer Options", optionNames(d.WriterOptions)},
+			})
+		}
+	default:
+		table.SetHeader([]string{"Format", "Read", "Write", "Extensions", "Common Names", "IP Version"})
+		for _, d := range formats {
+			name := d.Name
+			if name == "" {
+				name = "-"
+			}
+			table.Append([]string{name, yesNo(d.Read), yesNo(d.Write), strings.Join(d.Exts, ","), strings.Join(d.CommonNames, ","), ipVersionText(d.IPVersion)})
+		}
+	}
+	table.Render()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// ipVersionText returns the IP versions of a format, a zero version depends on the database.
+func ipVersionText(ipVersion int) string {
+	if ipVersion == 0 {
+		return "database"
+	}
+	versions := make([]string, 0, 2)
+	if ipVersion&model.IPv4 != 0 {
+		versions = append(versions, "IPv4")
+	}
+	if ipVersion&model.IPv6 != 0 {
+		versions = append(versions, "IPv6")
+	}
+	return strings.Join(versions, ",")
+}
+
+// fieldAliasText returns the field aliases in the order of model.CommonFields, e.g. "country=country_name".
+func fieldAliasText(alias map[string]string) string {
+	pairs := make([]string, 0, len(alias))
+	for _, field := range model.CommonFields {
+		if dbField, ok := alias[field]; ok {
+			pairs = append(pairs, field+"="+dbField)
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// optionNames returns the names of the options.
+func optionNames(specs option.Specs) string {
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+	return strings.Join(names, ",")
+}
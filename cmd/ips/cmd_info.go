@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+
+	infoCmd.Flags().StringSliceVarP(&inputFormat, "input-format", "", nil, UsageDPInputFormat)
+	infoCmd.Flags().StringVarP(&readerOption, "input-option", "", "", UsageReaderOption)
+	infoCmd.Flags().StringVarP(&fieldAliasFile, "field-alias-file", "", "", UsageFieldAliasFile)
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info <database> [database...] [--input-format]",
+	Short: "Show the metadata of IP database files",
+	Long: `The 'ips info' command shows the metadata of IP database files, including the format, IP version, fields, build time, version, description, languages, source and license.
+
+Multiple database files are combined as in 'ips dump', showing the newest build time and the metadata of all files.
+`,
+	Example: `  # Show the metadata of an IP database file
+  ips info qqwry.dat
+
+  # Show the metadata of an IP database file in a specific format
+  ips info --input-format ipdb city.free.ipdb`,
+	PreRun: PreRunInit,
+	Run:    Info,
+}
+
+func Info(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		_ = cmd.Help()
+		return
+	}
+
+	text, err := manager.InfoText(inputFormat, args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(text)
+}
@@ -47,7 +47,11 @@ func init() {
 	mdnsCmd.Flags().StringSliceVarP(&rootIPv6File, "ipv6-file", "", nil, UsageQueryIPv6File)
 	mdnsCmd.Flags().StringSliceVarP(&rootIPv6Format, "ipv6-format", "", nil, UsageQueryIPv6Format)
 	mdnsCmd.Flags().StringVarP(&readerOption, "database-option", "", "", UsageReaderOption)
+	mdnsCmd.Flags().StringVarP(&fieldAliasFile, "field-alias-file", "", "", UsageFieldAliasFile)
 	mdnsCmd.Flags().StringVarP(&hybridMode, "hybrid-mode", "", "aggregation", UsageHybridMode)
+	mdnsCmd.Flags().StringVarP(&hybridPriority, "hybrid-priority", "", "", UsageHybridPriority)
+	mdnsCmd.Flags().StringVarP(&hybridWeights, "hybrid-weights", "", "", UsageHybridWeights)
+	mdnsCmd.Flags().StringVarP(&hybridTieBreak, "hybrid-tie-break", "", "", UsageHybridTieBreak)
 
 	// output
 	mdnsCmd.Flags().StringVarP(&rootTextValuesSep, "text-values-sep", "", "", UsageTextValuesSep)
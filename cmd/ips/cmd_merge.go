@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+
+	// operate
+	mergeCmd.Flags().StringVarP(&dpFields, "fields", "f", "", UsageDPFields)
+	mergeCmd.Flags().StringVarP(&dpRewriterFiles, "rewrite-files", "r", "", UsageRewriteFiles)
+	mergeCmd.Flags().StringVarP(&lang, "lang", "", "", UsageLang)
+	mergeCmd.Flags().StringVarP(&dpOverlay, "overlay", "", "", UsageOverlay)
+	mergeCmd.Flags().StringVarP(&dpOverlayFields, "overlay-fields", "", "", UsageOverlayFields)
+
+	// input & output
+	mergeCmd.Flags().StringSliceVarP(&inputFormat, "input-format", "", nil, UsageMergeInputFormat)
+	mergeCmd.Flags().StringVarP(&readerOption, "input-option", "", "", UsageReaderOption)
+	mergeCmd.Flags().StringVarP(&fieldAliasFile, "field-alias-file", "", "", UsageFieldAliasFile)
+	mergeCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", UsagePackOutputFile)
+	mergeCmd.Flags().StringVarP(&outputFormat, "output-format", "", "", UsagePackOutputFormat)
+	mergeCmd.Flags().StringVarP(&writerOption, "output-option", "", "", UsageWriterOption)
+	mergeCmd.Flags().IntVarP(&readerJobs, "reader-jobs", "", 0, UsageReaderJobs)
+
+}
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge file file... -o outputFile [--input-format format,format...] [--output-format format]",
+	Short: "Merge IP databases of different address families into one",
+	Long: `The 'ips merge' command combines IP databases covering different address families or disjoint ranges, e.g. an IPv4 and an IPv6 database, into one dual-stack IP database.
+
+Unlike reading several databases with 'ips pack', which combines the values of databases covering the same addresses, the ranges of the merged databases are taken as they are. Databases supporting only IPv6 are not read in the IPv4 address space, and merging fails if ranges of the databases overlap.
+
+The fields of the databases are unified by their common field names, e.g. the 'area' field of qqwry becomes 'isp'. Fields a database does not have are left empty in its ranges.
+
+For more detailed information and advanced configuration options, please refer to https://github.com/sjzar/ips/blob/main/docs/merge.md
+`,
+	Example: `  # Merge an IPv4 and an IPv6 database into one ipdb database
+  ips merge qqwry.dat zxipv6wry.db -o dual.ipdb
+
+  # Merge the countries and ISPs into an mmdb database
+  ips merge qqwry.dat zxipv6wry.db -o dual.mmdb --fields country,isp`,
+	PreRun: PreRunInit,
+	Run:    Merge,
+}
+
+func Merge(cmd *cobra.Command, args []string) {
+
+	if len(args) < 2 || len(outputFile) == 0 {
+		_ = cmd.Help()
+		return
+	}
+
+	ctx, stop := SignalContext()
+	defer stop()
+
+	if err := manager.MergeContext(ctx, inputFormat, args, outputFormat, outputFile); err != nil {
+		log.Fatal(err)
+	}
+}
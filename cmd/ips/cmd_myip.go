@@ -45,6 +45,9 @@ func init() {
 	myipCmd.Flags().StringSliceVarP(&rootIPv6Format, "ipv6-format", "", nil, UsageQueryIPv6Format)
 	myipCmd.Flags().StringVarP(&readerOption, "database-option", "", "", UsageReaderOption)
 	myipCmd.Flags().StringVarP(&hybridMode, "hybrid-mode", "", "aggregation", UsageHybridMode)
+	myipCmd.Flags().StringVarP(&hybridPriority, "hybrid-priority", "", "", UsageHybridPriority)
+	myipCmd.Flags().StringVarP(&hybridWeights, "hybrid-weights", "", "", UsageHybridWeights)
+	myipCmd.Flags().StringVarP(&hybridTieBreak, "hybrid-tie-break", "", "", UsageHybridTieBreak)
 
 	// output
 	myipCmd.Flags().StringVarP(&rootTextFormat, "text-format", "", "", UsageTextFormat)
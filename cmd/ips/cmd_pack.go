@@ -28,12 +28,18 @@ func init() {
 	packCmd.Flags().StringVarP(&dpFields, "fields", "f", "", UsageDPFields)
 	packCmd.Flags().StringVarP(&dpRewriterFiles, "rewrite-files", "r", "", UsageRewriteFiles)
 	packCmd.Flags().StringVarP(&lang, "lang", "", "", UsageLang)
+	packCmd.Flags().StringVarP(&dpOverlay, "overlay", "", "", UsageOverlay)
+	packCmd.Flags().StringVarP(&dpOverlayFields, "overlay-fields", "", "", UsageOverlayFields)
 
 	// input & output
 	packCmd.Flags().StringSliceVarP(&inputFile, "input-file", "i", nil, UsageDPInputFile)
 	packCmd.Flags().StringSliceVarP(&inputFormat, "input-format", "", nil, UsageDPInputFormat)
 	packCmd.Flags().StringVarP(&readerOption, "input-option", "", "", UsageReaderOption)
+	packCmd.Flags().StringVarP(&fieldAliasFile, "field-alias-file", "", "", UsageFieldAliasFile)
 	packCmd.Flags().StringVarP(&hybridMode, "hybrid-mode", "", "aggregation", UsageHybridMode)
+	packCmd.Flags().StringVarP(&hybridPriority, "hybrid-priority", "", "", UsageHybridPriority)
+	packCmd.Flags().StringVarP(&hybridWeights, "hybrid-weights", "", "", UsageHybridWeights)
+	packCmd.Flags().StringVarP(&hybridTieBreak, "hybrid-tie-break", "", "", UsageHybridTieBreak)
 	packCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", UsagePackOutputFile)
 	packCmd.Flags().StringVarP(&outputFormat, "output-format", "", "", UsagePackOutputFormat)
 	packCmd.Flags().StringVarP(&writerOption, "output-option", "", "", UsageWriterOption)
@@ -61,7 +67,10 @@ func Pack(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	if err := manager.Pack(inputFormat, inputFile, outputFormat, outputFile); err != nil {
+	ctx, stop := SignalContext()
+	defer stop()
+
+	if err := manager.PackContext(ctx, inputFormat, inputFile, outputFormat, outputFile); err != nil {
 		log.Fatal(err)
 	}
 }
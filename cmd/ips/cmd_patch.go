@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	CmdCreate = "create"
+	CmdApply  = "apply"
+)
+
+func init() {
+	rootCmd.AddCommand(patchCmd)
+
+	// operate
+	patchCmd.Flags().StringVarP(&dpFields, "fields", "f", "", UsageFields)
+	patchCmd.Flags().StringVarP(&dpRewriterFiles, "rewrite-files", "r", "", UsageRewriteFiles)
+	patchCmd.Flags().StringVarP(&lang, "lang", "", "", UsageLang)
+
+	// input & output
+	patchCmd.Flags().StringSliceVarP(&inputFormat, "input-format", "", nil, UsagePatchInputFormat)
+	patchCmd.Flags().StringVarP(&readerOption, "input-option", "", "", UsageReaderOption)
+	patchCmd.Flags().StringVarP(&fieldAliasFile, "field-alias-file", "", "", UsageFieldAliasFile)
+	patchCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", UsagePatchOutputFile)
+	patchCmd.Flags().StringVarP(&outputFormat, "output-format", "", "", UsagePatchOutputFormat)
+	patchCmd.Flags().StringVarP(&writerOption, "output-option", "", "", UsageWriterOption)
+	patchCmd.Flags().IntVarP(&readerJobs, "reader-jobs", "", 0, UsageReaderJobs)
+
+}
+
+var patchCmd = &cobra.Command{
+	Use:   "patch [create oldFile newFile -o patchFile] [apply baseFile patchFile -o outputFile]",
+	Short: "Create and apply incremental updates of an IP database",
+	Long: `The 'ips patch' command updates an IP database without distributing the new release as a whole.
+
+Use 'create' to write the ranges that changed between two releases of a database, with the values of the selected fields in the new release, to a patch file. Use 'apply' to apply the patch to the old release and write the new release in any output format.
+
+The patch carries checksums of the data of both releases, which do not depend on the formats of the databases. 'apply' refuses a base database that is not the old release, and only writes the output file if its data matches the new release. The base database must be read with the same rewrite files and language as the old release when the patch was created.
+
+For more detailed information and advanced configuration options, please refer to https://github.com/sjzar/ips/blob/main/docs/patch.md
+`,
+	Example: `  # Create a patch from the old to the new release
+  ips patch create old.ipdb new.ipdb -o update.ipsp
+
+  # Apply the patch to the old release on another host
+  ips patch apply old.ipdb update.ipsp -o new.ipdb`,
+	PreRun: PreRunInit,
+	Run:    Patch,
+}
+
+func Patch(cmd *cobra.Command, args []string) {
+
+	if len(args) != 3 {
+		_ = cmd.Help()
+		return
+	}
+
+	ctx, stop := SignalContext()
+	defer stop()
+
+	var err error
+	switch args[0] {
+	case CmdCreate:
+		err = manager.CreatePatchContext(ctx, inputFormat, args[1:], outputFile)
+	case CmdApply:
+		var baseFormat string
+		if len(inputFormat) > 0 {
+			baseFormat = inputFormat[0]
+		}
+		err = manager.ApplyPatchContext(ctx, baseFormat, args[1], args[2], outputFormat, outputFile)
+	default:
+		_ = cmd.Help()
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
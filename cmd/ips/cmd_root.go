@@ -19,11 +19,14 @@ package ips
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path"
 	"runtime"
 	"strings"
+	"syscall"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -44,6 +47,8 @@ func init() {
 	rootCmd.Flags().BoolVarP(&useDBFields, "use-db-fields", "", false, UsageUseDBFields)
 	rootCmd.Flags().StringVarP(&rewriteFiles, "rewrite-files", "r", "", UsageRewriteFiles)
 	rootCmd.Flags().StringVarP(&lang, "lang", "", "", UsageLang)
+	rootCmd.Flags().StringVarP(&overlay, "overlay", "", "", UsageOverlay)
+	rootCmd.Flags().StringVarP(&overlayFields, "overlay-fields", "", "", UsageOverlayFields)
 
 	// database
 	rootCmd.Flags().StringSliceVarP(&rootFile, "file", "i", nil, UsageQueryFile)
@@ -53,7 +58,14 @@ func init() {
 	rootCmd.Flags().StringSliceVarP(&rootIPv6File, "ipv6-file", "", nil, UsageQueryIPv6File)
 	rootCmd.Flags().StringSliceVarP(&rootIPv6Format, "ipv6-format", "", nil, UsageQueryIPv6Format)
 	rootCmd.Flags().StringVarP(&readerOption, "database-option", "", "", UsageReaderOption)
+	rootCmd.Flags().StringVarP(&fieldAliasFile, "field-alias-file", "", "", UsageFieldAliasFile)
 	rootCmd.Flags().StringVarP(&hybridMode, "hybrid-mode", "", "aggregation", UsageHybridMode)
+	rootCmd.Flags().StringVarP(&hybridPriority, "hybrid-priority", "", "", UsageHybridPriority)
+	rootCmd.Flags().StringVarP(&hybridWeights, "hybrid-weights", "", "", UsageHybridWeights)
+	rootCmd.Flags().StringVarP(&hybridTieBreak, "hybrid-tie-break", "", "", UsageHybridTieBreak)
+	rootCmd.Flags().IntVarP(&hybridTimeoutMs, "hybrid-timeout", "", 0, UsageHybridTimeout)
+	rootCmd.Flags().IntVarP(&cacheSize, "cache-size", "", 0, UsageCacheSize)
+	rootCmd.Flags().IntVarP(&cacheTTLS, "cache-ttl", "", 0, UsageCacheTTL)
 
 	// output
 	rootCmd.Flags().StringVarP(&rootTextFormat, "text-format", "", "", UsageTextFormat)
@@ -157,6 +169,12 @@ func PreRunInit(cmd *cobra.Command, args []string) {
 	manager = ips.NewManager(conf)
 }
 
+// SignalContext returns a context that is cancelled on Ctrl-C or SIGTERM,
+// so long-running commands can stop cleanly.
+func SignalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
 // ScanLines scan lines but keep the suffix \r and \n
 func ScanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	if atEOF && len(data) == 0 {
@@ -39,7 +39,14 @@ func init() {
 	serverCmd.Flags().StringSliceVarP(&rootIPv6File, "ipv6-file", "", nil, UsageQueryIPv6File)
 	serverCmd.Flags().StringSliceVarP(&rootIPv6Format, "ipv6-format", "", nil, UsageQueryIPv6Format)
 	serverCmd.Flags().StringVarP(&readerOption, "database-option", "", "", UsageReaderOption)
+	serverCmd.Flags().StringVarP(&fieldAliasFile, "field-alias-file", "", "", UsageFieldAliasFile)
 	serverCmd.Flags().StringVarP(&hybridMode, "hybrid-mode", "", "aggregation", UsageHybridMode)
+	serverCmd.Flags().StringVarP(&hybridPriority, "hybrid-priority", "", "", UsageHybridPriority)
+	serverCmd.Flags().StringVarP(&hybridWeights, "hybrid-weights", "", "", UsageHybridWeights)
+	serverCmd.Flags().StringVarP(&hybridTieBreak, "hybrid-tie-break", "", "", UsageHybridTieBreak)
+	serverCmd.Flags().IntVarP(&hybridTimeoutMs, "hybrid-timeout", "", 0, UsageHybridTimeout)
+	serverCmd.Flags().IntVarP(&cacheSize, "cache-size", "", 0, UsageCacheSize)
+	serverCmd.Flags().IntVarP(&cacheTTLS, "cache-ttl", "", 0, UsageCacheTTL)
 
 }
 
@@ -49,6 +49,12 @@ var (
 	// rewriteFiles specifies the files for data rewriting.
 	rewriteFiles string
 
+	// overlay specifies the file with ranges overriding the database values.
+	overlay string
+
+	// overlayFields specifies the fields of the overlay file to apply.
+	overlayFields string
+
 	// lang specifies the language for the output.
 	lang string
 
@@ -75,6 +81,18 @@ var (
 	// hybridMode specifies the operational mode of the HybridReader.
 	hybridMode string
 
+	// hybridPriority maps fields to the indexes of the databases they are taken from in aggregation mode.
+	hybridPriority string
+
+	// hybridWeights lists the voting weights of the databases in consensus mode.
+	hybridWeights string
+
+	// hybridTieBreak specifies how ties are broken in consensus mode.
+	hybridTieBreak string
+
+	// hybridTimeoutMs limits the lookup time (in milliseconds) of each database of the HybridReader.
+	hybridTimeoutMs int
+
 	// output
 	// rootTextFormat defines the format for text output.
 	rootTextFormat string
@@ -99,6 +117,15 @@ var (
 	// dpRewriterFiles specifies the files for data rewriting during dump and pack operations.
 	dpRewriterFiles string
 
+	// dpSources specifies whether to add the sources of the fields during dump and pack operations.
+	dpSources bool
+
+	// dpOverlay specifies the file with ranges overriding the database values during dump and pack operations.
+	dpOverlay string
+
+	// dpOverlayFields specifies the fields of the overlay file to apply during dump and pack operations.
+	dpOverlayFields string
+
 	// inputFile specifies the input file for dump and pack operations.
 	inputFile []string
 
@@ -111,6 +138,12 @@ var (
 	// outputFormat specifies the output format for pack operations.
 	outputFormat string
 
+	// outputType specifies the output type for diff and changelog operations.
+	outputType string
+
+	// patchFile specifies the patch file for changelog operations.
+	patchFile string
+
 	// database
 	// readerOption specifies the options for the reader.
 	readerOption string
@@ -118,9 +151,18 @@ var (
 	// writerOption specifies the options for the writer.
 	writerOption string
 
+	// fieldAliasFile specifies the file mapping database fields to common fields.
+	fieldAliasFile string
+
 	// readerJobs specifies the number of concurrent reader jobs.
 	readerJobs int
 
+	// cacheSize specifies the number of IP ranges cached for queries.
+	cacheSize int
+
+	// cacheTTLS specifies the lifetime (in seconds) of the cached IP ranges.
+	cacheTTLS int
+
 	// myip
 	// localAddr specifies the local address (in IP format) that should be used for outbound connections.
 	// Useful in systems with multiple network interfaces.
@@ -179,6 +221,14 @@ func GetFlagConfig() *ips.Config {
 		conf.RewriteFiles = rewriteFiles
 	}
 
+	if len(overlay) != 0 {
+		conf.Overlay = overlay
+	}
+
+	if len(overlayFields) != 0 {
+		conf.OverlayFields = overlayFields
+	}
+
 	if len(lang) != 0 {
 		conf.Lang = lang
 	}
@@ -223,6 +273,22 @@ func GetFlagConfig() *ips.Config {
 		conf.HybridMode = hybridMode
 	}
 
+	if len(hybridPriority) != 0 {
+		conf.HybridPriority = hybridPriority
+	}
+
+	if len(hybridWeights) != 0 {
+		conf.HybridWeights = hybridWeights
+	}
+
+	if len(hybridTieBreak) != 0 {
+		conf.HybridTieBreak = hybridTieBreak
+	}
+
+	if hybridTimeoutMs != 0 {
+		conf.HybridTimeoutMs = hybridTimeoutMs
+	}
+
 	if len(rootTextFormat) != 0 {
 		conf.TextFormat = rootTextFormat
 	}
@@ -253,6 +319,18 @@ func GetFlagConfig() *ips.Config {
 		conf.DPRewriterFiles = dpRewriterFiles
 	}
 
+	if dpSources {
+		conf.DPSources = dpSources
+	}
+
+	if len(dpOverlay) != 0 {
+		conf.DPOverlay = dpOverlay
+	}
+
+	if len(dpOverlayFields) != 0 {
+		conf.DPOverlayFields = dpOverlayFields
+	}
+
 	if len(readerOption) != 0 {
 		conf.ReaderOption = readerOption
 	}
@@ -261,10 +339,22 @@ func GetFlagConfig() *ips.Config {
 		conf.WriterOption = writerOption
 	}
 
+	if len(fieldAliasFile) != 0 {
+		conf.FieldAliasFile = fieldAliasFile
+	}
+
 	if readerJobs != 0 {
 		conf.ReaderJobs = readerJobs
 	}
 
+	if cacheSize != 0 {
+		conf.CacheSize = cacheSize
+	}
+
+	if cacheTTLS != 0 {
+		conf.CacheTTLS = cacheTTLS
+	}
+
 	if len(addr) != 0 {
 		conf.Addr = addr
 	}
@@ -24,29 +24,50 @@ const (
 
 	// Operate Flags
 
-	UsageLang         = "Language for output data. (default \"zh-CN\")"
-	UsageFields       = "Fields to include in the output, separated by commas. (default \"country,province,city,isp\")"
-	UsageUseDBFields  = "Use field names as they appear in the database. Default is common field names."
-	UsageRewriteFiles = "Paths to files containing data rewrite rules, separated by commas."
-	UsageDPFields     = "Fields to extract from the database. Defaults to all available fields."
+	UsageLang          = "Language for output data. (default \"zh-CN\")"
+	UsageFields        = "Fields to include in the output, separated by commas. (default \"country,province,city,isp\")"
+	UsageUseDBFields   = "Use field names as they appear in the database. Default is common field names."
+	UsageRewriteFiles  = "Paths to files containing data rewrite rules, separated by commas."
+	UsageDPFields      = "Fields to extract from the database. Defaults to all available fields."
+	UsageDPSources     = "Add the source database of each field as a '<field>_source' column when merging multiple databases."
+	UsageOverlay       = "Path to a plain format file with IP ranges whose values take precedence over the database."
+	UsageOverlayFields = "Fields of the overlay file to apply, separated by commas. Defaults to all fields of the overlay file."
 
 	// Database Flags
 
-	UsageQueryFile        = "Path to the combined IPv4/IPv6 database file."
-	UsageQueryFormat      = "The format of the IPv4/IPv6 database file."
-	UsageQueryIPv4File    = "Path to the IPv4 database file."
-	UsageQueryIPv4Format  = "The format of the IPv4 database file."
-	UsageQueryIPv6File    = "Path to the IPv6 database file."
-	UsageQueryIPv6Format  = "The format of the IPv6 database file."
-	UsageDPInputFile      = "Path to the input IP database file (required)."
-	UsageDPInputFormat    = "The format of the input IP database file."
-	UsageDumpOutputFile   = "Destination path for the dumped data. Defaults to standard output if not specified."
-	UsagePackOutputFile   = "Path to the output IP database file (required)."
-	UsagePackOutputFormat = "The format for the output IP database file."
-	UsageReaderOption     = "Additional options for the database reader, if applicable."
-	UsageWriterOption     = "Additional options for the database writer, if applicable."
-	UsageHybridMode       = "Sets mode for multi-IP source handling; 'comparison' to compare, 'aggregation' to merge data."
-	UsageReaderJobs       = "Set the number of concurrent reader jobs. This parameter controls the parallelism level of reading operations."
+	UsageQueryFile         = "Path to the combined IPv4/IPv6 database file."
+	UsageQueryFormat       = "The format of the IPv4/IPv6 database file."
+	UsageQueryIPv4File     = "Path to the IPv4 database file."
+	UsageQueryIPv4Format   = "The format of the IPv4 database file."
+	UsageQueryIPv6File     = "Path to the IPv6 database file."
+	UsageQueryIPv6Format   = "The format of the IPv6 database file."
+	UsageDPInputFile       = "Path to the input IP database file (required)."
+	UsageDPInputFormat     = "The format of the input IP database file."
+	UsageDumpOutputFile    = "Destination path for the dumped data. Defaults to standard output if not specified."
+	UsagePackOutputFile    = "Path to the output IP database file (required)."
+	UsageCompileOutputFile = "Path to the output memory snapshot file (required)."
+	UsageDiffInputFormat   = "The formats of the old and the new IP database files, separated by commas."
+	UsageDiffOutputFile    = "Destination path for the differences. Defaults to standard output if not specified."
+	UsageDiffOutputType    = "Output type of the differences; 'text', 'csv' or 'json'."
+	UsageChangelogOutput   = "Destination path for the changelog. Defaults to standard output if not specified."
+	UsageChangelogType     = "Output type of the changelog; 'text' or 'json'."
+	UsageChangelogPatch    = "Path to a plain format patch file with the changed ranges and their new values."
+	UsagePatchInputFormat  = "The formats of the old and the new IP database files for 'create', or of the base IP database file for 'apply', separated by commas."
+	UsagePatchOutputFile   = "Path to the patch file for 'create', defaults to standard output, or to the output IP database file for 'apply' (required)."
+	UsagePatchOutputFormat = "The format for the output IP database file of 'apply'."
+	UsagePackOutputFormat  = "The format for the output IP database file."
+	UsageMergeInputFormat  = "The formats of the IP database files to merge, separated by commas."
+	UsageReaderOption      = "Additional options for the database reader, if applicable."
+	UsageWriterOption      = "Additional options for the database writer, if applicable."
+	UsageFieldAliasFile    = "Path to the file mapping database fields to common fields."
+	UsageHybridMode        = "Sets mode for multi-IP source handling; 'comparison' to compare, 'aggregation' to merge data, 'consensus' to vote on each field, 'cascade' to stop at the first complete answer."
+	UsageHybridPriority    = "Databases of each field in aggregation and cascade mode, as field=indexes separated by semicolons, e.g. 'country=1,0;isp=2,0'."
+	UsageHybridWeights     = "Voting weights of the databases in consensus mode, separated by commas. Default weight is 1."
+	UsageHybridTieBreak    = "Tie-breaking rule in consensus mode; 'first' picks the value of the first database, 'empty' leaves the field empty."
+	UsageHybridTimeout     = "Lookup timeout in milliseconds of each database in multi-IP source mode. Databases that time out are left out."
+	UsageCacheSize         = "Number of IP ranges to cache for queries. Every cached range answers all IPs in it."
+	UsageCacheTTL          = "Lifetime of the cached IP ranges in seconds. Default is no expiration."
+	UsageReaderJobs        = "Set the number of concurrent reader jobs. This parameter controls the parallelism level of reading operations."
 
 	// Output Flags
 
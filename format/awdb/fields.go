@@ -117,8 +117,13 @@ var CommonFieldsAlias = map[string]string{
 	model.ISP:            FieldISP,
 	model.Continent:      FieldContinent,
 	model.UTCOffset:      FieldTimeZone,
+	model.TimeZone:       FieldTimeZone,
 	model.Latitude:       FieldLatwgs,
 	model.Longitude:      FieldLngwgs,
 	model.ChinaAdminCode: FieldAdcode,
 	model.ASN:            FieldASNumber,
+	model.CountryCode:    FieldAreaCode,
+	model.PostalCode:     FieldZipCode,
+	model.AccuracyRadius: FieldRadius,
+	model.ASOrganization: FieldOwner,
 }
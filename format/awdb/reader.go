@@ -18,16 +18,21 @@ package awdb
 
 import (
 	"net"
+	"time"
 
 	"github.com/dilfish/awdb-golang/awdb-golang"
 
 	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
 	"github.com/sjzar/ips/pkg/model"
 )
 
 const (
 	DBFormat = "awdb"
 	DBExt    = ".awdb"
+
+	// Source is the provider of AWDB databases.
+	Source = "ipplus360.com"
 )
 
 // Reader is a structure that provides functionalities to read from AWDB IP database.
@@ -50,6 +55,13 @@ func NewReader(file string) (*Reader, error) {
 	}
 	meta.AddCommonFieldAlias(CommonFieldsAlias)
 
+	if db.Metadata.BuildEpoch > 0 {
+		meta.BuildTime = time.Unix(int64(db.Metadata.BuildEpoch), 0).In(time.UTC)
+	}
+	meta.Description = model.PickDescription(db.Metadata.Description, db.Metadata.DatabaseType)
+	meta.Languages = db.Metadata.Languages
+	meta.Source = Source
+
 	if db.Metadata.IPVersion == 4 {
 		meta.IPVersion |= model.IPv4
 	}
@@ -72,10 +84,13 @@ func (r *Reader) Meta() *model.Meta {
 func (r *Reader) Find(ip net.IP) (*model.IPInfo, error) {
 
 	var record interface{}
-	ipNet, _, err := r.db.LookupNetwork(ip, &record)
+	ipNet, ok, err := r.db.LookupNetwork(ip, &record)
 	if err != nil {
 		return nil, err
 	}
+	if !ok {
+		return nil, errors.NewNotFoundError(ip, ipnet.NewRange(ipNet))
+	}
 
 	ret := &model.IPInfo{
 		IP:     ip,
@@ -84,8 +99,10 @@ func (r *Reader) Find(ip net.IP) (*model.IPInfo, error) {
 	}
 
 	ret.Data = make(map[string]string)
-	for k, _v := range record.(map[string]interface{}) {
-		ret.Data[k] = string(_v.([]byte))
+	if m, ok := record.(map[string]interface{}); ok {
+		for k, v := range m {
+			ret.SetValue(k, model.NewValue(v))
+		}
 	}
 	ret.AddCommonFieldAlias(CommonFieldsAlias)
 
@@ -94,7 +111,7 @@ func (r *Reader) Find(ip net.IP) (*model.IPInfo, error) {
 
 // SetOption configures the Reader with the provided option.
 func (r *Reader) SetOption(option interface{}) error {
-	return nil
+	return errors.ErrUnsupportedOption
 }
 
 // Close closes the IP database.
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+const (
+	DBFormat = "csv"
+	DBExt    = ".csv"
+	NetField = "net"
+)
+
+// Writer provides functionalities to write IP data as CSV.
+// The first record is the header, "net" followed by the fields,
+// then each network is written as a record with the values of the fields.
+type Writer struct {
+	meta   *model.Meta
+	cw     *csv.Writer
+	buffer *bytes.Buffer
+}
+
+// NewWriter initializes a new Writer instance for writing IP data as CSV.
+func NewWriter(meta *model.Meta) (*Writer, error) {
+	return &Writer{
+		meta: meta,
+	}, nil
+}
+
+// SetOption sets the provided options to the Writer, the Writer has no options.
+func (w *Writer) SetOption(option interface{}) error {
+	return errors.ErrUnsupportedOption
+}
+
+// Insert adds the given IP information into the writer.
+func (w *Writer) Insert(info *model.IPInfo) error {
+	if w.cw == nil {
+		w.buffer = &bytes.Buffer{}
+		w.cw = csv.NewWriter(w.buffer)
+		if err := w.Header(); err != nil {
+			return err
+		}
+	}
+
+	values := info.Values()
+	for _, ipNet := range info.IPNet.IPNets() {
+		if err := w.cw.Write(append([]string{ipNet.String()}, values...)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Open binds the Writer to the output and writes the header,
+// the records inserted afterwards are streamed to the output.
+func (w *Writer) Open(writer io.Writer) error {
+	if writer == nil {
+		return errors.ErrNilWriter
+	}
+	w.cw = csv.NewWriter(writer)
+	w.buffer = nil
+
+	return w.Header()
+}
+
+// Close flushes the records that are not written to the output yet.
+func (w *Writer) Close() error {
+	if w.cw == nil {
+		return nil
+	}
+	w.cw.Flush()
+	err := w.cw.Error()
+	w.cw = nil
+
+	return err
+}
+
+// Abort drops the records that are not written to the output yet.
+func (w *Writer) Abort() error {
+	w.cw = nil
+
+	return nil
+}
+
+// WriteTo writes the buffered data into the provided writer.
+func (w *Writer) WriteTo(writer io.Writer) (int64, error) {
+	if w.buffer == nil {
+		return 0, nil
+	}
+	w.cw.Flush()
+	if err := w.cw.Error(); err != nil {
+		return 0, err
+	}
+
+	return w.buffer.WriteTo(writer)
+}
+
+// Header writes the header record.
+func (w *Writer) Header() error {
+	if w.cw == nil {
+		return errors.ErrNilWriter
+	}
+
+	return w.cw.Write(append([]string{NetField}, w.meta.Fields...))
+}
+
+// WriterFormat returns the format of the writer.
+func (w *Writer) WriterFormat() string {
+	return DBFormat
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"maps"
+	"slices"
+	"sort"
+
+	"github.com/sjzar/ips/format/awdb"
+	"github.com/sjzar/ips/format/csv"
+	"github.com/sjzar/ips/format/ip2region"
+	"github.com/sjzar/ips/format/ipdb"
+	"github.com/sjzar/ips/format/jsonl"
+	"github.com/sjzar/ips/format/mmdb"
+	"github.com/sjzar/ips/format/option"
+	"github.com/sjzar/ips/format/plain"
+	"github.com/sjzar/ips/format/qqwry"
+	"github.com/sjzar/ips/format/zxinc"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// Descriptor describes a format and its capabilities.
+type Descriptor struct {
+
+	// Name is the format name used by --format options, empty for readers and writers
+	// registered only by file extension or common name.
+	Name string
+
+	// Exts lists the file extensions of the format.
+	Exts []string
+
+	// CommonNames lists the common file name prefixes of the format.
+	CommonNames []string
+
+	// IPVersion is the IP version of the format (model.IPv4, model.IPv6 or both),
+	// zero if it depends on the database.
+	IPVersion int
+
+	// Fields lists the native fields known to the format, empty if they are defined by the database.
+	Fields []string
+
+	// FieldAlias maps common fields to native fields.
+	FieldAlias map[string]string
+
+	// Read and Write report whether a Reader and a Writer are registered, set by Formats.
+	Read, Write bool
+
+	// ReaderOptions and WriterOptions list the supported options, set by Formats.
+	ReaderOptions, WriterOptions option.Specs
+}
+
+var (
+	Descriptors = map[string]Descriptor{
+		awdb.DBFormat:      {Exts: []string{awdb.DBExt}, Fields: awdb.FullFields, FieldAlias: awdb.CommonFieldsAlias},
+		csv.DBFormat:       {Exts: []string{csv.DBExt}},
+		ip2region.DBFormat: {Exts: []string{ip2region.DBExt}, IPVersion: model.IPv4, Fields: ip2region.FullFields, FieldAlias: ip2region.CommonFieldsAlias},
+		ipdb.DBFormat:      {Exts: []string{ipdb.DBExt}, Fields: ipdb.FullFields, FieldAlias: ipdb.CommonFieldsAlias},
+		jsonl.DBFormat:     {Exts: []string{jsonl.DBExt}},
+		mmdb.DBFormat:      {Exts: []string{mmdb.DBExt}, Fields: mmdb.FullFields, FieldAlias: mmdb.CommonFieldsAlias},
+		plain.DBFormat:     {Exts: []string{plain.DBExt}},
+		qqwry.DBFormat:     {Exts: []string{qqwry.DBExt}, IPVersion: model.IPv4, Fields: qqwry.FullFields, FieldAlias: qqwry.CommonFieldsAlias},
+		zxinc.DBFormat:     {Exts: []string{zxinc.DBExt}, IPVersion: model.IPv6, Fields: zxinc.FullFields, FieldAlias: zxinc.CommonFieldsAlias},
+	}
+)
+
+// RegisterDescriptor registers the description of a format,
+// which is listed by Formats together with the registered readers, writers and options.
+func RegisterDescriptor(d Descriptor) {
+	if d.Name == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	Descriptors[d.Name] = d
+}
+
+// Formats lists the registered formats sorted by name, including those registered at runtime.
+// Extensions and common names are only listed if a reader or writer is registered for them,
+// those not claimed by a Descriptor are listed as formats without name.
+// The returned descriptors are copies, changing them does not affect the registered formats.
+func Formats() []Descriptor {
+	mu.Lock()
+	defer mu.Unlock()
+
+	names := make(map[string]bool)
+	for name := range ReaderFormats {
+		names[name] = true
+	}
+	for name := range WriterFormats {
+		names[name] = true
+	}
+	for name := range Descriptors {
+		names[name] = true
+	}
+
+	claimedExts, claimedCommonNames := make(map[string]bool), make(map[string]bool)
+	ret := make([]Descriptor, 0, len(names))
+	for name := range names {
+		d := Descriptors[name]
+		d.Name = name
+		_, d.Read = ReaderFormats[name]
+		_, d.Write = WriterFormats[name]
+		d.Fields = slices.Clone(d.Fields)
+		d.FieldAlias = maps.Clone(d.FieldAlias)
+		d.ReaderOptions = slices.Clone(ReaderOptions[name].Specs)
+		d.WriterOptions = slices.Clone(WriterOptions[name].Specs)
+
+		exts := make([]string, 0, len(d.Exts))
+		for _, ext := range d.Exts {
+			_, read := ReaderExts[ext]
+			_, write := WriterExts[ext]
+			if read || write {
+				exts = append(exts, ext)
+				claimedExts[ext] = true
+			}
+		}
+		d.Exts = exts
+
+		commonNames := make([]string, 0, len(d.CommonNames))
+		for _, commonName := range d.CommonNames {
+			if _, ok := ReaderCommonNames[commonName]; ok {
+				commonNames = append(commonNames, commonName)
+				claimedCommonNames[commonName] = true
+			}
+		}
+		d.CommonNames = commonNames
+
+		ret = append(ret, d)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+
+	// extensions and common names registered without a descriptor
+	var unnamed []Descriptor
+	for ext := range ReaderExts {
+		if !claimedExts[ext] {
+			_, write := WriterExts[ext]
+			unnamed = append(unnamed, Descriptor{Exts: []string{ext}, Read: true, Write: write})
+			claimedExts[ext] = true
+		}
+	}
+	for ext := range WriterExts {
+		if !claimedExts[ext] {
+			unnamed = append(unnamed, Descriptor{Exts: []string{ext}, Write: true})
+		}
+	}
+	for commonName := range ReaderCommonNames {
+		if !claimedCommonNames[commonName] {
+			unnamed = append(unnamed, Descriptor{CommonNames: []string{commonName}, Read: true})
+		}
+	}
+	key := func(d Descriptor) string {
+		if len(d.Exts) > 0 {
+			return d.Exts[0]
+		}
+		return d.CommonNames[0]
+	}
+	sort.Slice(unnamed, func(i, j int) bool { return key(unnamed[i]) < key(unnamed[j]) })
+
+	return append(ret, unnamed...)
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geo
+
+import (
+	"bufio"
+	"strings"
+	"sync"
+
+	"github.com/sjzar/ips/format/geo/data"
+)
+
+var (
+	codeOnce sync.Once
+
+	// countryCodes maps country names in all languages and ISO codes to ISO 3166-1 alpha-2 codes.
+	countryCodes map[string]string
+
+	// subdivisionCodes maps country code and subdivision name, separated by "\t", to ISO 3166-2 codes.
+	subdivisionCodes map[string]string
+
+	// timeZones maps ISO 3166-1 alpha-2 codes to IANA time zones.
+	timeZones map[string]string
+)
+
+// subdivisionSuffixes are the suffixes trimmed from Chinese subdivision names, longest first.
+var subdivisionSuffixes = []string{"壮族自治区", "回族自治区", "维吾尔自治区", "特别行政区", "自治区", "省", "市"}
+
+// loadCodes builds the code indexes from the geolocation data.
+func loadCodes() {
+	countryCodes = make(map[string]string)
+	countryIDs := make(map[int]string)
+	forEachInfo(data.Country, func(info *Info) {
+		if len(info.IsoCode) == 0 {
+			return
+		}
+		countryIDs[info.GeoNameID] = info.IsoCode
+		countryCodes[info.IsoCode] = info.IsoCode
+		for _, name := range info.Names {
+			countryCodes[name] = info.IsoCode
+		}
+	})
+
+	subdivisionCodes = make(map[string]string)
+	forEachInfo(data.Region, func(info *Info) {
+		countryCode := countryIDs[info.CountryID]
+		if len(countryCode) == 0 || len(info.IsoCode) == 0 {
+			return
+		}
+		for _, name := range info.Names {
+			subdivisionCodes[countryCode+"\t"+name] = countryCode + "-" + info.IsoCode
+		}
+	})
+
+	timeZones = make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(data.TimeZone))
+	for scanner.Scan() {
+		if split := strings.SplitN(scanner.Text(), "\t", 2); len(split) == 2 {
+			timeZones[split[0]] = split[1]
+		}
+	}
+}
+
+// forEachInfo calls fn for each geolocation info in the data.
+func forEachInfo(d string, fn func(info *Info)) {
+	scanner := bufio.NewScanner(strings.NewReader(d))
+	for scanner.Scan() {
+		if info, ok := ParseGeoInfo(scanner.Text()); ok {
+			fn(info)
+		}
+	}
+}
+
+// CountryCode returns the ISO 3166-1 alpha-2 code of the country, or an empty string if it is unknown.
+// The country can be given by its name in any supported language or by its code.
+func CountryCode(country string) string {
+	codeOnce.Do(loadCodes)
+	return countryCodes[strings.TrimSpace(country)]
+}
+
+// SubdivisionCode returns the ISO 3166-2 code of the subdivision of the country, e.g. "CN-BJ",
+// or an empty string if it is unknown. The subdivision can be given by its name in any supported language.
+func SubdivisionCode(countryCode, subdivision string) string {
+	codeOnce.Do(loadCodes)
+	subdivision = strings.TrimSpace(subdivision)
+	if code, ok := subdivisionCodes[countryCode+"\t"+subdivision]; ok {
+		return code
+	}
+	for _, suffix := range subdivisionSuffixes {
+		if name := strings.TrimSuffix(subdivision, suffix); name != subdivision {
+			return subdivisionCodes[countryCode+"\t"+name]
+		}
+	}
+	return ""
+}
+
+// TimeZone returns the IANA time zone of the country, or an empty string if the country
+// is unknown or uses more than one time zone.
+func TimeZone(countryCode string) string {
+	codeOnce.Do(loadCodes)
+	return timeZones[countryCode]
+}
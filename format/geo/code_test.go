@@ -0,0 +1,42 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodes(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.Equal("CN", CountryCode("中国"))
+	ast.Equal("CN", CountryCode("China"))
+	ast.Equal("CN", CountryCode("CN"))
+	ast.Equal("", CountryCode("unknown"))
+
+	ast.Equal("CN-BJ", SubdivisionCode("CN", "北京"))
+	ast.Equal("CN-GD", SubdivisionCode("CN", "广东省"))
+	ast.Equal("CN-GX", SubdivisionCode("CN", "广西壮族自治区"))
+	ast.Equal("CN-GD", SubdivisionCode("CN", "Guangdong"))
+	ast.Equal("", SubdivisionCode("US", "广东"))
+
+	ast.Equal("Asia/Shanghai", TimeZone("CN"))
+	ast.Equal("Asia/Tokyo", TimeZone("JP"))
+	ast.Equal("", TimeZone("US"))
+}
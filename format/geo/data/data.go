@@ -31,3 +31,8 @@ var Region string
 
 //go:embed city.txt
 var City string
+
+// TimeZone maps ISO 3166-1 alpha-2 country codes to the IANA time zone of countries using a single time zone.
+//
+//go:embed timezone.txt
+var TimeZone string
@@ -17,27 +17,42 @@
 package ip2region
 
 import (
+	"context"
 	"net"
 
 	"github.com/sjzar/ips/format/ip2region/sdk"
+	"github.com/sjzar/ips/format/option"
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
 	"github.com/sjzar/ips/pkg/model"
 )
 
 const (
 	DBFormat = "ip2region"
 	DBExt    = ".xdb"
+
+	// Language is the language of the data.
+	Language = "zh-CN"
+
+	// Source is the project providing ip2region databases.
+	Source = "github.com/lionsoul2014/ip2region"
 )
 
 // Reader is a structure that provides functionalities to read from IP2Region IP database.
 type Reader struct {
+	file string      // Path of the IP database file
 	meta *model.Meta // Metadata of the IP database
 	db   *sdk.Reader // Database reader instance
 }
 
 // NewReader initializes a new instance of Reader.
 func NewReader(file string) (*Reader, error) {
+	return NewReaderWithOption(file, ReaderOption{})
+}
 
-	db, err := sdk.NewReader(file)
+// NewReaderWithOption initializes a new instance of Reader, loading the database file as set by option.
+func NewReaderWithOption(file string, option ReaderOption) (*Reader, error) {
+	db, err := open(file, option)
 	if err != nil {
 		return nil, err
 	}
@@ -47,10 +62,14 @@ func NewReader(file string) (*Reader, error) {
 		Format:      DBFormat,
 		IPVersion:   model.IPv4,
 		Fields:      FullFields,
+		BuildTime:   db.CreatedAt(),
+		Languages:   []string{Language},
+		Source:      Source,
 	}
 	meta.AddCommonFieldAlias(CommonFieldsAlias)
 
 	return &Reader{
+		file: file,
 		meta: meta,
 		db:   db,
 	}, nil
@@ -63,17 +82,49 @@ func (r *Reader) Find(ip net.IP) (*model.IPInfo, error) {
 		return nil, err
 	}
 
+	info := r.newIPInfo(ip, ipr, values)
+	if info == nil {
+		return nil, errors.NewNotFoundError(ip, ipr)
+	}
+	return info, nil
+}
+
+// Ranges returns an iterator over the IP ranges overlapping [start, end] by walking the segment index.
+func (r *Reader) Ranges(ctx context.Context, start, end net.IP) model.IPInfoSeq {
+	return func(yield func(*model.IPInfo, error) bool) {
+		err := r.db.Ranges(ctx, start, end, func(ipr *ipnet.Range, values []string) bool {
+			info := r.newIPInfo(ipr.Start, ipr, values)
+			if info == nil {
+				// unknown space
+				return true
+			}
+			return yield(info, nil)
+		})
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// newIPInfo builds the IP information from a database record.
+// The placeholder "0" is cleared, and nil is returned if the record holds no data.
+func (r *Reader) newIPInfo(ip net.IP, ipr *ipnet.Range, values []string) *model.IPInfo {
 	data := make(map[string]string, len(FullFields))
+	found := false
 	for i := range values {
-		if i > len(FullFields) {
+		if i >= len(FullFields) {
 			break
 		}
-		if values[i] != "0" {
+		if values[i] != "0" && values[i] != "" {
 			data[FullFields[i]] = values[i]
+			found = true
 		} else {
 			data[FullFields[i]] = ""
 		}
 	}
+	if !found {
+		return nil
+	}
 
 	ret := &model.IPInfo{
 		IP:     ip,
@@ -83,7 +134,7 @@ func (r *Reader) Find(ip net.IP) (*model.IPInfo, error) {
 	}
 	ret.AddCommonFieldAlias(CommonFieldsAlias)
 
-	return ret, nil
+	return ret
 }
 
 // Meta returns the meta-information of the IP database.
@@ -91,12 +142,51 @@ func (r *Reader) Meta() *model.Meta {
 	return r.meta
 }
 
+// ReaderOptions lists the options supported by the Reader.
+var ReaderOptions = option.Specs{
+	{Name: "mmap", Type: option.Bool, Default: "false", Description: "Memory-map the database file instead of loading it into the heap."},
+}
+
+// NewReaderOption converts validated option values into a ReaderOption.
+func NewReaderOption(values option.Values) ReaderOption {
+	return ReaderOption{Mmap: values.Bool("mmap")}
+}
+
+// ReaderOption contains configuration options for the Reader.
+type ReaderOption struct {
+	Mmap bool // If true, the database file is memory-mapped instead of loaded into the heap.
+}
+
 // SetOption configures the Reader with the provided option.
+// Switching the loading mode reloads the database file.
 func (r *Reader) SetOption(option interface{}) error {
+	opt, ok := option.(ReaderOption)
+	if !ok {
+		return errors.ErrUnsupportedOption
+	}
+	if opt.Mmap == r.db.Mapped() {
+		return nil
+	}
+
+	db, err := open(r.file, opt)
+	if err != nil {
+		return err
+	}
+
+	_ = r.db.Close()
+	r.db = db
 	return nil
 }
 
+// open loads the database file into the heap, or memory-maps it if option.Mmap is set.
+func open(file string, option ReaderOption) (*sdk.Reader, error) {
+	if option.Mmap {
+		return sdk.NewMmapReader(file)
+	}
+	return sdk.NewReader(file)
+}
+
 // Close closes the IP database.
 func (r *Reader) Close() error {
-	return nil
+	return r.db.Close()
 }
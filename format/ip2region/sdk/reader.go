@@ -17,12 +17,14 @@
 package sdk
 
 import (
+	"context"
 	"encoding/binary"
-	"io"
 	"net"
-	"os"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/sjzar/ips/internal/mmap"
 	"github.com/sjzar/ips/ipnet"
 	"github.com/sjzar/ips/pkg/errors"
 )
@@ -45,37 +47,67 @@ const (
 )
 
 type Reader struct {
+	file *mmap.File
 	data []byte
 }
 
+// NewReader 加载IP库到内存
 func NewReader(file string) (*Reader, error) {
-	f, err := os.Open(file)
+	f, err := mmap.ReadFile(file)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		_ = f.Close()
-	}()
+	return newReader(f)
+}
 
-	data, err := io.ReadAll(f)
+// NewMmapReader 通过 mmap 加载IP库，不支持 mmap 的平台会回退到内存加载
+func NewMmapReader(file string) (*Reader, error) {
+	f, err := mmap.Open(file)
 	if err != nil {
 		return nil, err
 	}
+	return newReader(f)
+}
 
+func newReader(f *mmap.File) (*Reader, error) {
+	data := f.Bytes()
 	if len(data) < 256 {
+		_ = f.Close()
 		return nil, errors.ErrInvalidDatabase
 	}
 
 	end := binary.LittleEndian.Uint32(data[12:16])
 	if end+IndexLen > uint32(len(data)) {
+		_ = f.Close()
 		return nil, errors.ErrInvalidDatabase
 	}
 
 	return &Reader{
+		file: f,
 		data: data,
 	}, nil
 }
 
+// CreatedAt 返回IP库的生成时间，头部未记录时返回零值
+func (i *Reader) CreatedAt() time.Time {
+	createdAt := binary.LittleEndian.Uint32(i.data[4:8])
+	if createdAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(createdAt), 0).In(time.UTC)
+}
+
+// Mapped 是否通过 mmap 加载
+func (i *Reader) Mapped() bool {
+	return i.file.Mapped()
+}
+
+// Close 释放IP库数据
+func (i *Reader) Close() error {
+	i.data = nil
+	return i.file.Close()
+}
+
 // Find 查找IP
 func (i *Reader) Find(ip net.IP) (*ipnet.Range, []string, error) {
 
@@ -101,6 +133,54 @@ func (i *Reader) Find(ip net.IP) (*ipnet.Range, []string, error) {
 	}, data, nil
 }
 
+// Ranges 按顺序遍历段索引，对与 [start, end] 有交集的每个IP段调用 fn，fn 返回 false 或 ctx 结束时停止
+func (i *Reader) Ranges(ctx context.Context, start, end net.IP, fn func(ipr *ipnet.Range, data []string) bool) error {
+	start, end = start.To4(), end.To4()
+	if start == nil || end == nil {
+		return errors.ErrUnsupportedIPVersion
+	}
+	first, last := ipnet.IPv4ToUint32(start), ipnet.IPv4ToUint32(end)
+
+	// the segment index is sorted and stored between the start and end index pointers in the header
+	sPtr := binary.LittleEndian.Uint32(i.data[8:12])
+	ePtr := binary.LittleEndian.Uint32(i.data[12:16])
+	count := int((ePtr-sPtr)/IndexLen) + 1
+	index := sort.Search(count, func(m int) bool {
+		p := sPtr + uint32(m*IndexLen)
+		return binary.LittleEndian.Uint32(i.data[p+4:]) >= first
+	})
+
+	for p := sPtr + uint32(index*IndexLen); p <= ePtr; p += IndexLen {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		buff := i.data[p : p+IndexLen]
+		startIP := binary.LittleEndian.Uint32(buff)
+		if startIP > last {
+			break
+		}
+		endIP := binary.LittleEndian.Uint32(buff[4:])
+		length := uint32(binary.LittleEndian.Uint16(buff[8:]))
+		offset := binary.LittleEndian.Uint32(buff[10:])
+
+		data := make([]string, 0)
+		if length != 0 {
+			data = strings.Split(string(i.data[offset:offset+length]), FieldSpe)
+		}
+
+		ipr := &ipnet.Range{
+			Start: ipnet.Uint32ToIPv4(startIP).To4(),
+			End:   ipnet.Uint32ToIPv4(endIP).To4(),
+		}
+		if !fn(ipr, data) {
+			break
+		}
+	}
+
+	return nil
+}
+
 // findOffset 查找IP对应的偏移量
 func (i *Reader) findOffset(ip uint32) (startIP, endIP uint32, length, offset uint32) {
 
@@ -92,4 +92,7 @@ var CommonFieldsAlias = map[string]string{
 	model.Latitude:       FieldLatitude,
 	model.Longitude:      FieldLongitude,
 	model.ChinaAdminCode: FieldChinaAdminCode,
+	model.CountryCode:    FieldCountryCode,
+	model.TimeZone:       FieldTimezone,
+	model.Domain:         FieldOwnerDomain,
 }
@@ -17,10 +17,13 @@
 package ipdb
 
 import (
+	"context"
 	"net"
 
 	"github.com/sjzar/ips/format/ipdb/sdk"
+	"github.com/sjzar/ips/format/option"
 	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
 	"github.com/sjzar/ips/pkg/model"
 )
 
@@ -31,13 +34,19 @@ const (
 
 // Reader is a structure that provides functionalities to read from IPDB IP database.
 type Reader struct {
+	file string      // Path of the IP database file
 	meta *model.Meta // Metadata of the IP database
 	db   *sdk.City   // Database reader instance
 }
 
 // NewReader initializes a new instance of Reader.
 func NewReader(file string) (*Reader, error) {
-	city, err := sdk.NewCity(file)
+	return NewReaderWithOption(file, ReaderOption{})
+}
+
+// NewReaderWithOption initializes a new instance of Reader, loading the database file as set by option.
+func NewReaderWithOption(file string, option ReaderOption) (*Reader, error) {
+	city, err := open(file, option)
 	if err != nil {
 		return nil, err
 	}
@@ -46,6 +55,8 @@ func NewReader(file string) (*Reader, error) {
 		MetaVersion: model.MetaVersion,
 		Format:      DBFormat,
 		Fields:      city.Fields(),
+		BuildTime:   city.BuildTime(),
+		Languages:   city.Languages(),
 	}
 	meta.AddCommonFieldAlias(CommonFieldsAlias)
 
@@ -57,6 +68,7 @@ func NewReader(file string) (*Reader, error) {
 	}
 
 	return &Reader{
+		file: file,
 		meta: meta,
 		db:   city,
 	}, nil
@@ -65,10 +77,30 @@ func NewReader(file string) (*Reader, error) {
 // Find retrieves IP information based on the given IP address.
 func (r *Reader) Find(ip net.IP) (*model.IPInfo, error) {
 	data, ipNet, err := r.db.FindMap(ip.String(), "CN")
+	if err == sdk.ErrDataNotExists {
+		return nil, errors.NewNotFoundError(ip, ipnet.NewRange(ipNet))
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	return r.newIPInfo(ip, ipNet, data), nil
+}
+
+// Ranges returns an iterator over the IP ranges overlapping [start, end] by walking the database tree.
+func (r *Reader) Ranges(ctx context.Context, start, end net.IP) model.IPInfoSeq {
+	return func(yield func(*model.IPInfo, error) bool) {
+		err := r.db.Ranges(ctx, start, end, "CN", func(ipNet *net.IPNet, data map[string]string) bool {
+			return yield(r.newIPInfo(ipNet.IP, ipNet, data), nil)
+		})
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// newIPInfo builds the IP information from a database record.
+func (r *Reader) newIPInfo(ip net.IP, ipNet *net.IPNet, data map[string]string) *model.IPInfo {
 	ret := &model.IPInfo{
 		IP:     ip,
 		IPNet:  ipnet.NewRange(ipNet),
@@ -77,7 +109,7 @@ func (r *Reader) Find(ip net.IP) (*model.IPInfo, error) {
 	}
 	ret.AddCommonFieldAlias(CommonFieldsAlias)
 
-	return ret, nil
+	return ret
 }
 
 // Meta returns the meta-information of the IP database.
@@ -85,12 +117,51 @@ func (r *Reader) Meta() *model.Meta {
 	return r.meta
 }
 
+// ReaderOptions lists the options supported by the Reader.
+var ReaderOptions = option.Specs{
+	{Name: "mmap", Type: option.Bool, Default: "false", Description: "Memory-map the database file instead of loading it into the heap."},
+}
+
+// NewReaderOption converts validated option values into a ReaderOption.
+func NewReaderOption(values option.Values) ReaderOption {
+	return ReaderOption{Mmap: values.Bool("mmap")}
+}
+
+// ReaderOption contains configuration options for the Reader.
+type ReaderOption struct {
+	Mmap bool // If true, the database file is memory-mapped instead of loaded into the heap.
+}
+
 // SetOption configures the Reader with the provided option.
+// Switching the loading mode reloads the database file.
 func (r *Reader) SetOption(option interface{}) error {
+	opt, ok := option.(ReaderOption)
+	if !ok {
+		return errors.ErrUnsupportedOption
+	}
+	if opt.Mmap == r.db.Mapped() {
+		return nil
+	}
+
+	city, err := open(r.file, opt)
+	if err != nil {
+		return err
+	}
+
+	_ = r.db.Close()
+	r.db = city
 	return nil
 }
 
+// open loads the database file into the heap, or memory-maps it if option.Mmap is set.
+func open(file string, option ReaderOption) (*sdk.City, error) {
+	if option.Mmap {
+		return sdk.NewMmapCity(file)
+	}
+	return sdk.NewCity(file)
+}
+
 // Close closes the IP database.
 func (r *Reader) Close() error {
-	return nil
+	return r.db.Close()
 }
@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipdb
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+func TestReaderRanges(t *testing.T) {
+	ast := assert.New(t)
+
+	meta := &model.Meta{
+		IPVersion: model.IPv4,
+		Fields:    []string{"country_name"},
+	}
+	writer, err := NewWriter(meta)
+	ast.Nil(err)
+
+	for cidr, country := range map[string]string{
+		"1.0.0.0/24": "A",
+		"1.0.1.0/24": "B",
+		"8.8.8.0/24": "C",
+	} {
+		ip, ipNet, err := net.ParseCIDR(cidr)
+		ast.Nil(err)
+		ast.Nil(writer.Insert(&model.IPInfo{
+			IP:     ip,
+			IPNet:  ipnet.NewRange(ipNet),
+			Data:   map[string]string{"country_name": country},
+			Fields: meta.Fields,
+		}))
+	}
+
+	file := filepath.Join(t.TempDir(), "test.ipdb")
+	f, err := os.Create(file)
+	ast.Nil(err)
+	_, err = writer.WriteTo(f)
+	ast.Nil(err)
+	ast.Nil(f.Close())
+
+	reader, err := NewReader(file)
+	ast.Nil(err)
+	defer reader.Close()
+
+	for _, mmap := range []bool{false, true} {
+		ast.Nil(reader.SetOption(ReaderOption{Mmap: mmap}))
+
+		var ranges, values []string
+		reader.Ranges(context.Background(), net.IPv4(0, 0, 0, 0), ipnet.LastIPv4)(func(info *model.IPInfo, err error) bool {
+			ast.Nil(err)
+			ranges = append(ranges, info.IPNet.IPNets()[0].String())
+			values = append(values, info.Values()[0])
+
+			// ranges agree with Find
+			found, err := reader.Find(info.IPNet.End)
+			ast.Nil(err)
+			ast.Equal(info.Values(), found.Values())
+			return true
+		})
+		ast.Equal([]string{"1.0.0.0/24", "1.0.1.0/24", "8.8.8.0/24"}, ranges)
+		ast.Equal([]string{"A", "B", "C"}, values)
+
+		// partial range and early stop
+		count := 0
+		reader.Ranges(context.Background(), net.IPv4(1, 0, 1, 128), ipnet.LastIPv4)(func(info *model.IPInfo, err error) bool {
+			ast.Nil(err)
+			ast.Equal("B", info.Values()[0])
+			count++
+			return false
+		})
+		ast.Equal(1, count)
+	}
+
+	// cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reader.Ranges(ctx, net.IPv4(0, 0, 0, 0), ipnet.LastIPv4)(func(info *model.IPInfo, err error) bool {
+		ast.ErrorIs(err, context.Canceled)
+		return true
+	})
+}
+
+func TestReaderMeta(t *testing.T) {
+	ast := assert.New(t)
+
+	buildTime := time.Date(2023, 10, 25, 0, 0, 0, 0, time.UTC)
+	writer, err := NewWriter(&model.Meta{
+		IPVersion: model.IPv4,
+		Fields:    []string{"country_name"},
+		BuildTime: buildTime,
+	})
+	ast.Nil(err)
+
+	ip, ipNet, err := net.ParseCIDR("1.0.0.0/24")
+	ast.Nil(err)
+	ast.Nil(writer.Insert(&model.IPInfo{
+		IP:     ip,
+		IPNet:  ipnet.NewRange(ipNet),
+		Data:   map[string]string{"country_name": "A"},
+		Fields: []string{"country_name"},
+	}))
+
+	file := filepath.Join(t.TempDir(), "test.ipdb")
+	f, err := os.Create(file)
+	ast.Nil(err)
+	_, err = writer.WriteTo(f)
+	ast.Nil(err)
+	ast.Nil(f.Close())
+
+	reader, err := NewReader(file)
+	ast.Nil(err)
+	defer reader.Close()
+
+	meta := reader.Meta()
+	ast.True(buildTime.Equal(meta.BuildTime))
+	ast.Equal([]string{"CN"}, meta.Languages)
+}
+
+func TestNewReaderWithOption(t *testing.T) {
+	ast := assert.New(t)
+
+	writer, err := NewWriter(&model.Meta{
+		IPVersion: model.IPv4,
+		Fields:    []string{"country_name"},
+	})
+	ast.Nil(err)
+	file := filepath.Join(t.TempDir(), "test.ipdb")
+	f, err := os.Create(file)
+	ast.Nil(err)
+	_, err = writer.WriteTo(f)
+	ast.Nil(err)
+	ast.Nil(f.Close())
+
+	// the file is memory-mapped without being loaded into the heap first
+	reader, err := NewReaderWithOption(file, ReaderOption{Mmap: true})
+	ast.Nil(err)
+	ast.True(reader.db.Mapped())
+	ast.Nil(reader.Close())
+
+	reader, err = NewReaderWithOption(file, ReaderOption{})
+	ast.Nil(err)
+	ast.False(reader.db.Mapped())
+	ast.Nil(reader.Close())
+
+	// load errors are returned unchanged
+	for _, mmap := range []bool{false, true} {
+		_, err = NewReaderWithOption(filepath.Join(t.TempDir(), "missing.ipdb"), ReaderOption{Mmap: mmap})
+		ast.ErrorIs(err, os.ErrNotExist)
+	}
+}
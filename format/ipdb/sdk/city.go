@@ -19,8 +19,12 @@ package sdk
 // Copy From https://github.com/ipipdotnet/ipdb-go
 // modify by shenjunzheng
 // modify: Find / FindMap return ipNet
+// modify: FindMap returns the empty network on ErrDataNotExists
+// modify: support memory-mapped loading
+// modify: support walking ranges in the tree
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net"
@@ -104,6 +108,19 @@ func NewCity(name string) (*City, error) {
 	}, nil
 }
 
+// NewMmapCity initialize with memory-mapped database file
+func NewMmapCity(name string) (*City, error) {
+
+	r, e := newMmapReader(name, &CityInfo{})
+	if e != nil {
+		return nil, e
+	}
+
+	return &City{
+		reader: r,
+	}, nil
+}
+
 // NewCityByIO initialize
 func NewCityByIO(r io.Reader) (*City, error) {
 	reader, err := newIOReader(r, &CityInfo{})
@@ -152,11 +169,12 @@ func (db *City) Find(addr, language string) ([]string, *net.IPNet, error) {
 }
 
 // FindMap query with addr
+// On ErrDataNotExists, ipNet is the empty network containing addr
 func (db *City) FindMap(addr, language string) (map[string]string, *net.IPNet, error) {
 
 	data, ipNet, err := db.reader.find1(addr, language)
 	if err != nil {
-		return nil, nil, err
+		return nil, ipNet, err
 	}
 	info := make(map[string]string, len(db.reader.meta.Fields))
 	for k, v := range data {
@@ -166,6 +184,17 @@ func (db *City) FindMap(addr, language string) (map[string]string, *net.IPNet, e
 	return info, ipNet, nil
 }
 
+// Ranges walk the ranges overlapping [start, end] in ascending order, until fn returns false
+func (db *City) Ranges(ctx context.Context, start, end net.IP, language string, fn func(ipNet *net.IPNet, data map[string]string) bool) error {
+	return db.reader.ranges(ctx, start, end, language, func(ipNet *net.IPNet, data []string) bool {
+		info := make(map[string]string, len(db.reader.meta.Fields))
+		for k, v := range data {
+			info[db.reader.meta.Fields[k]] = v
+		}
+		return fn(ipNet, info)
+	})
+}
+
 // FindInfo query with addr
 func (db *City) FindInfo(addr, language string) (*CityInfo, *net.IPNet, error) {
 
@@ -237,3 +266,13 @@ func (db *City) Fields() []string {
 func (db *City) BuildTime() time.Time {
 	return db.reader.Build()
 }
+
+// Mapped whether the database is memory-mapped
+func (db *City) Mapped() bool {
+	return db.reader.Mapped()
+}
+
+// Close release the database
+func (db *City) Close() error {
+	return db.reader.Close()
+}
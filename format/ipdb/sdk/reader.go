@@ -17,22 +17,28 @@
 package sdk
 
 import (
+	"bytes"
+	"context"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"io"
 	"io/ioutil"
 	"net"
-	"os"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 	"unsafe"
+
+	"github.com/sjzar/ips/internal/mmap"
 )
 
 // Copy From https://github.com/ipipdotnet/ipdb-go
 // modify by shenjunzheng
 // modify: Find / FindMap return ipNet
+// modify: support memory-mapped loading
+// modify: support walking ranges in the tree
 
 const IPv4 = 0x01
 const IPv6 = 0x02
@@ -68,38 +74,51 @@ type reader struct {
 	v4offset  int
 
 	meta MetaData
+	file *mmap.File
 	data []byte
 
 	refType map[string]string
 }
 
 func newReader(name string, obj interface{}) (*reader, error) {
-	var err error
-	var fileInfo os.FileInfo
-	fileInfo, err = os.Stat(name)
+	file, err := mmap.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return newFileReader(file, obj)
+}
+
+func newMmapReader(name string, obj interface{}) (*reader, error) {
+	file, err := mmap.Open(name)
 	if err != nil {
 		return nil, err
 	}
-	fileSize := int(fileInfo.Size())
+	return newFileReader(file, obj)
+}
+
+func newFileReader(file *mmap.File, obj interface{}) (*reader, error) {
+	body := file.Bytes()
+	fileSize := len(body)
 	if fileSize < 4 {
+		_ = file.Close()
 		return nil, ErrFileSize
 	}
-	body, err := os.ReadFile(name)
-	if err != nil {
-		return nil, ErrReadFull
-	}
 	var meta MetaData
 	metaLength := int(binary.BigEndian.Uint32(body[0:4]))
 	if fileSize < (4 + metaLength) {
+		_ = file.Close()
 		return nil, ErrFileSize
 	}
 	if err := json.Unmarshal(body[4:4+metaLength], &meta); err != nil {
+		_ = file.Close()
 		return nil, err
 	}
 	if len(meta.Languages) == 0 || len(meta.Fields) == 0 {
+		_ = file.Close()
 		return nil, ErrMetaData
 	}
 	if fileSize != (4 + metaLength + meta.TotalSize) {
+		_ = file.Close()
 		return nil, ErrFileSize
 	}
 
@@ -120,6 +139,7 @@ func newReader(name string, obj interface{}) (*reader, error) {
 		meta:    meta,
 		refType: dm,
 
+		file: file,
 		data: body[4+metaLength:],
 	}
 
@@ -203,7 +223,7 @@ func (db *reader) FindMap(addr, language string) (map[string]string, *net.IPNet,
 
 	data, ipNet, err := db.find1(addr, language)
 	if err != nil {
-		return nil, nil, err
+		return nil, ipNet, err
 	}
 	info := make(map[string]string, len(db.meta.Fields))
 	for k, v := range data {
@@ -240,6 +260,10 @@ func (db *reader) find0(addr string) ([]byte, *net.IPNet, error) {
 		return nil, nil, ErrIPFormat
 	}
 
+	if err == ErrDataNotExists {
+		// ipNet is the empty sub tree containing the IP
+		return nil, ipNet, err
+	}
 	if err != nil || node < 0 {
 		return nil, nil, err
 	}
@@ -261,11 +285,17 @@ func (db *reader) find1(addr, language string) ([]string, *net.IPNet, error) {
 
 	body, ipNet, err := db.find0(addr)
 	if err != nil {
-		return nil, nil, err
+		return nil, ipNet, err
 	}
 
-	str := (*string)(unsafe.Pointer(&body))
-	tmp := strings.Split(*str, "\t")
+	// strings must not refer to mapped memory, which is released on Close
+	var tmp []string
+	if db.file != nil && db.file.Mapped() {
+		tmp = strings.Split(string(body), "\t")
+	} else {
+		str := (*string)(unsafe.Pointer(&body))
+		tmp = strings.Split(*str, "\t")
+	}
 
 	if (off + len(db.meta.Fields)) > len(tmp) {
 		return nil, nil, ErrDatabaseError
@@ -274,6 +304,83 @@ func (db *reader) find1(addr, language string) ([]string, *net.IPNet, error) {
 	return tmp[off : off+len(db.meta.Fields)], ipNet, nil
 }
 
+func (db *reader) ranges(ctx context.Context, start, end net.IP, language string, fn func(ipNet *net.IPNet, data []string) bool) error {
+
+	off, ok := db.meta.Languages[language]
+	if !ok {
+		return ErrNoSupportLanguage
+	}
+
+	var node int
+	if s, e := start.To4(), end.To4(); s != nil && e != nil {
+		if !db.IsIPv4Support() {
+			return ErrNoSupportIPv4
+		}
+		start, end, node = s, e, db.v4offset
+	} else if s, e := start.To16(), end.To16(); s != nil && e != nil {
+		if !db.IsIPv6Support() {
+			return ErrNoSupportIPv6
+		}
+		start, end, node = s, e, 0
+	} else {
+		return ErrIPFormat
+	}
+
+	_, err := db.walk(ctx, node, 0, make(net.IP, len(start)), start, end, func(ipNet *net.IPNet, body []byte) (bool, error) {
+		tmp := strings.Split(string(body), "\t")
+		if (off + len(db.meta.Fields)) > len(tmp) {
+			return false, ErrDatabaseError
+		}
+		return fn(ipNet, tmp[off:off+len(db.meta.Fields)]), nil
+	})
+	return err
+}
+
+// walk visits the data nodes under node in ascending order, skipping sub trees outside [start, end].
+func (db *reader) walk(ctx context.Context, node, depth int, prefix, start, end net.IP, fn func(ipNet *net.IPNet, body []byte) (bool, error)) (bool, error) {
+
+	bitCount := len(prefix) * 8
+	mask := net.CIDRMask(depth, bitCount)
+	last := make(net.IP, len(prefix))
+	for i := range prefix {
+		last[i] = prefix[i] | ^mask[i]
+	}
+	if bytes.Compare(last, start) < 0 || bytes.Compare(prefix, end) > 0 {
+		return true, nil
+	}
+
+	if node >= db.nodeCount || depth == bitCount {
+		if node <= db.nodeCount {
+			// data is not exists
+			return true, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		body, err := db.resolve(node)
+		if err != nil {
+			return false, err
+		}
+		ip := make(net.IP, len(prefix))
+		copy(ip, prefix)
+		return fn(&net.IPNet{IP: ip, Mask: mask}, body)
+	}
+
+	for bit := 0; bit < 2; bit++ {
+		child := prefix
+		if bit == 1 {
+			child = make(net.IP, len(prefix))
+			copy(child, prefix)
+			child[depth>>3] |= 0x80 >> uint(depth%8)
+		}
+		if ok, err := db.walk(ctx, db.readNode(node, bit), depth+1, child, start, end, fn); !ok || err != nil {
+			return ok, err
+		}
+	}
+
+	return true, nil
+}
+
 func (db *reader) search(ip net.IP, bitCount int) (int, int, error) {
 
 	var node int
@@ -297,7 +404,7 @@ func (db *reader) search(ip net.IP, bitCount int) (int, int, error) {
 		return node, i, nil
 	}
 
-	return -1, 0, ErrDataNotExists
+	return -1, i, ErrDataNotExists
 }
 
 func (db *reader) readNode(node, index int) int {
@@ -337,5 +444,15 @@ func (db *reader) Languages() []string {
 	for k := range db.meta.Languages {
 		ls = append(ls, k)
 	}
+	sort.Strings(ls)
 	return ls
 }
+
+func (db *reader) Mapped() bool {
+	return db.file != nil && db.file.Mapped()
+}
+
+func (db *reader) Close() error {
+	db.data = nil
+	return db.file.Close()
+}
@@ -17,11 +17,14 @@
 package ipdb
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/json"
 	"io"
 	"net"
+	"os"
 	"strings"
 	"time"
 
@@ -35,26 +38,37 @@ const (
 
 // Writer provides functionalities to write IP data into IPDB format.
 type Writer struct {
-	meta      *model.Meta    // Metadata for the IP database
-	ipdbMeta  Meta           // Metadata for IPDB format
-	node      [][2]int       // Node data for IPDB format
-	dataHash  map[string]int // Data hash for IPDB format
-	dataChunk *bytes.Buffer  // Data chunk buffer for IPDB format
+	meta      *model.Meta               // Metadata for the IP database
+	ipdbMeta  Meta                      // Metadata for IPDB format
+	node      [][2]int                  // Node data for IPDB format, kept in memory until the output is written
+	dataHash  map[[sha256.Size]byte]int // Offsets of the saved data keyed by their hash
+	dataChunk *bytes.Buffer             // Data chunk buffer for IPDB format
+
+	output    io.Writer     // Output bound by Open
+	dataFile  *os.File      // Temporary file holding the data chunk between Open and Close
+	dataSpill *bufio.Writer // Buffered writer of dataFile
+	dataSize  int           // Size of the data chunk in dataFile
 }
 
 // NewWriter initializes a new Writer instance for writing IP data in IPDB format.
+// The build time of the source database is kept if it is known.
 func NewWriter(meta *model.Meta) (*Writer, error) {
+	build := time.Now()
+	if !meta.BuildTime.IsZero() {
+		build = meta.BuildTime
+	}
+
 	return &Writer{
 		meta: meta,
 		ipdbMeta: Meta{
-			Build:     int(time.Now().Unix()),
+			Build:     int(build.Unix()),
 			IPVersion: meta.IPVersion,
 			Languages: map[string]int{"CN": 0},
 			Fields:    model.ConvertToDBFields(meta.Fields, meta.FieldAlias, CommonFieldsAlias),
 		},
 		node:      [][2]int{{}},
 		dataChunk: &bytes.Buffer{},
-		dataHash:  make(map[string]int),
+		dataHash:  make(map[[sha256.Size]byte]int),
 	}, nil
 }
 
@@ -73,7 +87,7 @@ func (w *Writer) SetOption(option interface{}) error {
 		return nil
 	}
 
-	return nil
+	return errors.ErrUnsupportedOption
 }
 
 // Insert adds the given IP information into the writer.
@@ -92,8 +106,74 @@ func (w *Writer) Insert(info *model.IPInfo) error {
 	return nil
 }
 
+// Open binds the Writer to the output. Until Close, the data chunk is kept
+// in a temporary file instead of memory, only the node tree and a hash of each
+// distinct data stay in memory.
+func (w *Writer) Open(iw io.Writer) error {
+	if iw == nil {
+		return errors.ErrNilWriter
+	}
+	f, err := os.CreateTemp("", "ips-ipdb-*.data")
+	if err != nil {
+		return err
+	}
+	w.output = iw
+	w.dataFile = f
+	w.dataSpill = bufio.NewWriter(f)
+	w.dataSize = w.dataChunk.Len()
+	if _, err := w.dataChunk.WriteTo(w.dataSpill); err != nil {
+		_ = w.closeDataFile()
+		return err
+	}
+
+	return nil
+}
+
+// Close writes the IP data into the output bound by Open and removes the temporary file.
+func (w *Writer) Close() error {
+	if w.dataFile == nil {
+		return nil
+	}
+	defer func() {
+		_ = w.closeDataFile()
+	}()
+
+	if err := w.dataSpill.Flush(); err != nil {
+		return err
+	}
+	if _, err := w.dataFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := w.writeChunks(w.output, w.dataFile, w.dataSize)
+	return err
+}
+
+// Abort removes the temporary file without writing to the output bound by Open.
+func (w *Writer) Abort() error {
+	if w.dataFile == nil {
+		return nil
+	}
+	return w.closeDataFile()
+}
+
+// closeDataFile closes and removes the temporary data file.
+func (w *Writer) closeDataFile() error {
+	name := w.dataFile.Name()
+	err := w.dataFile.Close()
+	if rmErr := os.Remove(name); err == nil {
+		err = rmErr
+	}
+	w.output, w.dataFile, w.dataSpill, w.dataSize = nil, nil, nil, 0
+	return err
+}
+
 // WriteTo writes the IP data into the provided writer in IPDB format.
 func (w *Writer) WriteTo(iw io.Writer) (int64, error) {
+	return w.writeChunks(iw, w.dataChunk, w.dataChunk.Len())
+}
+
+// writeChunks writes the meta chunk, the node chunk and the data chunk read from data.
+func (w *Writer) writeChunks(iw io.Writer, data io.Reader, dataSize int) (int64, error) {
 
 	// Node Chunk
 	nodeChunk := &bytes.Buffer{}
@@ -115,7 +195,7 @@ func (w *Writer) WriteTo(iw io.Writer) (int64, error) {
 
 	// MetaData Chunk
 	metaDataChunk := &bytes.Buffer{}
-	w.ipdbMeta.TotalSize = nodeChunk.Len() + w.dataChunk.Len()
+	w.ipdbMeta.TotalSize = nodeChunk.Len() + dataSize
 	metaData, err := json.Marshal(w.ipdbMeta)
 	if err != nil {
 		return 0, err
@@ -130,7 +210,7 @@ func (w *Writer) WriteTo(iw io.Writer) (int64, error) {
 	if _, err := nodeChunk.WriteTo(iw); err != nil {
 		return 0, err
 	}
-	if _, err := w.dataChunk.WriteTo(iw); err != nil {
+	if _, err := io.Copy(iw, data); err != nil {
 		return 0, err
 	}
 
@@ -158,6 +238,20 @@ func (w *Writer) insert(ipNet *net.IPNet, values []string) error {
 func (w *Writer) Resolve(offset int) string {
 	offset -= 8
 	data := w.dataChunk.Bytes()
+	if w.dataFile != nil {
+		if w.dataSpill.Flush() != nil || offset+2 > w.dataSize {
+			return ""
+		}
+		head := make([]byte, 2)
+		if _, err := w.dataFile.ReadAt(head, int64(offset)); err != nil {
+			return ""
+		}
+		data = make([]byte, binary.BigEndian.Uint16(head))
+		if _, err := w.dataFile.ReadAt(data, int64(offset+2)); err != nil {
+			return ""
+		}
+		return string(data)
+	}
 	size := int(binary.BigEndian.Uint16(data[offset : offset+2]))
 	if (offset + 2 + size) > len(data) {
 		return ""
@@ -194,18 +288,34 @@ func (w *Writer) Nodes(ip net.IP, mask int) (node, index int, ok bool) {
 }
 
 // Fields 保存数据并返回数据的偏移量
-// 相同的数据仅保存一份
+// 相同的数据仅保存一份，按数据的 sha256 去重，内存中不保留数据本身
 // 数据格式 2 byte length + n byte data
 func (w *Writer) Fields(fields []string) int {
 	data := strings.Join(fields, FieldsSep)
-	if _, ok := w.dataHash[data]; !ok {
+	key := sha256.Sum256([]byte(data))
+	if _, ok := w.dataHash[key]; !ok {
 		_data := []byte(data)
 		// +8 是由于 loopBack node 占用了8byte
-		w.dataHash[data] = w.dataChunk.Len() + 8
-		w.dataChunk.Write(IntToBinaryBE(len(_data), 16))
-		w.dataChunk.Write(_data)
+		chunk := io.Writer(w.dataChunk)
+		if w.dataSpill != nil {
+			chunk = w.dataSpill
+		}
+		w.dataHash[key] = w.dataLen() + 8
+		_, _ = chunk.Write(IntToBinaryBE(len(_data), 16))
+		_, _ = chunk.Write(_data)
+		if w.dataSpill != nil {
+			w.dataSize += 2 + len(_data)
+		}
+	}
+	return w.dataHash[key]
+}
+
+// dataLen returns the size of the data chunk.
+func (w *Writer) dataLen() int {
+	if w.dataFile != nil {
+		return w.dataSize
 	}
-	return w.dataHash[data]
+	return w.dataChunk.Len()
 }
 
 // IntToBinaryBE 将int转换为 binary big endian
@@ -17,9 +17,12 @@
 package ipdb
 
 import (
+	"bytes"
 	"io"
 	"net"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -104,3 +107,62 @@ func TestWriter(t *testing.T) {
 	ast.Equal(model.IPv4, writer.ipdbMeta.IPVersion)
 	ast.Equal(1296, writer.ipdbMeta.TotalSize) // nodeChunk(160 * 8 + loopNode(8byte)) + dataChunk(8byte)
 }
+
+func TestWriterOpen(t *testing.T) {
+	ast := assert.New(t)
+
+	meta := &model.Meta{
+		IPVersion: model.IPv4,
+		Fields:    []string{"field1"},
+		BuildTime: time.Date(2023, 10, 25, 0, 0, 0, 0, time.UTC),
+	}
+	insert := func(w *Writer) {
+		for _, record := range [][2]string{{"1.0.0.0/24", "A"}, {"1.0.1.0/24", "B"}, {"8.8.8.0/24", "A"}} {
+			ip, ipNet, err := net.ParseCIDR(record[0])
+			ast.Nil(err)
+			ast.Nil(w.Insert(&model.IPInfo{
+				IP:     ip,
+				IPNet:  ipnet.NewRange(ipNet),
+				Data:   map[string]string{"field1": record[1]},
+				Fields: []string{"field1"},
+			}))
+		}
+	}
+
+	buffered, err := NewWriter(meta)
+	ast.Nil(err)
+	insert(buffered)
+	expected := &bytes.Buffer{}
+	_, err = buffered.WriteTo(expected)
+	ast.Nil(err)
+
+	streamed, err := NewWriter(meta)
+	ast.Nil(err)
+	ast.Equal(errors.ErrNilWriter, streamed.Open(nil))
+	output := &bytes.Buffer{}
+	ast.Nil(streamed.Open(output))
+	insert(streamed)
+	ast.Equal(0, streamed.dataChunk.Len())
+	ast.Equal("B", streamed.Resolve(streamed.Fields([]string{"B"})))
+	ast.Equal(0, output.Len())
+
+	name := streamed.dataFile.Name()
+	ast.Nil(streamed.Close())
+	ast.Equal(expected.Bytes(), output.Bytes())
+	_, err = os.Stat(name)
+	ast.True(os.IsNotExist(err))
+	ast.Nil(streamed.Close())
+
+	aborted, err := NewWriter(meta)
+	ast.Nil(err)
+	output.Reset()
+	ast.Nil(aborted.Open(output))
+	insert(aborted)
+	name = aborted.dataFile.Name()
+	ast.Nil(aborted.Abort())
+	ast.Equal(0, output.Len())
+	_, err = os.Stat(name)
+	ast.True(os.IsNotExist(err))
+	ast.Nil(aborted.Close())
+	ast.Equal(0, output.Len())
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jsonl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+const (
+	DBFormat = "jsonl"
+	DBExt    = ".jsonl"
+)
+
+// Writer provides functionalities to write IP data as JSON Lines,
+// one object per network: {"net":"1.0.0.0/24","data":{"country":"中国",...}}.
+// The fields of data keep the order of the fields, typed values are written as JSON numbers and booleans.
+type Writer struct {
+	meta   *model.Meta
+	iw     io.Writer
+	buffer *bytes.Buffer
+	bw     *bufio.Writer
+}
+
+// NewWriter initializes a new Writer instance for writing IP data as JSON Lines.
+func NewWriter(meta *model.Meta) (*Writer, error) {
+	return &Writer{
+		meta: meta,
+	}, nil
+}
+
+// SetOption sets the provided options to the Writer, the Writer has no options.
+func (w *Writer) SetOption(option interface{}) error {
+	return errors.ErrUnsupportedOption
+}
+
+// Insert adds the given IP information into the writer.
+func (w *Writer) Insert(info *model.IPInfo) error {
+	if w.iw == nil {
+		w.buffer = &bytes.Buffer{}
+		w.iw = w.buffer
+	}
+
+	data, err := marshalData(info.Fields, info.TypedValues())
+	if err != nil {
+		return err
+	}
+	for _, ipNet := range info.IPNet.IPNets() {
+		cidr, _ := json.Marshal(ipNet.String())
+		if _, err := fmt.Fprintf(w.iw, "{\"net\":%s,\"data\":%s}\n", cidr, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// marshalData encodes the fields and their values as a JSON object in the order of the fields.
+func marshalData(fields []string, values []model.Value) ([]byte, error) {
+	buf := bytes.NewBufferString("{")
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(field)
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// Open binds the Writer to the output, the records inserted afterwards are streamed to the output.
+func (w *Writer) Open(writer io.Writer) error {
+	if writer == nil {
+		return errors.ErrNilWriter
+	}
+	w.bw = bufio.NewWriter(writer)
+	w.iw = w.bw
+	w.buffer = nil
+
+	return nil
+}
+
+// Close flushes the records that are not written to the output yet.
+func (w *Writer) Close() error {
+	if w.bw == nil {
+		return nil
+	}
+	err := w.bw.Flush()
+	w.bw = nil
+	w.iw = nil
+
+	return err
+}
+
+// Abort drops the records that are not written to the output yet.
+func (w *Writer) Abort() error {
+	w.bw = nil
+	w.iw = nil
+
+	return nil
+}
+
+// WriteTo writes the buffered data into the provided writer.
+func (w *Writer) WriteTo(writer io.Writer) (int64, error) {
+	if w.buffer == nil {
+		return 0, nil
+	}
+
+	return w.buffer.WriteTo(writer)
+}
+
+// WriterFormat returns the format of the writer.
+func (w *Writer) WriterFormat() string {
+	return DBFormat
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jsonl
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+func TestWriter(t *testing.T) {
+	ast := assert.New(t)
+
+	meta := &model.Meta{
+		IPVersion: model.IPv4,
+		Fields:    []string{"country", "asn"},
+	}
+	insert := func(w *Writer) {
+		for _, record := range []struct {
+			cidr    string
+			country string
+			asn     int64
+		}{{"1.0.0.0/24", "A\"", 13335}, {"1.0.1.0/24", "B", 4134}} {
+			ip, ipNet, err := net.ParseCIDR(record.cidr)
+			ast.Nil(err)
+			info := &model.IPInfo{
+				IP:     ip,
+				IPNet:  ipnet.NewRange(ipNet),
+				Fields: meta.Fields,
+			}
+			info.SetValue("country", model.StringValue(record.country))
+			info.SetValue("asn", model.IntValue(record.asn))
+			ast.Nil(w.Insert(info))
+		}
+	}
+	expected := `{"net":"1.0.0.0/24","data":{"country":"A\"","asn":13335}}` + "\n" +
+		`{"net":"1.0.1.0/24","data":{"country":"B","asn":4134}}` + "\n"
+
+	buffered, err := NewWriter(meta)
+	ast.Nil(err)
+	ast.Equal(errors.ErrUnsupportedOption, buffered.SetOption(nil))
+	insert(buffered)
+	output := &bytes.Buffer{}
+	_, err = buffered.WriteTo(output)
+	ast.Nil(err)
+	ast.Equal(expected, output.String())
+
+	streamed, err := NewWriter(meta)
+	ast.Nil(err)
+	ast.Equal(errors.ErrNilWriter, streamed.Open(nil))
+	output.Reset()
+	ast.Nil(streamed.Open(output))
+	insert(streamed)
+	ast.Nil(streamed.Close())
+	ast.Equal(expected, output.String())
+
+	aborted, err := NewWriter(meta)
+	ast.Nil(err)
+	output.Reset()
+	ast.Nil(aborted.Open(output))
+	insert(aborted)
+	ast.Nil(aborted.Abort())
+	ast.Nil(aborted.Close())
+	ast.Equal(0, output.Len())
+}
@@ -74,16 +74,63 @@ const (
 
 	// FieldAutonomousSystemOrganization 自治系统组织
 	FieldAutonomousSystemOrganization = "autonomous_system_organization"
+
+	// Traits Fields
+
+	// FieldISP 运营商
+	FieldISP = "isp"
+
+	// FieldConnectionType 连接类型
+	FieldConnectionType = "connection_type"
+
+	// FieldDomain 域名
+	FieldDomain = "domain"
+
+	// FieldUserType 用户类型
+	FieldUserType = "user_type"
 )
 
+// FullFields 全字段列表
+var FullFields = []string{
+	FieldCity,
+	FieldContinent,
+	FieldCountry,
+	FieldSubdivisions,
+	FieldAccuracyRadius,
+	FieldLatitude,
+	FieldLongitude,
+	FieldMetroCode,
+	FieldTimeZone,
+	FieldPostalCode,
+	FieldRegisteredCountry,
+	FieldRepresentedCountry,
+	FieldRepresentedCountryType,
+	FieldIsAnonymousProxy,
+	FieldIsSatelliteProvider,
+	FieldAutonomousSystemNumber,
+	FieldAutonomousSystemOrganization,
+	FieldISP,
+	FieldConnectionType,
+	FieldDomain,
+	FieldUserType,
+}
+
 // CommonFieldsAlias 公共字段到数据库字段映射
 var CommonFieldsAlias = map[string]string{
-	model.Country:   FieldCountry,
-	model.City:      FieldCity,
-	model.Continent: FieldContinent,
-	model.Province:  FieldSubdivisions,
-	model.UTCOffset: FieldTimeZone,
-	model.Latitude:  FieldLatitude,
-	model.Longitude: FieldLongitude,
-	model.ASN:       FieldAutonomousSystemNumber,
+	model.Country:        FieldCountry,
+	model.City:           FieldCity,
+	model.Continent:      FieldContinent,
+	model.Province:       FieldSubdivisions,
+	model.ISP:            FieldISP,
+	model.UTCOffset:      FieldTimeZone,
+	model.TimeZone:       FieldTimeZone,
+	model.Latitude:       FieldLatitude,
+	model.Longitude:      FieldLongitude,
+	model.ASN:            FieldAutonomousSystemNumber,
+	model.ASOrganization: FieldAutonomousSystemOrganization,
+	model.PostalCode:     FieldPostalCode,
+	model.AccuracyRadius: FieldAccuracyRadius,
+	model.ConnectionType: FieldConnectionType,
+	model.Domain:         FieldDomain,
+	model.UsageType:      FieldUserType,
 }
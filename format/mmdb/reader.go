@@ -18,8 +18,11 @@ package mmdb
 
 import (
 	"net"
+	"time"
 
 	"github.com/sjzar/ips/format/mmdb/sdk"
+	"github.com/sjzar/ips/format/option"
+	"github.com/sjzar/ips/pkg/errors"
 	"github.com/sjzar/ips/pkg/model"
 )
 
@@ -51,6 +54,13 @@ func NewReader(file string) (*Reader, error) {
 	}
 	meta.AddCommonFieldAlias(CommonFieldsAlias)
 
+	metadata := db.Metadata()
+	if metadata.BuildEpoch > 0 {
+		meta.BuildTime = time.Unix(int64(metadata.BuildEpoch), 0).In(time.UTC)
+	}
+	meta.Description = model.PickDescription(metadata.Description, metadata.DatabaseType)
+	meta.Languages = metadata.Languages
+
 	return &Reader{
 		meta: meta,
 		db:   db,
@@ -59,7 +69,7 @@ func NewReader(file string) (*Reader, error) {
 
 // Find retrieves IP information based on the given IP address.
 func (r *Reader) Find(ip net.IP) (*model.IPInfo, error) {
-	ipNet, data, err := r.db.Find(ip)
+	ipNet, data, err := r.db.FindValues(ip)
 	if err != nil {
 		return nil, err
 	}
@@ -67,9 +77,12 @@ func (r *Reader) Find(ip net.IP) (*model.IPInfo, error) {
 	ret := &model.IPInfo{
 		IP:     ip,
 		IPNet:  ipNet,
-		Data:   data,
+		Data:   make(map[string]string, len(data)),
 		Fields: r.meta.Fields,
 	}
+	for field, value := range data {
+		ret.SetValue(field, value)
+	}
 	ret.AddCommonFieldAlias(CommonFieldsAlias)
 
 	return ret, nil
@@ -80,6 +93,20 @@ func (r *Reader) Meta() *model.Meta {
 	return r.meta
 }
 
+// ReaderOptions lists the options supported by the Reader.
+var ReaderOptions = option.Specs{
+	{Name: "disable_extra_data", Type: option.Bool, Default: "false", Description: "Do not supplement data matched via GeoNameID."},
+	{Name: "use_full_field", Type: option.Bool, Default: "false", Description: "Combine all data into a single JSON string field."},
+}
+
+// NewReaderOption converts validated option values into a ReaderOption.
+func NewReaderOption(values option.Values) ReaderOption {
+	return ReaderOption{
+		DisableExtraData: values.Bool("disable_extra_data"),
+		UseFullField:     values.Bool("use_full_field"),
+	}
+}
+
 // ReaderOption contains configuration options for the Reader.
 type ReaderOption struct {
 	DisableExtraData bool // If true, extra data (matched via GeoNameID) won't be used.
@@ -88,11 +115,13 @@ type ReaderOption struct {
 
 // SetOption applies the provided option to the Reader's configuration.
 func (r *Reader) SetOption(option interface{}) error {
-	if opt, ok := option.(ReaderOption); ok {
-		r.db.DisableExtraData = opt.DisableExtraData
-		r.db.UseFullField = opt.UseFullField
-		r.option = opt
+	opt, ok := option.(ReaderOption)
+	if !ok {
+		return errors.ErrUnsupportedOption
 	}
+	r.db.DisableExtraData = opt.DisableExtraData
+	r.db.UseFullField = opt.UseFullField
+	r.option = opt
 	return nil
 }
 
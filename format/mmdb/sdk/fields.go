@@ -16,6 +16,10 @@
 
 package sdk
 
+import (
+	"github.com/sjzar/ips/pkg/model"
+)
+
 // PreProcessFieldAlias maps fields from various sources to a standardized format.
 // This is useful for normalizing field names from different IP information providers.
 var PreProcessFieldAlias = map[string]string{
@@ -59,3 +63,14 @@ func DoPreProcessFieldAlias(data map[string]string) map[string]string {
 	}
 	return data
 }
+
+// DoPreProcessValueAlias is like DoPreProcessFieldAlias for typed values.
+func DoPreProcessValueAlias(data map[string]model.Value) map[string]model.Value {
+	for k, v := range PreProcessFieldAlias {
+		if _, ok := data[k]; ok {
+			data[v] = data[k]
+			delete(data, k)
+		}
+	}
+	return data
+}
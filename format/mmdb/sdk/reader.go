@@ -21,13 +21,12 @@ import (
 	"fmt"
 	"net"
 	"reflect"
-	"strconv"
-	"strings"
 
 	"github.com/oschwald/maxminddb-golang"
 
 	"github.com/sjzar/ips/format/geo"
 	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
 	"github.com/sjzar/ips/pkg/model"
 )
 
@@ -80,14 +79,32 @@ func NewReader(file string) (*Reader, error) {
 }
 
 // Find looks up the given IP in the database and returns its associated range and data.
+// It returns errors.NotFoundError if the database has no record for ip.
 func (r *Reader) Find(ip net.IP) (*ipnet.Range, map[string]string, error) {
+	ipr, values, err := r.FindValues(ip)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data := make(map[string]string, len(values))
+	for key, value := range values {
+		data[key] = value.String()
+	}
+	return ipr, data, nil
+}
+
+// FindValues is like Find but keeps the types of the values.
+func (r *Reader) FindValues(ip net.IP) (*ipnet.Range, map[string]model.Value, error) {
 	var m map[string]interface{}
-	ipNet, _, err := r.db.LookupNetwork(ip, &m)
+	ipNet, ok, err := r.db.LookupNetwork(ip, &m)
 	if err != nil {
 		return nil, nil, err
 	}
+	if !ok {
+		return nil, nil, errors.NewNotFoundError(ip, ipnet.NewRange(ipNet))
+	}
 
-	data, err := ConvertMapToFields(m, r.UseFullField)
+	data, err := ConvertMapToValues(m, r.UseFullField)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -95,6 +112,11 @@ func (r *Reader) Find(ip net.IP) (*ipnet.Range, map[string]string, error) {
 	return ipnet.NewRange(ipNet), data, nil
 }
 
+// Metadata returns the metadata of the MMDB database.
+func (r *Reader) Metadata() maxminddb.Metadata {
+	return r.db.Metadata
+}
+
 // Close closes the underlying maxminddb Reader.
 func (r *Reader) Close() error {
 	return r.db.Close()
@@ -103,7 +125,22 @@ func (r *Reader) Close() error {
 // ConvertMapToFields converts a map of data from MMDB into a map of strings,
 // optionally using full fields (combining all data into a JSON string).
 func ConvertMapToFields(m map[string]interface{}, useFullField bool) (map[string]string, error) {
-	data := make(map[string]string)
+	values, err := ConvertMapToValues(m, useFullField)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]string, len(values))
+	for key, value := range values {
+		data[key] = value.String()
+	}
+	return data, nil
+}
+
+// ConvertMapToValues is like ConvertMapToFields but keeps the types of the values.
+// The string views of the values are the strings returned by ConvertMapToFields.
+func ConvertMapToValues(m map[string]interface{}, useFullField bool) (map[string]model.Value, error) {
+	data := make(map[string]model.Value)
 
 	for key, _value := range m {
 		if useFullField {
@@ -111,29 +148,48 @@ func ConvertMapToFields(m map[string]interface{}, useFullField bool) (map[string
 			if err != nil {
 				return nil, err
 			}
-			data[key] = string(jsonValue)
+			data[key] = model.StringValue(string(jsonValue))
 			continue
 		}
 
-		value, err := ParseReflectValue(_value)
+		value, err := parseReflectValue(_value)
 		if err != nil {
 			return nil, err
 		}
 		switch v := value.(type) {
-		case string:
+		case model.Value:
 			data[key] = v
-		case map[string]string:
+		case map[string]model.Value:
 			for subKey, subValue := range v {
 				data[key+"_"+subKey] = subValue
 			}
 		}
 	}
 
-	return DoPreProcessFieldAlias(data), nil
+	return DoPreProcessValueAlias(data), nil
 }
 
 // ParseReflectValue processes an interface value and returns its converted string or map representation.
 func ParseReflectValue(value interface{}) (interface{}, error) {
+	value, err := parseReflectValue(value)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case model.Value:
+		return v.String(), nil
+	case map[string]model.Value:
+		data := make(map[string]string, len(v))
+		for key, subValue := range v {
+			data[key] = subValue.String()
+		}
+		return data, nil
+	}
+	return value, nil
+}
+
+// parseReflectValue is like ParseReflectValue but returns a value or a flattened map of values.
+func parseReflectValue(value interface{}) (interface{}, error) {
 	if value == nil {
 		return nil, nil
 	}
@@ -146,7 +202,7 @@ func ParseReflectValue(value interface{}) (interface{}, error) {
 	return processReflectValue(val)
 }
 
-// processReflectValue processes a reflect.Value and returns its converted string or map representation.
+// processReflectValue processes a reflect.Value and returns its converted value or flattened map representation.
 func processReflectValue(val reflect.Value) (interface{}, error) {
 	switch val.Kind() {
 	case reflect.Struct:
@@ -164,9 +220,9 @@ func processReflectValue(val reflect.Value) (interface{}, error) {
 	}
 }
 
-// handleStruct processes the provided reflect.Value of a struct type and converts it into a map of strings.
-func handleStruct(val reflect.Value) (map[string]string, error) {
-	data := make(map[string]string)
+// handleStruct processes the provided reflect.Value of a struct type and converts it into a flattened map of values.
+func handleStruct(val reflect.Value) (map[string]model.Value, error) {
+	data := make(map[string]model.Value)
 	typ := val.Type()
 	for i := 0; i < val.NumField(); i++ {
 		field := val.Field(i)
@@ -176,9 +232,9 @@ func handleStruct(val reflect.Value) (map[string]string, error) {
 			return nil, err
 		}
 		switch v := parseValue.(type) {
-		case string:
+		case model.Value:
 			data[fieldType.Name] = v
-		case map[string]string:
+		case map[string]model.Value:
 			for subKey, subValue := range v {
 				data[fieldType.Name+"_"+subKey] = subValue
 			}
@@ -187,22 +243,22 @@ func handleStruct(val reflect.Value) (map[string]string, error) {
 	return data, nil
 }
 
-// handleMap processes the provided reflect.Value of a map type and converts it into a map of strings or a single string.
+// handleMap processes the provided reflect.Value of a map type and converts it into a flattened map of values or a single value.
 func handleMap(val reflect.Value) (interface{}, error) {
 	geoInfo, ok := geo.ParseInfoFromMMDB(val.Interface().(map[string]interface{}), false)
 	if ok {
-		return geoInfo.Name(geo.Language), nil
+		return model.StringValue(geoInfo.Name(geo.Language)), nil
 	}
-	data := make(map[string]string)
+	data := make(map[string]model.Value)
 	for _, key := range val.MapKeys() {
 		parseValue, err := processReflectValue(val.MapIndex(key))
 		if err != nil {
 			return nil, err
 		}
 		switch v := parseValue.(type) {
-		case string:
+		case model.Value:
 			data[key.String()] = v
-		case map[string]string:
+		case map[string]model.Value:
 			for subKey, subValue := range v {
 				data[key.String()+"_"+subKey] = subValue
 			}
@@ -211,42 +267,37 @@ func handleMap(val reflect.Value) (interface{}, error) {
 	return data, nil
 }
 
-// handleSliceAndArray processes the provided reflect.Value of a slice or array type and converts it into a string.
+// handleSliceAndArray processes the provided reflect.Value of a slice or array type and converts it into a list value.
+// Its string view joins the items with commas, maps are written as JSON objects.
 func handleSliceAndArray(val reflect.Value) (interface{}, error) {
-	subData := make([]string, 0, val.Len())
+	subData := make([]model.Value, 0, val.Len())
 	for i := 0; i < val.Len(); i++ {
 		parseValue, err := processReflectValue(val.Index(i))
 		if err != nil {
 			return nil, err
 		}
 		switch v := parseValue.(type) {
-		case string:
+		case model.Value:
 			subData = append(subData, v)
-		case map[string]string:
-			outJson, err := json.Marshal(v)
-			if err != nil {
-				return nil, err
-			}
-			subData = append(subData, string(outJson))
+		case map[string]model.Value:
+			subData = append(subData, model.MapValue(v))
 		}
 	}
-	return strings.Join(subData, ","), nil
+	return model.ListValue(subData), nil
 }
 
-// handleSimpleType converts the provided reflect.Value of a basic type into a string.
-func handleSimpleType(val reflect.Value) string {
+// handleSimpleType converts the provided reflect.Value of a basic type into a value.
+// Floats keep six decimal places in their string view.
+func handleSimpleType(val reflect.Value) model.Value {
 	switch val.Kind() {
 	case reflect.String:
-		return val.String()
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return strconv.FormatInt(val.Int(), 10)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return strconv.FormatUint(val.Uint(), 10)
+		return model.StringValue(val.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Bool:
+		return model.NewValue(val.Interface())
 	case reflect.Float32, reflect.Float64:
-		return fmt.Sprintf("%.6f", val.Float())
-	case reflect.Bool:
-		return strconv.FormatBool(val.Bool())
+		return model.FloatValue(val.Float()).WithString(fmt.Sprintf("%.6f", val.Float()))
 	}
 
-	return ""
+	return model.StringValue("")
 }
@@ -20,6 +20,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/model"
 )
 
 func TestConvertMapToFields(t *testing.T) {
@@ -181,3 +183,36 @@ func TestConvertMapToFields(t *testing.T) {
 		}
 	}
 }
+
+func TestConvertMapToValues(t *testing.T) {
+	ast := assert.New(t)
+
+	values, err := ConvertMapToValues(map[string]interface{}{
+		"int":   uint32(123),
+		"float": 1.5,
+		"bool":  true,
+		"list":  []interface{}{"a", uint16(1)},
+		"map": map[string]interface{}{
+			"sub": "value",
+		},
+	}, false)
+	ast.Nil(err)
+
+	ast.Equal(model.TypeInt, values["int"].Type())
+	ast.Equal(int64(123), values["int"].Interface())
+	ast.Equal(model.TypeFloat, values["float"].Type())
+	ast.Equal("1.500000", values["float"].String())
+	ast.Equal(true, values["bool"].Interface())
+	ast.Equal([]interface{}{"a", int64(1)}, values["list"].Interface())
+	ast.Equal("a,1", values["list"].String())
+	ast.Equal(model.TypeString, values["map_sub"].Type())
+	ast.Equal("value", values["map_sub"].String())
+
+	// ParseReflectValue keeps returning strings
+	value, err := ParseReflectValue([]interface{}{"a", uint16(1)})
+	ast.Nil(err)
+	ast.Equal("a,1", value)
+	value, err = ParseReflectValue(map[string]interface{}{"sub": 1.5})
+	ast.Nil(err)
+	ast.Equal(map[string]string{"sub": "1.500000"}, value)
+}
@@ -18,20 +18,23 @@ package mmdb
 
 import (
 	"io"
+	"math"
 	"net"
 	"reflect"
-	"strconv"
 	"strings"
 
 	"github.com/maxmind/mmdbwriter"
 	"github.com/maxmind/mmdbwriter/mmdbtype"
 
 	"github.com/sjzar/ips/format/geo"
+	"github.com/sjzar/ips/format/option"
 	"github.com/sjzar/ips/pkg/errors"
 	"github.com/sjzar/ips/pkg/model"
 )
 
 // Writer provides functionalities to write IP data into MMDB format.
+// It is not a format.StreamWriter: the search tree is kept in memory by mmdbwriter
+// until WriteTo, since it is only complete after the last network is inserted.
 type Writer struct {
 	meta   *model.Meta      // Metadata for the IP database
 	writer *mmdbwriter.Tree // MMDB writer instance
@@ -39,10 +42,17 @@ type Writer struct {
 }
 
 // NewWriter initializes a new Writer instance for writing IP data in MMDB format.
+// The build time and description of the source database are kept if they are known.
 func NewWriter(meta *model.Meta) (*Writer, error) {
 	opts := mmdbwriter.Options{
 		DatabaseType: "GeoIP2-City",
 	}
+	if !meta.BuildTime.IsZero() {
+		opts.BuildEpoch = meta.BuildTime.Unix()
+	}
+	if meta.Description != "" {
+		opts.Description = map[string]string{"en": meta.Description}
+	}
 
 	writer, err := mmdbwriter.New(opts)
 	if err != nil {
@@ -55,6 +65,16 @@ func NewWriter(meta *model.Meta) (*Writer, error) {
 	}, nil
 }
 
+// WriterOptions lists the options supported by the Writer.
+var WriterOptions = option.Specs{
+	{Name: "select_languages", Type: option.String, Default: "", Description: "Languages of the names to keep, separated by commas. \"-\" drops all names, empty keeps all."},
+}
+
+// NewWriterOption converts validated option values into a WriterOption.
+func NewWriterOption(values option.Values) WriterOption {
+	return WriterOption{SelectLanguages: values.String("select_languages")}
+}
+
 // WriterOption provides options for the Writer.
 type WriterOption struct {
 	SelectLanguages string // SelectLanguages specifies the languages to be selected for the names.
@@ -73,14 +93,15 @@ func (w *Writer) SetOption(option interface{}) error {
 			return err
 		}
 		w.writer = writer
+		return nil
 	}
-	return nil
+	return errors.ErrUnsupportedOption
 }
 
 // Insert adds the given IP information into the writer.
 func (w *Writer) Insert(info *model.IPInfo) error {
 	fields := model.ConvertToDBFields(w.meta.Fields, w.meta.FieldAlias, CommonFieldsAlias)
-	values := info.Values()
+	values := info.TypedValues()
 	if len(values) != len(fields) {
 		return errors.ErrMismatchedFieldsLength
 	}
@@ -125,29 +146,30 @@ func (w *Writer) WriterFormat() string {
 }
 
 // ConvertMap converts fields and values to a map.
-func (w *Writer) ConvertMap(fields, values []string) map[string]interface{} {
+// Typed values keep their types, and the values of known GeoIP2 fields are converted to their GeoIP2 types.
+func (w *Writer) ConvertMap(fields []string, values []model.Value) map[string]interface{} {
 	ret := make(map[string]interface{})
 	for i := range fields {
 		value := values[i]
-		if len(value) == 0 {
+		if len(value.String()) == 0 {
 			continue
 		}
 		var convertedValue interface{}
 		switch fields[i] {
 		case FieldCity, FieldContinent, FieldCountry, FieldRegisteredCountry, FieldRepresentedCountry:
-			convertedValue = w.convertGeoInfo(fields[i], value)
+			convertedValue = w.convertGeoInfo(fields[i], value.String())
 		case FieldSubdivisions:
-			convertedValue = w.convertSubdivisions(value)
+			convertedValue = w.convertSubdivisions(value.String())
 		case FieldAccuracyRadius, FieldMetroCode, FieldLatitude, FieldLongitude, FieldTimeZone:
 			ret = w.convertLocation(fields[i], value, ret)
 		case FieldPostalCode:
 			convertedValue = map[string]interface{}{
-				"code": value,
+				"code": value.String(),
 			}
 		case FieldIsAnonymousProxy, FieldIsSatelliteProvider, "is_legitimate_proxy", FieldAutonomousSystemNumber:
 			ret = w.convertTraits(fields[i], value, ret)
 		default:
-			convertedValue = value
+			convertedValue = value.Interface()
 		}
 		if convertedValue != nil {
 			ret[fields[i]] = convertedValue
@@ -166,33 +188,33 @@ func (w *Writer) convertGeoInfo(field, value string) interface{} {
 }
 
 // convertLocation handles the conversion for location related fields.
-func (w *Writer) convertLocation(field, value string, ret map[string]interface{}) map[string]interface{} {
+func (w *Writer) convertLocation(field string, value model.Value, ret map[string]interface{}) map[string]interface{} {
 	dataMap, _ := getOrCreateMap(ret, "location")
 	switch field {
 	case FieldAccuracyRadius, FieldMetroCode:
-		if parsedValue, err := strconv.ParseUint(value, 10, 16); err == nil {
-			dataMap[field] = parsedValue
+		if parsedValue, ok := value.Int(); ok && parsedValue >= 0 && parsedValue <= math.MaxUint16 {
+			dataMap[field] = uint64(parsedValue)
 		}
 	case FieldLatitude, FieldLongitude:
-		if parsedValue, err := strconv.ParseFloat(value, 64); err == nil {
+		if parsedValue, ok := value.Float(); ok {
 			dataMap[field] = parsedValue
 		}
 	case FieldTimeZone:
-		dataMap[field] = value
+		dataMap[field] = value.String()
 	}
 	return ret
 }
 
 // convertTraits handles the conversion for trait related fields.
-func (w *Writer) convertTraits(field, value string, ret map[string]interface{}) map[string]interface{} {
+func (w *Writer) convertTraits(field string, value model.Value, ret map[string]interface{}) map[string]interface{} {
 	dataMap, _ := getOrCreateMap(ret, "traits")
 	switch field {
 	case FieldIsAnonymousProxy, FieldIsSatelliteProvider, "is_legitimate_proxy":
-		if parsedValue, err := strconv.ParseBool(value); err == nil {
+		if parsedValue, ok := value.Bool(); ok {
 			dataMap[field] = parsedValue
 		}
 	case FieldAutonomousSystemNumber:
-		if parsedValue, err := strconv.ParseUint(value, 10, 64); err == nil {
+		if parsedValue, ok := value.Int(); ok && parsedValue >= 0 {
 			dataMap[field] = mmdbtype.Uint64(parsedValue)
 		}
 	}
@@ -309,7 +331,15 @@ func handleSimpleType(val reflect.Value) mmdbtype.DataType {
 	case reflect.String:
 		return mmdbtype.String(val.String())
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return mmdbtype.Int32(val.Int())
+		// MMDB has no signed 64-bit type
+		switch v := val.Int(); {
+		case v >= math.MinInt32 && v <= math.MaxInt32:
+			return mmdbtype.Int32(v)
+		case v > 0:
+			return mmdbtype.Uint64(v)
+		default:
+			return mmdbtype.Float64(v)
+		}
 	case reflect.Uint, reflect.Uint8, reflect.Uint16:
 		return mmdbtype.Uint16(val.Uint())
 	case reflect.Uint32:
@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mmdb
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+func TestWriterTypedValues(t *testing.T) {
+	ast := assert.New(t)
+
+	meta := &model.Meta{
+		IPVersion: model.IPv4,
+		Fields:    []string{"score", "tags", "latitude", "is_anonymous_proxy", "name"},
+	}
+	writer, err := NewWriter(meta)
+	ast.Nil(err)
+
+	_, ipNet, err := net.ParseCIDR("61.0.0.0/8")
+	ast.Nil(err)
+	info := &model.IPInfo{IPNet: ipnet.NewRange(ipNet), Fields: meta.Fields}
+	info.SetValue("score", model.IntValue(42))
+	info.SetValue("tags", model.ListValue([]model.Value{model.StringValue("a"), model.StringValue("b")}))
+	info.SetValue("latitude", model.FloatValue(39.9042))
+	info.SetValue("is_anonymous_proxy", model.BoolValue(true))
+	info.SetValue("name", model.StringValue("test"))
+	ast.Nil(writer.Insert(info))
+
+	file := filepath.Join(t.TempDir(), "test.mmdb")
+	f, err := os.Create(file)
+	ast.Nil(err)
+	_, err = writer.WriteTo(f)
+	ast.Nil(err)
+	ast.Nil(f.Close())
+
+	reader, err := NewReader(file)
+	ast.Nil(err)
+	defer reader.Close()
+
+	ret, err := reader.Find(net.ParseIP("61.144.235.160"))
+	ast.Nil(err)
+
+	score, ok := ret.GetValue("score")
+	ast.True(ok)
+	ast.Equal(model.TypeInt, score.Type())
+	ast.Equal("42", score.String())
+
+	tags, _ := ret.GetValue("tags")
+	ast.Equal(model.TypeList, tags.Type())
+	ast.Equal("a,b", tags.String())
+
+	latitude, _ := ret.GetValue("latitude")
+	f64, ok := latitude.Float()
+	ast.True(ok)
+	ast.InDelta(39.9042, f64, 1e-9)
+	ast.Equal("39.904200", latitude.String())
+
+	proxy, _ := ret.GetValue("is_anonymous_proxy")
+	b, ok := proxy.Bool()
+	ast.True(ok && b)
+	ast.Equal(model.TypeBool, proxy.Type())
+
+	// JSON output keeps the string views and the types
+	ret.Fields = []string{"score", "tags", "name"}
+	output := ret.Output(true)
+	ast.Equal(map[string]string{"score": "42", "tags": "a,b", "name": "test"}, output.Data)
+	ast.Equal(map[string]interface{}{
+		"score": int64(42),
+		"tags":  []interface{}{"a", "b"},
+	}, output.TypedData)
+
+	// rewritten values fall back to strings
+	ret.Data["score"] = "high"
+	score, _ = ret.GetValue("score")
+	ast.Equal(model.TypeString, score.Type())
+	ast.Equal("high", score.String())
+}
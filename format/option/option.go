@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package option describes the options of IP database readers and writers.
+//
+// Each format declares its options as Specs, and options given in URL query format
+// (e.g. "mmap=true&foo=bar") are validated against them once, before they are converted
+// into the typed option passed to SetOption.
+package option
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+// Option value types.
+const (
+	Bool   = "bool"
+	Int    = "int"
+	String = "string"
+)
+
+// Spec describes an option.
+type Spec struct {
+
+	// Name is the key of the option in URL query format.
+	Name string
+
+	// Type is the value type of the option: Bool, Int or String.
+	Type string
+
+	// Default is the value used when the option is not given.
+	Default string
+
+	// Description explains what the option does.
+	Description string
+}
+
+// Specs is the list of options supported by a reader or writer.
+type Specs []Spec
+
+// Get returns the spec of the named option.
+func (s Specs) Get(name string) (Spec, bool) {
+	for _, spec := range s {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return Spec{}, false
+}
+
+// Names returns the sorted names of the options.
+func (s Specs) Names() []string {
+	names := make([]string, 0, len(s))
+	for _, spec := range s {
+		names = append(names, spec.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Defaults returns the default values of the options.
+func (s Specs) Defaults() Values {
+	values := make(Values, len(s))
+	for _, spec := range s {
+		values[spec.Name] = spec.Default
+	}
+	return values
+}
+
+// Parse parses options in URL query format and validates them.
+// Options missing from query take their default values, unknown options are rejected.
+func (s Specs) Parse(query string) (Values, error) {
+	return s.parse(query, true)
+}
+
+// ParseKnown is like Parse but ignores unknown options,
+// it is used when the same options are shared by several readers.
+func (s Specs) ParseKnown(query string) (Values, error) {
+	return s.parse(query, false)
+}
+
+func (s Specs) parse(query string, strict bool) (Values, error) {
+	args, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	values := s.Defaults()
+	for name, vals := range args {
+		spec, ok := s.Get(name)
+		if !ok {
+			if !strict {
+				continue
+			}
+			supported := "none"
+			if len(s) > 0 {
+				supported = strings.Join(s.Names(), ", ")
+			}
+			return nil, fmt.Errorf("%w %q, supported options: %s", errors.ErrUnknownOption, name, supported)
+		}
+
+		value := vals[len(vals)-1]
+		if err := spec.Validate(value); err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+// Validate checks that value is valid for the type of the option.
+func (s Spec) Validate(value string) error {
+	var err error
+	switch s.Type {
+	case Bool:
+		_, err = strconv.ParseBool(value)
+	case Int:
+		_, err = strconv.Atoi(value)
+	}
+	if err != nil {
+		return fmt.Errorf("%w %q for option %s, expected %s", errors.ErrInvalidOptionValue, value, s.Name, s.Type)
+	}
+	return nil
+}
+
+// Values holds validated option values by name.
+type Values map[string]string
+
+// Bool returns the value of a Bool option.
+func (v Values) Bool(name string) bool {
+	ret, _ := strconv.ParseBool(v[name])
+	return ret
+}
+
+// Int returns the value of an Int option.
+func (v Values) Int(name string) int {
+	ret, _ := strconv.Atoi(v[name])
+	return ret
+}
+
+// String returns the value of a String option.
+func (v Values) String(name string) string {
+	return v[name]
+}
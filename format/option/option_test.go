@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package option
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+func TestSpecsParse(t *testing.T) {
+	ast := assert.New(t)
+
+	specs := Specs{
+		{Name: "mmap", Type: Bool, Default: "false"},
+		{Name: "size", Type: Int, Default: "10"},
+		{Name: "lang", Type: String},
+	}
+
+	values, err := specs.Parse("")
+	ast.Nil(err)
+	ast.False(values.Bool("mmap"))
+	ast.Equal(10, values.Int("size"))
+	ast.Equal("", values.String("lang"))
+
+	values, err = specs.Parse("mmap=true&size=20&lang=en")
+	ast.Nil(err)
+	ast.True(values.Bool("mmap"))
+	ast.Equal(20, values.Int("size"))
+	ast.Equal("en", values.String("lang"))
+
+	_, err = specs.Parse("mmap=true&foo=bar")
+	ast.True(errors.Is(err, errors.ErrUnknownOption))
+	ast.Contains(err.Error(), "supported options: lang, mmap, size")
+
+	_, err = specs.Parse("size=abc")
+	ast.True(errors.Is(err, errors.ErrInvalidOptionValue))
+
+	values, err = specs.ParseKnown("mmap=1&foo=bar")
+	ast.Nil(err)
+	ast.True(values.Bool("mmap"))
+
+	_, err = Specs{}.Parse("foo=bar")
+	ast.Contains(err.Error(), "supported options: none")
+}
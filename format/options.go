@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"github.com/sjzar/ips/format/ip2region"
+	"github.com/sjzar/ips/format/ipdb"
+	"github.com/sjzar/ips/format/mmdb"
+	"github.com/sjzar/ips/format/option"
+	"github.com/sjzar/ips/format/qqwry"
+	"github.com/sjzar/ips/format/zxinc"
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+// Options describes the options of a reader or writer format.
+type Options struct {
+
+	// Specs lists the supported options.
+	Specs option.Specs
+
+	// New converts validated option values into the option passed to SetOption.
+	New func(values option.Values) interface{}
+
+	// Open opens a database file with the option returned by New, optional.
+	// It is used by NewReaderWithOption for options that take effect while the file is loaded.
+	Open func(file string, option interface{}) (Reader, error)
+}
+
+var (
+	ReaderOptions = map[string]Options{
+		ip2region.DBFormat: {
+			Specs: ip2region.ReaderOptions,
+			New:   func(v option.Values) interface{} { return ip2region.NewReaderOption(v) },
+			Open: func(file string, o interface{}) (Reader, error) {
+				opt, ok := o.(ip2region.ReaderOption)
+				if !ok {
+					return nil, errors.ErrUnsupportedOption
+				}
+				return ip2region.NewReaderWithOption(file, opt)
+			},
+		},
+		ipdb.DBFormat: {
+			Specs: ipdb.ReaderOptions,
+			New:   func(v option.Values) interface{} { return ipdb.NewReaderOption(v) },
+			Open: func(file string, o interface{}) (Reader, error) {
+				opt, ok := o.(ipdb.ReaderOption)
+				if !ok {
+					return nil, errors.ErrUnsupportedOption
+				}
+				return ipdb.NewReaderWithOption(file, opt)
+			},
+		},
+		mmdb.DBFormat: {Specs: mmdb.ReaderOptions, New: func(v option.Values) interface{} { return mmdb.NewReaderOption(v) }},
+		qqwry.DBFormat: {
+			Specs: qqwry.ReaderOptions,
+			New:   func(v option.Values) interface{} { return qqwry.NewReaderOption(v) },
+			Open: func(file string, o interface{}) (Reader, error) {
+				opt, ok := o.(qqwry.ReaderOption)
+				if !ok {
+					return nil, errors.ErrUnsupportedOption
+				}
+				return qqwry.NewReaderWithOption(file, opt)
+			},
+		},
+		zxinc.DBFormat: {
+			Specs: zxinc.ReaderOptions,
+			New:   func(v option.Values) interface{} { return zxinc.NewReaderOption(v) },
+			Open: func(file string, o interface{}) (Reader, error) {
+				opt, ok := o.(zxinc.ReaderOption)
+				if !ok {
+					return nil, errors.ErrUnsupportedOption
+				}
+				return zxinc.NewReaderWithOption(file, opt)
+			},
+		},
+	}
+	WriterOptions = map[string]Options{
+		mmdb.DBFormat: {Specs: mmdb.WriterOptions, New: func(v option.Values) interface{} { return mmdb.NewWriterOption(v) }},
+	}
+)
+
+// RegisterReaderOptions registers the options of a Reader format.
+func RegisterReaderOptions(name string, opts Options) {
+	if name == "" || opts.New == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	ReaderOptions[name] = opts
+}
+
+// RegisterWriterOptions registers the options of a Writer format.
+func RegisterWriterOptions(name string, opts Options) {
+	if name == "" || opts.New == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	WriterOptions[name] = opts
+}
+
+// ParseReaderOption validates options in URL query format against the options of the Reader format,
+// and returns the option to pass to SetOption. It returns nil if query is empty.
+func ParseReaderOption(format, query string) (interface{}, error) {
+	return parseOption(ReaderOptions[format], query, true)
+}
+
+// ParseReaderOptionKnown is like ParseReaderOption but ignores options unknown to the format,
+// for options shared by several readers.
+func ParseReaderOptionKnown(format, query string) (interface{}, error) {
+	return parseOption(ReaderOptions[format], query, false)
+}
+
+// ParseWriterOption validates options in URL query format against the options of the Writer format,
+// and returns the option to pass to SetOption. It returns nil if query is empty.
+func ParseWriterOption(format, query string) (interface{}, error) {
+	return parseOption(WriterOptions[format], query, true)
+}
+
+func parseOption(opts Options, query string, strict bool) (interface{}, error) {
+	if len(query) == 0 {
+		return nil, nil
+	}
+
+	var values option.Values
+	var err error
+	if strict {
+		values, err = opts.Specs.Parse(query)
+	} else {
+		values, err = opts.Specs.ParseKnown(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.New == nil {
+		return nil, nil
+	}
+	return opts.New(values), nil
+}
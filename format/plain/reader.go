@@ -32,11 +32,12 @@ import (
 )
 
 const (
-	DBFormat   = "plain"
-	DBExt      = ".txt"
-	MetaPrefix = "# Meta: "
-	FieldSep   = ","
-	FieldData  = "text"
+	DBFormat     = "plain"
+	DBExt        = ".txt"
+	MetaPrefix   = "# Meta: "
+	FieldsPrefix = "# Fields: "
+	FieldSep     = ","
+	FieldData    = "text"
 )
 
 // Reader is a structure that provides functionalities to read from Plain Text.
@@ -66,6 +67,9 @@ func NewReader(file string) (*Reader, error) {
 // Find retrieves IP information based on the given IP address.
 func (r *Reader) Find(ip net.IP) (*model.IPInfo, error) {
 	data, ipNet, err := r.db.FindMap(ip.String(), "CN")
+	if err == sdk.ErrDataNotExists {
+		return nil, errors.NewNotFoundError(ip, ipnet.NewRange(ipNet))
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -92,7 +96,7 @@ func (r *Reader) Meta() *model.Meta {
 
 // SetOption configures the Reader with the provided option.
 func (r *Reader) SetOption(option interface{}) error {
-	return nil
+	return errors.ErrUnsupportedOption
 }
 
 // Close closes the IP database.
@@ -17,6 +17,7 @@
 package plain
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -33,6 +34,7 @@ type Writer struct {
 	meta   *model.Meta
 	iw     io.Writer
 	buffer *bytes.Buffer
+	bw     *bufio.Writer
 }
 
 // NewWriter initializes a new Writer instance for writing IP data in plain text format.
@@ -63,7 +65,7 @@ func (w *Writer) SetOption(option interface{}) error {
 		return nil
 	}
 
-	return nil
+	return errors.ErrUnsupportedOption
 }
 
 // Insert adds the given IP information into the writer.
@@ -86,6 +88,39 @@ func (w *Writer) Insert(info *model.IPInfo) error {
 	return nil
 }
 
+// Open binds the Writer to the output and writes the header,
+// the records inserted afterwards are streamed to the output.
+func (w *Writer) Open(writer io.Writer) error {
+	if writer == nil {
+		return errors.ErrNilWriter
+	}
+	w.bw = bufio.NewWriter(writer)
+	w.iw = w.bw
+	w.buffer = nil
+
+	return w.Header()
+}
+
+// Close flushes the records that are not written to the output yet.
+func (w *Writer) Close() error {
+	if w.bw == nil {
+		return nil
+	}
+	err := w.bw.Flush()
+	w.bw = nil
+	w.iw = nil
+
+	return err
+}
+
+// Abort drops the records that are not written to the output yet.
+func (w *Writer) Abort() error {
+	w.bw = nil
+	w.iw = nil
+
+	return nil
+}
+
 // WriteTo writes the buffered data into the provided writer.
 func (w *Writer) WriteTo(writer io.Writer) (int64, error) {
 	if w.buffer == nil {
@@ -102,7 +137,7 @@ func (w *Writer) Header() error {
 	}
 
 	str := fmt.Sprintf("# Dump Time: %s\n", time.Now().Local().Format("2006-01-02 15:04:05"))
-	str += fmt.Sprintf("# Fields: %s\n", strings.Join(w.meta.Fields, FieldSep))
+	str += fmt.Sprintf("%s%s\n", FieldsPrefix, strings.Join(w.meta.Fields, FieldSep))
 	str += fmt.Sprintf("# IP Version: %d\n", w.meta.IPVersion)
 	b, _ := json.Marshal(w.meta)
 	str += fmt.Sprintf("%s%s\n", MetaPrefix, string(b))
@@ -18,14 +18,15 @@ package sdk
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
-	"io"
 	"net"
-	"os"
+	"sort"
 
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/simplifiedchinese"
 
+	"github.com/sjzar/ips/internal/mmap"
 	"github.com/sjzar/ips/ipnet"
 	"github.com/sjzar/ips/pkg/errors"
 )
@@ -42,6 +43,7 @@ const (
 
 // Reader represents the QQWry database reader.
 type Reader struct {
+	file       *mmap.File        // IP database file
 	data       []byte            // IP database data
 	start      uint32            // Start position of IP database data
 	end        uint32            // End position of IP database data
@@ -50,20 +52,27 @@ type Reader struct {
 
 // NewReader initializes a new QQWry instance given the file path.
 func NewReader(filePath string) (*Reader, error) {
-	file, err := os.Open(filePath)
+	file, err := mmap.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		_ = file.Close()
-	}()
+	return newReader(file)
+}
 
-	data, err := io.ReadAll(file)
+// NewMmapReader initializes a new QQWry instance backed by a memory-mapped file.
+// It falls back to heap loading on platforms without mmap support.
+func NewMmapReader(filePath string) (*Reader, error) {
+	file, err := mmap.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
+	return newReader(file)
+}
 
+func newReader(file *mmap.File) (*Reader, error) {
+	data := file.Bytes()
 	if len(data) < 8 {
+		_ = file.Close()
 		return nil, errors.ErrInvalidDatabase
 	}
 
@@ -71,10 +80,12 @@ func NewReader(filePath string) (*Reader, error) {
 	end := binary.LittleEndian.Uint32(data[4:])
 
 	if uint32(len(data)) < end+7 {
+		_ = file.Close()
 		return nil, errors.ErrInvalidDatabase
 	}
 
 	return &Reader{
+		file:       file,
 		data:       data,
 		start:      start,
 		end:        end,
@@ -106,6 +117,67 @@ func (q *Reader) Find(ip net.IP) (*ipnet.Range, string, string, error) {
 	}, country, area, nil
 }
 
+// Ranges walks the index and calls fn for each IP range overlapping [start, end] in ascending order.
+// It stops when fn returns false or the context is done.
+func (q *Reader) Ranges(ctx context.Context, start, end net.IP, fn func(ipr *ipnet.Range, country, area string) bool) error {
+	start, end = start.To4(), end.To4()
+	if start == nil || end == nil {
+		return errors.ErrUnsupportedIPVersion
+	}
+	last := ipnet.IPv4ToUint32(end)
+
+	for pos := q.findIndex(ipnet.IPv4ToUint32(start)); pos <= q.end; pos += 7 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		startIP := binary.LittleEndian.Uint32(q.data[pos : pos+4])
+		if startIP > last {
+			break
+		}
+		offset := Bytes3Uint32(q.data[pos+4 : pos+7])
+		endIP := binary.LittleEndian.Uint32(q.data[offset : offset+4])
+		country, area, err := q.parse(offset+4, 0)
+		if err != nil {
+			return err
+		}
+
+		ipr := &ipnet.Range{
+			Start: ipnet.Uint32ToIPv4(startIP).To4(),
+			End:   ipnet.Uint32ToIPv4(endIP).To4(),
+		}
+		if !fn(ipr, country, area) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// findIndex returns the position of the last index record whose start IP is not greater than ip.
+func (q *Reader) findIndex(ip uint32) uint32 {
+	count := int((q.end-q.start)/7) + 1
+	i := sort.Search(count, func(i int) bool {
+		pos := q.start + uint32(i)*7
+		return binary.LittleEndian.Uint32(q.data[pos:pos+4]) > ip
+	})
+	if i > 0 {
+		i--
+	}
+	return q.start + uint32(i)*7
+}
+
+// Mapped reports whether the database is memory-mapped.
+func (q *Reader) Mapped() bool {
+	return q.file.Mapped()
+}
+
+// Close releases the database data.
+func (q *Reader) Close() error {
+	q.data = nil
+	return q.file.Close()
+}
+
 // findOffset determines the offset for the given IP in the QQWry database.
 func (q *Reader) findOffset(ip uint32) (startIP uint32, offset uint32) {
 	low := q.start
@@ -17,8 +17,10 @@
 package format
 
 import (
+	"context"
 	"net"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 
@@ -49,6 +51,31 @@ type Reader interface {
 	Close() error
 }
 
+// ContextReader is an optional interface for readers whose lookups can be cancelled or time out.
+type ContextReader interface {
+
+	// FindContext retrieves IP information based on the given IP address.
+	// It returns ctx.Err() if ctx is done before the lookup completes.
+	FindContext(ctx context.Context, ip net.IP) (*model.IPInfo, error)
+}
+
+// RangeIterator is an optional interface for readers that can enumerate their IP ranges natively,
+// which is much faster than probing the address space with Find.
+type RangeIterator interface {
+
+	// Ranges returns an iterator over the IP ranges overlapping [start, end] in ascending order.
+	// The first and last ranges may extend beyond start and end. Address space without data is skipped.
+	// A nil iterator means the reader cannot enumerate ranges natively.
+	Ranges(ctx context.Context, start, end net.IP) model.IPInfoSeq
+}
+
+// BatchReader is an optional interface for readers that can look up many IP addresses at once.
+type BatchReader interface {
+
+	// FindBatch retrieves IP information for each of the given IP addresses, in the same order.
+	FindBatch(ips []net.IP) ([]*model.IPInfo, error)
+}
+
 // NewReader creates a Reader based on its format or file name.
 func NewReader(format, file string) (Reader, error) {
 	if fn, ok := ReaderFormats[format]; ok {
@@ -68,6 +95,62 @@ func NewReader(format, file string) (Reader, error) {
 	return nil, errors.ErrUnsupportedFormat
 }
 
+// NewReaderWithOption is like NewReader, and configures the Reader with option, as returned by ParseReaderOption.
+// Formats that load the database file according to the option open it with the option directly,
+// e.g. a memory-mapped file is never loaded into the heap. A nil option is ignored.
+func NewReaderWithOption(format, file string, option interface{}) (Reader, error) {
+	if option != nil {
+		if open := ReaderOptions[ReaderFormat(format, file)].Open; open != nil {
+			return open(file, option)
+		}
+	}
+
+	r, err := NewReader(format, file)
+	if err != nil {
+		return nil, err
+	}
+	if option != nil {
+		if err := r.SetOption(option); err != nil {
+			_ = r.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// ReaderFormat returns the name of the Reader format NewReader uses for format and file, without opening the file.
+// It returns an empty string if the format has no name, e.g. a Reader registered by file extension without a Descriptor.
+func ReaderFormat(format, file string) string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := ReaderFormats[format]; ok {
+		return format
+	}
+
+	if ext := filepath.Ext(file); ReaderExts[ext] != nil {
+		return describedFormat(func(d Descriptor) bool { return slices.Contains(d.Exts, ext) })
+	}
+
+	for commonName := range ReaderCommonNames {
+		if strings.HasPrefix(filepath.Base(file), commonName) {
+			return describedFormat(func(d Descriptor) bool { return slices.Contains(d.CommonNames, commonName) })
+		}
+	}
+
+	return ""
+}
+
+// describedFormat returns the name of the Reader format whose Descriptor matches.
+func describedFormat(match func(d Descriptor) bool) string {
+	for name, d := range Descriptors {
+		if _, ok := ReaderFormats[name]; ok && match(d) {
+			return name
+		}
+	}
+	return ""
+}
+
 var (
 	mu            sync.Mutex
 	ReaderFormats = map[string]func(string) (Reader, error){
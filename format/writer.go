@@ -20,7 +20,9 @@ import (
 	"io"
 	"path/filepath"
 
+	"github.com/sjzar/ips/format/csv"
 	"github.com/sjzar/ips/format/ipdb"
+	"github.com/sjzar/ips/format/jsonl"
 	"github.com/sjzar/ips/format/mmdb"
 	"github.com/sjzar/ips/format/plain"
 	"github.com/sjzar/ips/pkg/errors"
@@ -43,6 +45,28 @@ type Writer interface {
 	WriterFormat() string
 }
 
+// StreamWriter is a Writer with an explicit lifecycle.
+// Open is called with the output before the first Insert, and Close finishes the output
+// and releases the resources held by the writer. WriteTo is not used between Open and Close.
+// Writers that can stream, such as plain, jsonl and csv, write each inserted record to the output right away,
+// the others keep their bulk data in temporary files instead of memory; ipdb still keeps its
+// node tree in memory, which grows with the number of ranges.
+// Writers that do not implement it, such as mmdb, hold the whole database in memory until WriteTo.
+type StreamWriter interface {
+	Writer
+
+	// Open binds the writer to the output.
+	Open(w io.Writer) error
+
+	// Close finishes writing to the output and releases the resources of the writer.
+	Close() error
+
+	// Abort releases the resources of the writer without finishing the output, instead of Close
+	// when the transfer fails. Writers that stream records may have written some of them already,
+	// the others write nothing to the output.
+	Abort() error
+}
+
 // NewWriter creates a Writer based on its format or file name.
 func NewWriter(format string, file string, meta *model.Meta) (Writer, error) {
 	if fn, ok := WriterFormats[format]; ok {
@@ -58,12 +82,16 @@ func NewWriter(format string, file string, meta *model.Meta) (Writer, error) {
 
 var (
 	WriterFormats = map[string]func(meta *model.Meta) (Writer, error){
+		csv.DBFormat:   func(meta *model.Meta) (Writer, error) { return csv.NewWriter(meta) },
 		ipdb.DBFormat:  func(meta *model.Meta) (Writer, error) { return ipdb.NewWriter(meta) },
+		jsonl.DBFormat: func(meta *model.Meta) (Writer, error) { return jsonl.NewWriter(meta) },
 		mmdb.DBFormat:  func(meta *model.Meta) (Writer, error) { return mmdb.NewWriter(meta) },
 		plain.DBFormat: func(meta *model.Meta) (Writer, error) { return plain.NewWriter(meta) },
 	}
 	WriterExts = map[string]func(meta *model.Meta) (Writer, error){
+		csv.DBExt:   func(meta *model.Meta) (Writer, error) { return csv.NewWriter(meta) },
 		ipdb.DBExt:  func(meta *model.Meta) (Writer, error) { return ipdb.NewWriter(meta) },
+		jsonl.DBExt: func(meta *model.Meta) (Writer, error) { return jsonl.NewWriter(meta) },
 		mmdb.DBExt:  func(meta *model.Meta) (Writer, error) { return mmdb.NewWriter(meta) },
 		plain.DBExt: func(meta *model.Meta) (Writer, error) { return plain.NewWriter(meta) },
 	}
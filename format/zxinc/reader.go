@@ -17,27 +17,48 @@
 package zxinc
 
 import (
+	"context"
 	"net"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/sjzar/ips/format/option"
 	"github.com/sjzar/ips/format/zxinc/sdk"
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
 	"github.com/sjzar/ips/pkg/model"
 )
 
 const (
 	DBFormat = "zxinc"
 	DBExt    = ".db"
+
+	// Language is the language of the data.
+	Language = "zh-CN"
+
+	// Source is the provider of ZXInc databases.
+	Source = "ip.zxinc.org"
 )
 
+// versionDateRegexp matches the date in the version record.
+var versionDateRegexp = regexp.MustCompile(`\d{8}`)
+
 // Reader is a structure that provides functionalities to read from ZXInc IP database.
 type Reader struct {
+	file string      // Path of the IP database file
 	meta *model.Meta // Metadata of the IP database
 	db   *sdk.Reader // Database reader instance
 }
 
 // NewReader initializes a new instance of Reader.
 func NewReader(file string) (*Reader, error) {
+	return NewReaderWithOption(file, ReaderOption{})
+}
 
-	db, err := sdk.NewReader(file)
+// NewReaderWithOption initializes a new instance of Reader, loading the database file as set by option.
+func NewReaderWithOption(file string, option ReaderOption) (*Reader, error) {
+	db, err := open(file, option)
 	if err != nil {
 		return nil, err
 	}
@@ -47,10 +68,26 @@ func NewReader(file string) (*Reader, error) {
 		Format:      DBFormat,
 		IPVersion:   model.IPv6,
 		Fields:      FullFields,
+		Languages:   []string{Language},
+		Source:      Source,
 	}
 	meta.AddCommonFieldAlias(CommonFieldsAlias)
 
+	// the last record holds the version, e.g. "ZX公网IPv6库 20210511版"
+	_ = db.Ranges(context.Background(), ipnet.LastIPv6, ipnet.LastIPv6, func(_ *ipnet.Range, _, area string) bool {
+		if strings.HasPrefix(area, "ZX") {
+			if split := strings.Fields(area); len(split) == 2 {
+				meta.Description, meta.Version = split[0], split[1]
+			}
+			if date := versionDateRegexp.FindString(area); date != "" {
+				meta.BuildTime, _ = time.Parse("20060102", date)
+			}
+		}
+		return false
+	})
+
 	return &Reader{
+		file: file,
 		meta: meta,
 		db:   db,
 	}, nil
@@ -63,6 +100,36 @@ func (r *Reader) Find(ip net.IP) (*model.IPInfo, error) {
 		return nil, err
 	}
 
+	info := r.newIPInfo(ip, ipr, country, area)
+	if info == nil {
+		return nil, errors.NewNotFoundError(ip, ipr)
+	}
+	return info, nil
+}
+
+// Ranges returns an iterator over the IP ranges overlapping [start, end] by walking the database index.
+func (r *Reader) Ranges(ctx context.Context, start, end net.IP) model.IPInfoSeq {
+	return func(yield func(*model.IPInfo, error) bool) {
+		err := r.db.Ranges(ctx, start, end, func(ipr *ipnet.Range, country, area string) bool {
+			info := r.newIPInfo(ipr.Start, ipr, country, area)
+			if info == nil {
+				// unknown space
+				return true
+			}
+			return yield(info, nil)
+		})
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// newIPInfo builds the IP information from a database record, it returns nil if the record holds no data.
+func (r *Reader) newIPInfo(ip net.IP, ipr *ipnet.Range, country, area string) *model.IPInfo {
+	if country == "" && area == "" {
+		return nil
+	}
+
 	ret := &model.IPInfo{
 		IP:     ip,
 		IPNet:  ipr,
@@ -74,7 +141,7 @@ func (r *Reader) Find(ip net.IP) (*model.IPInfo, error) {
 	}
 	ret.AddCommonFieldAlias(CommonFieldsAlias)
 
-	return ret, nil
+	return ret
 }
 
 // Meta returns the meta-information of the IP database.
@@ -82,12 +149,51 @@ func (r *Reader) Meta() *model.Meta {
 	return r.meta
 }
 
+// ReaderOptions lists the options supported by the Reader.
+var ReaderOptions = option.Specs{
+	{Name: "mmap", Type: option.Bool, Default: "false", Description: "Memory-map the database file instead of loading it into the heap."},
+}
+
+// NewReaderOption converts validated option values into a ReaderOption.
+func NewReaderOption(values option.Values) ReaderOption {
+	return ReaderOption{Mmap: values.Bool("mmap")}
+}
+
+// ReaderOption contains configuration options for the Reader.
+type ReaderOption struct {
+	Mmap bool // If true, the database file is memory-mapped instead of loaded into the heap.
+}
+
 // SetOption configures the Reader with the provided option.
+// Switching the loading mode reloads the database file.
 func (r *Reader) SetOption(option interface{}) error {
+	opt, ok := option.(ReaderOption)
+	if !ok {
+		return errors.ErrUnsupportedOption
+	}
+	if opt.Mmap == r.db.Mapped() {
+		return nil
+	}
+
+	db, err := open(r.file, opt)
+	if err != nil {
+		return err
+	}
+
+	_ = r.db.Close()
+	r.db = db
 	return nil
 }
 
+// open loads the database file into the heap, or memory-maps it if option.Mmap is set.
+func open(file string, option ReaderOption) (*sdk.Reader, error) {
+	if option.Mmap {
+		return sdk.NewMmapReader(file)
+	}
+	return sdk.NewReader(file)
+}
+
 // Close closes the IP database.
 func (r *Reader) Close() error {
-	return nil
+	return r.db.Close()
 }
@@ -18,11 +18,13 @@ package sdk
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
-	"io"
+	"math"
 	"net"
-	"os"
+	"sort"
 
+	"github.com/sjzar/ips/internal/mmap"
 	"github.com/sjzar/ips/ipnet"
 	"github.com/sjzar/ips/pkg/errors"
 )
@@ -40,6 +42,9 @@ const (
 // Reader ZXInc 数据库
 type Reader struct {
 
+	// file IP库文件
+	file *mmap.File
+
 	// data IP库数据
 	data []byte
 
@@ -62,21 +67,28 @@ type Reader struct {
 	indexLen uint64
 }
 
+// NewReader 加载IP库到内存
 func NewReader(filePath string) (*Reader, error) {
-	file, err := os.Open(filePath)
+	file, err := mmap.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		_ = file.Close()
-	}()
+	return newReader(file)
+}
 
-	data, err := io.ReadAll(file)
+// NewMmapReader 通过 mmap 加载IP库，不支持 mmap 的平台会回退到内存加载
+func NewMmapReader(filePath string) (*Reader, error) {
+	file, err := mmap.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
+	return newReader(file)
+}
 
+func newReader(file *mmap.File) (*Reader, error) {
+	data := file.Bytes()
 	if len(data) < 24 {
+		_ = file.Close()
 		return nil, errors.ErrInvalidDatabase
 	}
 
@@ -89,10 +101,12 @@ func NewReader(filePath string) (*Reader, error) {
 	end := start + count*indexLen
 
 	if uint64(len(data)) < end || start >= end {
+		_ = file.Close()
 		return nil, errors.ErrInvalidDatabase
 	}
 
 	return &Reader{
+		file:      file,
 		data:      data,
 		start:     start,
 		end:       end,
@@ -126,6 +140,68 @@ func (q *Reader) Find(ip net.IP) (*ipnet.Range, string, string, error) {
 	}, country, area, nil
 }
 
+// Ranges 按顺序遍历索引，对与 [start, end] 有交集的每个IP段调用 fn，fn 返回 false 或 ctx 结束时停止
+func (q *Reader) Ranges(ctx context.Context, start, end net.IP, fn func(ipr *ipnet.Range, country, area string) bool) error {
+	start, end = start.To16(), end.To16()
+	if start == nil || end == nil {
+		return errors.ErrUnsupportedIPVersion
+	}
+	last := binary.BigEndian.Uint64(end[:q.ipLen])
+
+	for pos := q.findIndex(binary.BigEndian.Uint64(start[:q.ipLen])); pos < q.end; pos += q.indexLen {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		startIP := binary.LittleEndian.Uint64(q.data[pos : pos+q.ipLen])
+		if startIP > last {
+			break
+		}
+		endIP := ipnet.Uint64ToIP2(math.MaxUint64, math.MaxUint64)
+		if next := pos + q.indexLen; next < q.end {
+			endIP = ipnet.PrevIP(ipnet.Uint64ToIP(binary.LittleEndian.Uint64(q.data[next : next+q.ipLen])))
+		}
+		country, area, err := q.parse(Bytes3Uint32(q.data[pos+q.ipLen:pos+q.indexLen]), 0)
+		if err != nil {
+			return err
+		}
+
+		ipr := &ipnet.Range{
+			Start: ipnet.Uint64ToIP(startIP),
+			End:   endIP,
+		}
+		if !fn(ipr, country, area) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// findIndex 查找起始IP不大于 ip 的最后一条索引位置
+func (q *Reader) findIndex(ip uint64) uint64 {
+	count := int((q.end - q.start) / q.indexLen)
+	i := sort.Search(count, func(i int) bool {
+		pos := q.start + uint64(i)*q.indexLen
+		return binary.LittleEndian.Uint64(q.data[pos:pos+q.ipLen]) > ip
+	})
+	if i > 0 {
+		i--
+	}
+	return q.start + uint64(i)*q.indexLen
+}
+
+// Mapped 是否通过 mmap 加载
+func (q *Reader) Mapped() bool {
+	return q.file.Mapped()
+}
+
+// Close 释放IP库数据
+func (q *Reader) Close() error {
+	q.data = nil
+	return q.file.Close()
+}
+
 // findOffset 查找IP对应的偏移量
 func (q *Reader) findOffset(ip uint64) (startIP, nextIP uint64, offset uint32) {
 	low := q.start
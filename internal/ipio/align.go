@@ -0,0 +1,199 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"context"
+	"net"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// AlignedRange is an IP range in which the data of both readers of an alignment does not change.
+type AlignedRange struct {
+	IPNet *ipnet.Range
+
+	// Old and New are the results of the old and the new reader for the range, nil if a reader has no data.
+	Old, New *model.IPInfo
+}
+
+// AlignedRangeSeq is an iterator over aligned ranges, shaped like iter.Seq2[*AlignedRange, error].
+type AlignedRangeSeq = func(yield func(*AlignedRange, error) bool)
+
+// Align walks the ranges of two readers in ascending order and splits them at the boundaries of both,
+// so the readers can be compared range by range regardless of their formats and range layouts.
+// Each reader is walked over its own address space, the IPv6 space includes the IPv4 space.
+// Address space without data in both readers is skipped.
+func Align(ctx context.Context, oldReader, newReader format.Reader) AlignedRangeSeq {
+	return func(yield func(*AlignedRange, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		oldStart, oldEnd := addressSpace(oldReader.Meta())
+		newStart, newEnd := addressSpace(newReader.Meta())
+		alignCursors(newRangeCursor(ctx, oldReader, oldStart, oldEnd), newRangeCursor(ctx, newReader, newStart, newEnd), yield)
+	}
+}
+
+// alignCursors yields the ranges of both cursors split at the boundaries of both, see Align.
+func alignCursors(a, b *rangeCursor, yield func(*AlignedRange, error) bool) {
+	pos := make(net.IP, net.IPv6len)
+	for {
+		if err := a.advance(pos); err != nil {
+			yield(nil, err)
+			return
+		}
+		if err := b.advance(pos); err != nil {
+			yield(nil, err)
+			return
+		}
+		if a.info == nil && b.info == nil {
+			return
+		}
+
+		// jump over the address space without data
+		aIn, bIn := a.covers(pos), b.covers(pos)
+		if !aIn && !bIn {
+			pos = b.start
+			if b.info == nil || (a.info != nil && ipnet.IPLess(a.start, b.start)) {
+				pos = a.start
+			}
+			continue
+		}
+
+		end := b.limit(a.limit(ipnet.LastIPv6, aIn), bIn)
+		ar := &AlignedRange{IPNet: &ipnet.Range{Start: pos, End: end}}
+		if aIn {
+			ar.Old = a.info
+		}
+		if bIn {
+			ar.New = b.info
+		}
+		if !yield(ar, nil) || end.Equal(ipnet.LastIPv6) {
+			return
+		}
+		pos = ipnet.NextIP(end)
+	}
+}
+
+// rangeCursor reads the ranges of a reader one by one.
+type rangeCursor struct {
+	next       func() (*model.IPInfo, error)
+	info       *model.IPInfo
+	start, end net.IP // the range of info in IPv6 length
+	done       bool
+}
+
+// newRangeCursor starts reading the ranges of the reader overlapping [start, end].
+func newRangeCursor(ctx context.Context, r format.Reader, start, end net.IP) *rangeCursor {
+	return &rangeCursor{next: pull(ctx, Ranges(ctx, r, start, end))}
+}
+
+// newSliceCursor reads the ranges of infos, which must be in ascending order.
+func newSliceCursor(infos []*model.IPInfo) *rangeCursor {
+	return &rangeCursor{next: func() (*model.IPInfo, error) {
+		if len(infos) == 0 {
+			return nil, nil
+		}
+		info := infos[0]
+		infos = infos[1:]
+		return info, nil
+	}}
+}
+
+// advance moves the cursor to the first range that ends at or after pos.
+// info is nil once the ranges are exhausted.
+func (c *rangeCursor) advance(pos net.IP) error {
+	for !c.done && (c.info == nil || ipnet.IPLess(c.end, pos)) {
+		info, err := c.next()
+		if err != nil {
+			return err
+		}
+		if info == nil {
+			c.done, c.info = true, nil
+			return nil
+		}
+		c.info, c.start, c.end = info, info.IPNet.Start.To16(), info.IPNet.End.To16()
+	}
+	return nil
+}
+
+// covers reports whether the current range contains pos, which must not be after its end.
+func (c *rangeCursor) covers(pos net.IP) bool {
+	return c.info != nil && !ipnet.IPLess(pos, c.start)
+}
+
+// limit returns end cut at the end of the current range if it contains the position,
+// or before the start of the current range otherwise.
+func (c *rangeCursor) limit(end net.IP, in bool) net.IP {
+	switch {
+	case c.info == nil:
+		return end
+	case in:
+		if ipnet.IPLess(c.end, end) {
+			return c.end
+		}
+	default:
+		if prev := ipnet.PrevIP(c.start); ipnet.IPLess(prev, end) {
+			return prev
+		}
+	}
+	return end
+}
+
+// addressSpace returns the address space of a database, the IPv6 space includes the IPv4 space.
+func addressSpace(meta *model.Meta) (net.IP, net.IP) {
+	if meta.IsIPv6Support() {
+		return make(net.IP, net.IPv6len), ipnet.LastIPv6
+	}
+	return net.IPv4(0, 0, 0, 0), ipnet.LastIPv4
+}
+
+// pull runs seq in a goroutine and returns a function reading its items one by one,
+// which returns a nil result once seq is exhausted. The goroutine stops once ctx is done.
+func pull(ctx context.Context, seq model.IPInfoSeq) func() (*model.IPInfo, error) {
+	type item struct {
+		info *model.IPInfo
+		err  error
+	}
+	ch := make(chan item, ChannelBufferSize)
+	go func() {
+		defer close(ch)
+		seq(func(info *model.IPInfo, err error) bool {
+			select {
+			case ch <- item{info: info, err: err}:
+				return err == nil
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return func() (*model.IPInfo, error) {
+		select {
+		case it, ok := <-ch:
+			if !ok {
+				return nil, nil
+			}
+			return it.info, it.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
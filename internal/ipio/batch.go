@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sort"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// FindBatch retrieves IP information for a batch of IP addresses and returns the results in the order of ips.
+// Readers implementing format.BatchReader handle the batch themselves, others are queried with Find
+// the same way as StandardReader.FindBatch.
+func FindBatch(r format.Reader, ips []net.IP) ([]*model.IPInfo, error) {
+	if br, ok := r.(format.BatchReader); ok {
+		return br.FindBatch(ips)
+	}
+	return findBatch(context.Background(), func(_ context.Context, ip net.IP) (*model.IPInfo, error) {
+		return r.Find(ip)
+	}, ips)
+}
+
+// FindBatchContext is like FindBatch but honors ctx, see FindContext.
+func FindBatchContext(ctx context.Context, r format.Reader, ips []net.IP) ([]*model.IPInfo, error) {
+	if br, ok := r.(batchContextReader); ok {
+		return br.FindBatchContext(ctx, ips)
+	}
+	return findBatch(ctx, func(ctx context.Context, ip net.IP) (*model.IPInfo, error) {
+		return FindContext(ctx, r, ip)
+	}, ips)
+}
+
+// batchContextReader is implemented by the readers of this package.
+type batchContextReader interface {
+	FindBatchContext(ctx context.Context, ips []net.IP) ([]*model.IPInfo, error)
+}
+
+// findBatch looks up the IP addresses in ascending order. An address falling into the range of the
+// previous result reuses that result instead of calling find again, so find (and the operate chain
+// behind it) runs once per distinct range. Reused results are copies that only differ in IP, and do not share their maps.
+// Addresses without data get a result marked NotFound instead of failing the batch.
+// It stops with ctx.Err() once ctx is done.
+func findBatch(ctx context.Context, find func(ctx context.Context, ip net.IP) (*model.IPInfo, error), ips []net.IP) ([]*model.IPInfo, error) {
+	keys := make([]net.IP, len(ips))
+	order := make([]int, len(ips))
+	for i, ip := range ips {
+		if keys[i] = ip.To16(); keys[i] == nil {
+			return nil, errors.ErrInvalidIP
+		}
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return bytes.Compare(keys[order[i]], keys[order[j]]) < 0
+	})
+
+	ret := make([]*model.IPInfo, len(ips))
+	var last *model.IPInfo
+	var start, end net.IP
+	for _, i := range order {
+		if last != nil && bytes.Compare(keys[i], start) >= 0 && bytes.Compare(keys[i], end) <= 0 {
+			ret[i] = copyIPInfo(last, ips[i])
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		info, err := find(ctx, ips[i])
+		if notFound, ok := errors.AsNotFound(err); ok {
+			info, err = &model.IPInfo{IP: ips[i], IPNet: notFound.IPNet, NotFound: true}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = info
+		last, start, end = info, nil, nil
+		if info.IPNet != nil {
+			start, end = info.IPNet.Start.To16(), info.IPNet.End.To16()
+		}
+		if start == nil || end == nil {
+			last = nil
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,278 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+const (
+	// DefaultCacheSize is the number of ranges kept by CachedReader when no size is configured.
+	DefaultCacheSize = 4096
+)
+
+// CachedReader is a Reader that caches the results of another Reader by IP range.
+// Since every IPInfo carries the range it belongs to, one cached result answers all IPs in that range,
+// e.g. a hot /24 only hits the underlying reader once.
+// Entries are evicted by least recent use once the cache is full, and expire after TTL if set.
+// CachedReader is safe for concurrent use if the underlying reader is.
+type CachedReader struct {
+	Reader format.Reader
+
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries []*cacheEntry // sorted by start
+	lru     *list.List    // front is the most recently used
+}
+
+// cacheEntry is a cached result with its range normalized to 16 bytes.
+type cacheEntry struct {
+	start  net.IP
+	end    net.IP
+	info   *model.IPInfo
+	expire time.Time
+	elem   *list.Element
+}
+
+// NewCachedReader initializes and returns a new CachedReader.
+// size is the maximum number of cached ranges, DefaultCacheSize is used if size <= 0.
+// ttl is the lifetime of a cached range, zero means no expiration.
+func NewCachedReader(reader format.Reader, size int, ttl time.Duration) *CachedReader {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &CachedReader{
+		Reader: reader,
+		size:   size,
+		ttl:    ttl,
+		lru:    list.New(),
+	}
+}
+
+// Meta returns the meta-information of the underlying reader.
+func (c *CachedReader) Meta() *model.Meta {
+	return c.Reader.Meta()
+}
+
+// Find retrieves IP information based on the given IP address, from the cache if possible.
+// The returned IPInfo is a copy and can be modified by the caller.
+func (c *CachedReader) Find(ip net.IP) (*model.IPInfo, error) {
+	return c.FindContext(context.Background(), ip)
+}
+
+// FindContext is like Find but honors ctx when the underlying reader is queried.
+func (c *CachedReader) FindContext(ctx context.Context, ip net.IP) (*model.IPInfo, error) {
+	key := ip.To16()
+	if key == nil {
+		return FindContext(ctx, c.Reader, ip)
+	}
+
+	if info, ok := c.get(key); ok {
+		if info.NotFound {
+			return nil, errors.NewNotFoundError(ip, info.IPNet)
+		}
+		return copyIPInfo(info, ip), nil
+	}
+
+	info, err := FindContext(ctx, c.Reader, ip)
+	if notFound, ok := errors.AsNotFound(err); ok {
+		// unknown ranges are cached as well
+		c.put(&model.IPInfo{IPNet: notFound.IPNet, NotFound: true})
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.put(info)
+
+	return copyIPInfo(info, ip), nil
+}
+
+// FindBatch retrieves IP information for a batch of IP addresses, in the same order.
+func (c *CachedReader) FindBatch(ips []net.IP) ([]*model.IPInfo, error) {
+	return c.FindBatchContext(context.Background(), ips)
+}
+
+// FindBatchContext is like FindBatch but honors ctx.
+func (c *CachedReader) FindBatchContext(ctx context.Context, ips []net.IP) ([]*model.IPInfo, error) {
+	return findBatch(ctx, c.FindContext, ips)
+}
+
+// CachedReaderOption contains options for the CachedReader.
+type CachedReaderOption struct {
+	// Size is the maximum number of cached ranges.
+	Size int
+
+	// TTL is the lifetime of a cached range, zero means no expiration.
+	TTL time.Duration
+}
+
+// SetOption configures the CachedReader with the provided option.
+// Other options are passed to the underlying reader. The cache is purged in both cases.
+func (c *CachedReader) SetOption(option interface{}) error {
+	opt, ok := option.(CachedReaderOption)
+	if !ok {
+		if err := c.Reader.SetOption(option); err != nil {
+			return err
+		}
+		c.Purge()
+		return nil
+	}
+
+	c.mu.Lock()
+	if opt.Size > 0 {
+		c.size = opt.Size
+	}
+	c.ttl = opt.TTL
+	c.mu.Unlock()
+	c.Purge()
+	return nil
+}
+
+// Len returns the number of cached ranges.
+func (c *CachedReader) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Purge removes all cached ranges.
+func (c *CachedReader) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+	c.lru.Init()
+}
+
+// Close purges the cache and closes the underlying reader.
+func (c *CachedReader) Close() error {
+	c.Purge()
+	return c.Reader.Close()
+}
+
+// get returns the cached result whose range contains ip.
+func (c *CachedReader) get(ip net.IP) (*model.IPInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := c.search(ip) - 1
+	if i < 0 {
+		return nil, false
+	}
+	e := c.entries[i]
+	if bytes.Compare(ip, e.end) > 0 {
+		return nil, false
+	}
+	if c.ttl > 0 && time.Now().After(e.expire) {
+		c.remove(i)
+		return nil, false
+	}
+	c.lru.MoveToFront(e.elem)
+	return e.info, true
+}
+
+// put caches info by its range, replacing cached ranges overlapping with it
+// and evicting the least recently used ranges when the cache is full.
+func (c *CachedReader) put(info *model.IPInfo) {
+	if info.IPNet == nil {
+		return
+	}
+	start, end := info.IPNet.Start.To16(), info.IPNet.End.To16()
+	if start == nil || end == nil || bytes.Compare(start, end) > 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// ranges of one database do not overlap, but the data may have been reloaded
+	i := c.search(start)
+	if i > 0 && bytes.Compare(c.entries[i-1].end, start) >= 0 {
+		i--
+	}
+	for i < len(c.entries) && bytes.Compare(c.entries[i].start, end) <= 0 {
+		c.remove(i)
+	}
+
+	e := &cacheEntry{
+		start: start,
+		end:   end,
+		info:  info,
+	}
+	if c.ttl > 0 {
+		e.expire = time.Now().Add(c.ttl)
+	}
+	e.elem = c.lru.PushFront(e)
+	c.entries = append(c.entries, nil)
+	copy(c.entries[i+1:], c.entries[i:])
+	c.entries[i] = e
+
+	for len(c.entries) > c.size {
+		oldest := c.lru.Back().Value.(*cacheEntry)
+		c.remove(c.search(oldest.start) - 1)
+	}
+}
+
+// search returns the index of the first entry whose start is greater than ip.
+func (c *CachedReader) search(ip net.IP) int {
+	return sort.Search(len(c.entries), func(i int) bool {
+		return bytes.Compare(c.entries[i].start, ip) > 0
+	})
+}
+
+// remove deletes the entry at index i.
+func (c *CachedReader) remove(i int) {
+	c.lru.Remove(c.entries[i].elem)
+	copy(c.entries[i:], c.entries[i+1:])
+	c.entries[len(c.entries)-1] = nil
+	c.entries = c.entries[:len(c.entries)-1]
+}
+
+// copyIPInfo returns a copy of info for ip, so callers cannot modify the cached data.
+func copyIPInfo(info *model.IPInfo, ip net.IP) *model.IPInfo {
+	ret := *info
+	ret.IP = ip
+	ret.Data = make(map[string]string, len(info.Data))
+	for k, v := range info.Data {
+		ret.Data[k] = v
+	}
+	if info.Typed != nil {
+		ret.Typed = make(map[string]model.Value, len(info.Typed))
+		for k, v := range info.Typed {
+			ret.Typed[k] = v
+		}
+	}
+	if info.Sources != nil {
+		ret.Sources = make(map[string]model.Source, len(info.Sources))
+		for k, v := range info.Sources {
+			ret.Sources[k] = v
+		}
+	}
+	return &ret
+}
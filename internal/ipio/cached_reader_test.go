@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+func TestCachedReader(t *testing.T) {
+	ast := assert.New(t)
+
+	dbReader := newTestReader([]string{"country"},
+		testRange{"0.0.0.0", "1.0.0.255", []string{"A"}},
+		testRange{"1.0.1.0", "1.0.1.255", []string{"B"}},
+		testRange{"1.0.2.0", "1.0.2.255", []string{"C"}},
+	)
+	reader := NewCachedReader(dbReader, 2, 0)
+
+	for _, c := range []struct {
+		ip    string
+		value string
+		finds int
+	}{
+		{"1.0.1.1", "B", 1},
+		{"1.0.1.200", "B", 1},
+		{"1.0.0.1", "A", 2},
+		{"0.0.0.1", "A", 2},
+		{"1.0.2.1", "C", 3}, // evicts B
+		{"1.0.0.2", "A", 3},
+		{"1.0.1.2", "B", 4}, // evicts C
+		{"1.0.2.2", "C", 5},
+	} {
+		ip := net.ParseIP(c.ip)
+		info, err := reader.Find(ip)
+		ast.Nil(err)
+		ast.Equal(ip, info.IP)
+		ast.Equal([]string{c.value}, info.Values(), c.ip)
+		ast.Equal(c.finds, dbReader.finds, c.ip)
+	}
+	ast.Equal(2, reader.Len())
+
+	// results are copies
+	info, err := reader.Find(net.ParseIP("1.0.2.3"))
+	ast.Nil(err)
+	info.Data["country"] = "X"
+	info, err = reader.Find(net.ParseIP("1.0.2.4").To4())
+	ast.Nil(err)
+	ast.Equal([]string{"C"}, info.Values())
+
+	// errors are not cached
+	_, err = reader.Find(net.ParseIP("8.8.8.8"))
+	ast.Equal(errors.ErrInvalidDatabase, err)
+	ast.Equal(2, reader.Len())
+
+	// ttl
+	ast.Nil(reader.SetOption(CachedReaderOption{TTL: time.Millisecond}))
+	ast.Equal(0, reader.Len())
+	dbReader.finds = 0
+	_, err = reader.Find(net.ParseIP("1.0.0.1"))
+	ast.Nil(err)
+	time.Sleep(2 * time.Millisecond)
+	_, err = reader.Find(net.ParseIP("1.0.0.1"))
+	ast.Nil(err)
+	ast.Equal(2, dbReader.finds)
+}
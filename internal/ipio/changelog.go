@@ -0,0 +1,253 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"context"
+	"slices"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/ipnet"
+)
+
+// Types of changes between two releases of a database.
+const (
+	// ChangeAdded is a range of addresses without data in the old release.
+	ChangeAdded = "added"
+
+	// ChangeRemoved is a range of addresses without data in the new release.
+	ChangeRemoved = "removed"
+
+	// ChangeReassigned is a range with the same boundaries and new values.
+	ChangeReassigned = "reassigned"
+
+	// ChangeSplit is a range split into several ranges.
+	ChangeSplit = "split"
+
+	// ChangeMerged is a run of ranges merged into one range.
+	ChangeMerged = "merged"
+
+	// ChangeResized is a run of ranges whose boundaries moved.
+	ChangeResized = "resized"
+)
+
+// ValuedRange is an IP range with the values of the fields.
+type ValuedRange struct {
+	IPNet  *ipnet.Range
+	Values []string
+}
+
+// FieldChange is a change of the value of a field.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// Change is a change between two releases of a database. It covers the smallest run of addresses
+// at whose ends the ranges of both releases end, and holds the ranges of both releases in it.
+type Change struct {
+	Type string
+
+	// Old and New are the ranges of the old and the new release, adjacent ranges with the same values are merged.
+	Old, New []*ValuedRange
+
+	// Fields lists the distinct value changes of the fields in the order they occur.
+	Fields []FieldChange
+
+	segments []*changeSegment
+}
+
+// Range returns the range of addresses covered by the change.
+func (c *Change) Range() *ipnet.Range {
+	return &ipnet.Range{Start: c.segments[0].IPNet.Start, End: c.segments[len(c.segments)-1].IPNet.End}
+}
+
+// Patch returns the ranges covered by the change with the values of the new release,
+// nil values where the new release has no data. Applying them to the old release yields the new release.
+func (c *Change) Patch() []*ValuedRange {
+	var ranges []*ValuedRange
+	for _, seg := range c.segments {
+		var values []string
+		if seg.hasNew {
+			values = seg.New
+		}
+		ranges = appendValuedRange(ranges, seg.IPNet, values)
+	}
+	return ranges
+}
+
+// Changelog lists the changes between two releases of a database.
+type Changelog struct {
+	Old    format.Reader
+	New    format.Reader
+	Fields []string
+
+	// segment is called with each aligned range, if set.
+	segment func(seg *changeSegment)
+}
+
+// NewChangelog initializes a Changelog of the fields between the old and the new reader.
+// Fields are looked up by the names of either the database or the common fields.
+func NewChangelog(oldReader, newReader format.Reader, fields []string) *Changelog {
+	return &Changelog{
+		Old:    oldReader,
+		New:    newReader,
+		Fields: fields,
+	}
+}
+
+// Changes walks both readers over their address space and calls fn with each change in ascending order.
+// Runs of ranges that are the same in both releases are skipped.
+func (c *Changelog) Changes(ctx context.Context, fn func(change *Change) error) error {
+	var group *changeGroup
+	var err error
+	Align(ctx, c.Old, c.New)(func(ar *AlignedRange, e error) bool {
+		if e != nil {
+			err = e
+			return false
+		}
+
+		seg := &changeSegment{
+			IPNet:  ar.IPNet,
+			Old:    fieldValues(ar.Old, c.Fields),
+			New:    fieldValues(ar.New, c.Fields),
+			hasOld: ar.Old != nil,
+			hasNew: ar.New != nil,
+		}
+		if c.segment != nil {
+			c.segment(seg)
+		}
+		if group != nil && !group.continues(seg) {
+			if err = group.emit(c.Fields, fn); err != nil {
+				return false
+			}
+			group = nil
+		}
+		if group == nil {
+			group = &changeGroup{}
+		}
+		group.add(seg)
+		return true
+	})
+	if err == nil && group != nil {
+		err = group.emit(c.Fields, fn)
+	}
+	return err
+}
+
+// changeSegment is an aligned range with the values of both releases.
+type changeSegment struct {
+	IPNet          *ipnet.Range
+	Old, New       []string
+	hasOld, hasNew bool
+}
+
+// changeGroup collects the segments of a change.
+type changeGroup struct {
+	old, new []*ValuedRange
+	segments []*changeSegment
+}
+
+// continues reports whether the range of the old or the new release of the last segment continues in seg.
+func (g *changeGroup) continues(seg *changeSegment) bool {
+	last := g.segments[len(g.segments)-1]
+	if !adjacent(last.IPNet, seg.IPNet) {
+		return false
+	}
+	return (last.hasOld && seg.hasOld && slices.Equal(last.Old, seg.Old)) ||
+		(last.hasNew && seg.hasNew && slices.Equal(last.New, seg.New))
+}
+
+// add adds seg to the group.
+func (g *changeGroup) add(seg *changeSegment) {
+	g.segments = append(g.segments, seg)
+	if seg.hasOld {
+		g.old = appendValuedRange(g.old, seg.IPNet, seg.Old)
+	}
+	if seg.hasNew {
+		g.new = appendValuedRange(g.new, seg.IPNet, seg.New)
+	}
+}
+
+// emit classifies the group and calls fn with the change, unless both releases are the same.
+func (g *changeGroup) emit(fields []string, fn func(change *Change) error) error {
+	change := &Change{Old: g.old, New: g.new, segments: g.segments}
+	seen := make(map[FieldChange]bool)
+	for _, seg := range g.segments {
+		for i, field := range fields {
+			fc := FieldChange{Field: field, Old: seg.Old[i], New: seg.New[i]}
+			if fc.Old != fc.New && !seen[fc] {
+				seen[fc] = true
+				change.Fields = append(change.Fields, fc)
+			}
+		}
+	}
+
+	span := change.Range()
+	covered := covers(g.old, span) && covers(g.new, span)
+	switch {
+	case len(g.old) == 0:
+		change.Type = ChangeAdded
+	case len(g.new) == 0:
+		change.Type = ChangeRemoved
+	case !covered:
+		change.Type = ChangeResized
+	case len(g.old) == 1 && len(g.new) == 1:
+		if len(change.Fields) == 0 {
+			return nil
+		}
+		change.Type = ChangeReassigned
+	case len(g.old) == 1:
+		change.Type = ChangeSplit
+	case len(g.new) == 1:
+		change.Type = ChangeMerged
+	default:
+		change.Type = ChangeResized
+	}
+	return fn(change)
+}
+
+// appendValuedRange appends ipr with values to ranges, extending the last range if it is adjacent and has the same values.
+func appendValuedRange(ranges []*ValuedRange, ipr *ipnet.Range, values []string) []*ValuedRange {
+	if n := len(ranges); n > 0 {
+		last := ranges[n-1]
+		if adjacent(last.IPNet, ipr) && slices.Equal(last.Values, values) {
+			last.IPNet.End = ipr.End
+			return ranges
+		}
+	}
+	return append(ranges, &ValuedRange{IPNet: &ipnet.Range{Start: ipr.Start, End: ipr.End}, Values: values})
+}
+
+// covers reports whether ranges cover span without gaps.
+func covers(ranges []*ValuedRange, span *ipnet.Range) bool {
+	if len(ranges) == 0 || !ranges[0].IPNet.Start.Equal(span.Start) || !ranges[len(ranges)-1].IPNet.End.Equal(span.End) {
+		return false
+	}
+	for i := 1; i < len(ranges); i++ {
+		if !adjacent(ranges[i-1].IPNet, ranges[i].IPNet) {
+			return false
+		}
+	}
+	return true
+}
+
+// adjacent reports whether b begins right after the end of a.
+func adjacent(a, b *ipnet.Range) bool {
+	return ipnet.NextIP(a.End.To16()).Equal(b.Start)
+}
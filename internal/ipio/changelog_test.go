@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangelog(t *testing.T) {
+	ast := assert.New(t)
+
+	fields := []string{"country"}
+	oldReader := newTestReader(fields,
+		testRange{"0.0.0.0", "1.0.0.255", []string{"A"}},
+		testRange{"1.0.1.0", "1.0.1.255", []string{"B"}},
+		testRange{"1.0.2.0", "1.0.3.255", []string{"D"}},
+		testRange{"1.0.4.0", "1.0.4.255", []string{"F"}},
+		testRange{"1.0.5.0", "1.0.5.255", []string{"G"}},
+		testRange{"1.0.6.0", "1.0.6.255", []string{"H"}},
+		testRange{"1.0.7.0", "1.0.7.255", []string{"I"}},
+		testRange{"1.0.8.0", "1.0.8.255", nil},
+		testRange{"1.0.9.0", "1.0.9.255", []string{"K"}},
+		testRange{"1.0.10.0", "255.255.255.255", []string{"L"}})
+	newReader := newTestReader(fields,
+		testRange{"0.0.0.0", "0.255.255.255", []string{"A"}},
+		testRange{"1.0.0.0", "1.0.0.255", []string{"A"}},
+		testRange{"1.0.1.0", "1.0.1.255", []string{"C"}},
+		testRange{"1.0.2.0", "1.0.2.255", []string{"D"}},
+		testRange{"1.0.3.0", "1.0.3.255", []string{"E"}},
+		testRange{"1.0.4.0", "1.0.5.255", []string{"F"}},
+		testRange{"1.0.6.0", "1.0.6.127", []string{"H"}},
+		testRange{"1.0.6.128", "1.0.7.255", []string{"I"}},
+		testRange{"1.0.8.0", "1.0.8.255", []string{"J"}},
+		testRange{"1.0.9.0", "1.0.9.255", nil},
+		testRange{"1.0.10.0", "255.255.255.255", []string{"L"}})
+
+	var changes []string
+	var patch []string
+	err := NewChangelog(oldReader, newReader, fields).Changes(context.Background(), func(c *Change) error {
+		changes = append(changes, fmt.Sprintf("%s %s-%s %d/%d %v", c.Type, c.Range().Start, c.Range().End, len(c.Old), len(c.New), c.Fields))
+		for _, r := range c.Patch() {
+			patch = append(patch, fmt.Sprintf("%s-%s %v", r.IPNet.Start, r.IPNet.End, r.Values))
+		}
+		return nil
+	})
+	ast.Nil(err)
+	ast.Equal([]string{
+		"reassigned 1.0.1.0-1.0.1.255 1/1 [{country B C}]",
+		"split 1.0.2.0-1.0.3.255 1/2 [{country D E}]",
+		"merged 1.0.4.0-1.0.5.255 2/1 [{country G F}]",
+		"resized 1.0.6.0-1.0.7.255 2/2 [{country H I}]",
+		"added 1.0.8.0-1.0.8.255 0/1 [{country  J}]",
+		"removed 1.0.9.0-1.0.9.255 1/0 [{country K }]",
+	}, changes)
+	ast.Equal([]string{
+		"1.0.1.0-1.0.1.255 [C]",
+		"1.0.2.0-1.0.2.255 [D]",
+		"1.0.3.0-1.0.3.255 [E]",
+		"1.0.4.0-1.0.5.255 [F]",
+		"1.0.6.0-1.0.6.127 [H]",
+		"1.0.6.128-1.0.7.255 [I]",
+		"1.0.8.0-1.0.8.255 [J]",
+		"1.0.9.0-1.0.9.255 []",
+	}, patch)
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"context"
+	"net"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// FindContext retrieves IP information from the reader based on the given IP address, honoring ctx.
+// Readers implementing format.ContextReader handle ctx themselves. Other readers look up ip in memory,
+// so the lookup runs in the calling goroutine, and ctx is checked before and after it.
+func FindContext(ctx context.Context, r format.Reader, ip net.IP) (*model.IPInfo, error) {
+	if cr, ok := r.(format.ContextReader); ok {
+		return cr.FindContext(ctx, ip)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	info, err := r.Find(ip)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return info, err
+}
+
+// findAsync is like FindContext, but runs the lookup in its own goroutine when ctx can be cancelled,
+// and returns ctx.Err() as soon as ctx is done, leaving the lookup to finish in the background.
+// It is used where a deadline has to be met even by a slow reader, e.g. the source timeout of HybridReader.
+func findAsync(ctx context.Context, r format.Reader, ip net.IP) (*model.IPInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		return FindContext(ctx, r, ip)
+	}
+
+	type result struct {
+		info *model.IPInfo
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		info, err := FindContext(ctx, r, ip)
+		ch <- result{info: info, err: err}
+	}()
+
+	select {
+	case ret := <-ch:
+		return ret.info, ret.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
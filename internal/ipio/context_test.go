@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/format/ipdb"
+	"github.com/sjzar/ips/format/plain"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+func TestHybridReaderTimeout(t *testing.T) {
+	ast := assert.New(t)
+
+	fast := newTestReader([]string{"country"}, testRange{"0.0.0.0", "255.255.255.255", []string{"A"}})
+	slow := newTestReader([]string{"isp"}, testRange{"0.0.0.0", "255.255.255.255", []string{"X"}})
+	slow.delay = 500 * time.Millisecond
+
+	reader, err := NewHybridReader(nil, NewStandardReader(fast, nil), NewStandardReader(slow, nil))
+	ast.Nil(err)
+	ast.Nil(reader.SetOption(HybridReaderOption{Timeout: 20 * time.Millisecond}))
+
+	start := time.Now()
+	info, err := reader.Find(net.ParseIP("1.1.1.1"))
+	ast.Nil(err)
+	ast.Less(time.Since(start), 400*time.Millisecond)
+	ast.Equal("A", info.Data["0_country"])
+	_, ok := info.Data["1_isp"]
+	ast.False(ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = reader.FindContext(ctx, net.ParseIP("1.1.1.1"))
+	ast.Equal(context.Canceled, err)
+}
+
+func TestDumpContext(t *testing.T) {
+	ast := assert.New(t)
+
+	dbReader := newTestReader([]string{"country"}, testRange{"0.0.0.0", "255.255.255.255", []string{"A"}})
+	writer, err := plain.NewWriter(&model.Meta{Fields: []string{"country"}})
+	ast.Nil(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = NewStandardDumper(dbReader, writer).DumpContext(ctx, 1)
+	ast.Equal(context.Canceled, err)
+
+	// a failed transfer aborts the writer instead of finishing a truncated database
+	ipdbWriter, err := ipdb.NewWriter(&model.Meta{IPVersion: model.IPv4, Fields: []string{"country"}})
+	ast.Nil(err)
+	output := &bytes.Buffer{}
+	err = NewStandardDumper(dbReader, ipdbWriter).DumpTo(ctx, 1, output)
+	ast.Equal(context.Canceled, err)
+	ast.Equal(0, output.Len())
+
+	_, err = FindContext(ctx, dbReader, net.ParseIP("1.1.1.1"))
+	ast.Equal(context.Canceled, err)
+	_, err = FindBatchContext(ctx, NewStandardReader(dbReader, nil), []net.IP{net.ParseIP("1.1.1.1")})
+	ast.Equal(context.Canceled, err)
+
+	// lookups run in the calling goroutine and report a deadline passed during the lookup
+	slow := newTestReader([]string{"country"}, testRange{"0.0.0.0", "255.255.255.255", []string{"A"}})
+	slow.delay = 20 * time.Millisecond
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	_, err = FindContext(ctx, slow, net.ParseIP("1.1.1.1"))
+	ast.Equal(context.DeadlineExceeded, err)
+	ast.Equal(1, slow.finds)
+}
@@ -0,0 +1,242 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"context"
+	"math/big"
+	"slices"
+	"sort"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// DefaultDiffTop is the default number of value changes listed for each field in the diff statistics.
+const DefaultDiffTop = 10
+
+// DiffRange is an IP range in which the values of the compared fields differ between two readers.
+type DiffRange struct {
+	IPNet *ipnet.Range
+
+	// Old and New are the values of the fields in the old and the new reader, empty if a reader has no data.
+	Old, New []string
+
+	// Changed lists the fields with different values.
+	Changed []string
+}
+
+// DiffStats holds the statistics of a diff in numbers of addresses.
+type DiffStats struct {
+
+	// Addresses is the number of addresses with data in either reader.
+	Addresses *big.Int `json:"addresses"`
+
+	// Changed is the number of addresses with different values in any field.
+	Changed *big.Int `json:"changed"`
+
+	// Share is the share of Changed in Addresses.
+	Share float64 `json:"share"`
+
+	// Ranges is the number of differing ranges.
+	Ranges int `json:"ranges"`
+
+	// Fields holds the statistics of each field.
+	Fields []*FieldDiffStats `json:"fields"`
+}
+
+// FieldDiffStats holds the diff statistics of a field.
+type FieldDiffStats struct {
+	Field string `json:"field"`
+
+	// Changed is the number of addresses with different values of the field.
+	Changed *big.Int `json:"changed"`
+
+	// Share is the share of Changed in the addresses with data in either reader.
+	Share float64 `json:"share"`
+
+	// Top lists the most frequent value changes of the field by addresses.
+	Top []*ValueChange `json:"top"`
+
+	changes map[[2]string]*big.Int
+}
+
+// ValueChange is a change of the value of a field, with the number of addresses it applies to.
+type ValueChange struct {
+	Old       string   `json:"old"`
+	New       string   `json:"new"`
+	Addresses *big.Int `json:"addresses"`
+}
+
+// Differ compares the values of fields of two readers range by range.
+type Differ struct {
+	Old    format.Reader
+	New    format.Reader
+	Fields []string
+
+	// Top is the number of value changes listed for each field in the statistics.
+	Top int
+}
+
+// NewDiffer initializes a Differ comparing the fields of the old and the new reader.
+// Fields are looked up by the names of either the database or the common fields.
+func NewDiffer(oldReader, newReader format.Reader, fields []string) *Differ {
+	return &Differ{
+		Old:    oldReader,
+		New:    newReader,
+		Fields: fields,
+		Top:    DefaultDiffTop,
+	}
+}
+
+// Diff walks both readers over their address space and calls fn with each differing range in ascending order,
+// merging adjacent ranges with the same values. It returns the statistics of the comparison.
+func (d *Differ) Diff(ctx context.Context, fn func(r *DiffRange) error) (*DiffStats, error) {
+	stats := newDiffStats(d.Fields)
+
+	var pending *DiffRange
+	flush := func() error {
+		if pending == nil {
+			return nil
+		}
+		r := pending
+		pending = nil
+		stats.Ranges++
+		return fn(r)
+	}
+
+	var err error
+	Align(ctx, d.Old, d.New)(func(ar *AlignedRange, e error) bool {
+		if e != nil {
+			err = e
+			return false
+		}
+
+		oldValues, newValues := fieldValues(ar.Old, d.Fields), fieldValues(ar.New, d.Fields)
+		changed := stats.add(ar.IPNet.Size(), oldValues, newValues)
+		if len(changed) == 0 {
+			err = flush()
+			return err == nil
+		}
+		if pending != nil && slices.Equal(pending.Old, oldValues) && slices.Equal(pending.New, newValues) && pending.IPNet.Join(ar.IPNet) {
+			return true
+		}
+		if err = flush(); err != nil {
+			return false
+		}
+		pending = &DiffRange{IPNet: ar.IPNet, Old: oldValues, New: newValues, Changed: changed}
+		return true
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stats.finish(d.Top)
+	return stats, nil
+}
+
+// fieldValues returns the values of the fields in info, empty values if info is nil.
+func fieldValues(info *model.IPInfo, fields []string) []string {
+	values := make([]string, len(fields))
+	if info == nil {
+		return values
+	}
+	for i, field := range fields {
+		values[i], _ = info.GetData(field)
+	}
+	return values
+}
+
+// newDiffStats initializes the statistics of a diff of the fields.
+func newDiffStats(fields []string) *DiffStats {
+	stats := &DiffStats{
+		Addresses: new(big.Int),
+		Changed:   new(big.Int),
+		Fields:    make([]*FieldDiffStats, len(fields)),
+	}
+	for i, field := range fields {
+		stats.Fields[i] = &FieldDiffStats{
+			Field:   field,
+			Changed: new(big.Int),
+			changes: make(map[[2]string]*big.Int),
+		}
+	}
+	return stats
+}
+
+// add counts size addresses with the values of the old and the new reader, and returns the changed fields.
+func (s *DiffStats) add(size *big.Int, oldValues, newValues []string) []string {
+	s.Addresses.Add(s.Addresses, size)
+
+	var changed []string
+	for i, f := range s.Fields {
+		if oldValues[i] == newValues[i] {
+			continue
+		}
+		changed = append(changed, f.Field)
+		f.Changed.Add(f.Changed, size)
+		key := [2]string{oldValues[i], newValues[i]}
+		if n, ok := f.changes[key]; ok {
+			n.Add(n, size)
+		} else {
+			f.changes[key] = new(big.Int).Set(size)
+		}
+	}
+	if len(changed) > 0 {
+		s.Changed.Add(s.Changed, size)
+	}
+	return changed
+}
+
+// share returns the share of n in the addresses with data in either reader.
+func (s *DiffStats) share(n *big.Int) float64 {
+	if s.Addresses.Sign() == 0 {
+		return 0
+	}
+	f, _ := new(big.Rat).SetFrac(n, s.Addresses).Float64()
+	return f
+}
+
+// finish computes the shares and the top value changes of the fields.
+func (s *DiffStats) finish(top int) {
+	s.Share = s.share(s.Changed)
+	for _, f := range s.Fields {
+		f.Share = s.share(f.Changed)
+
+		f.Top = make([]*ValueChange, 0, len(f.changes))
+		for key, n := range f.changes {
+			f.Top = append(f.Top, &ValueChange{Old: key[0], New: key[1], Addresses: n})
+		}
+		sort.Slice(f.Top, func(i, j int) bool {
+			if c := f.Top[i].Addresses.Cmp(f.Top[j].Addresses); c != 0 {
+				return c > 0
+			}
+			if f.Top[i].Old != f.Top[j].Old {
+				return f.Top[i].Old < f.Top[j].Old
+			}
+			return f.Top[i].New < f.Top[j].New
+		})
+		if top >= 0 && len(f.Top) > top {
+			f.Top = f.Top[:top]
+		}
+		f.changes = nil
+	}
+}
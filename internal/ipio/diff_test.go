@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffer(t *testing.T) {
+	ast := assert.New(t)
+
+	fields := []string{"country", "isp"}
+	oldReader := newTestReader(fields,
+		testRange{"0.0.0.0", "1.0.0.255", []string{"CN", "X"}},
+		testRange{"1.0.1.0", "1.0.1.255", []string{"CN", "Y"}},
+		testRange{"1.0.2.0", "1.0.2.255", nil},
+		testRange{"1.0.3.0", "255.255.255.255", []string{"US", "Z"}})
+	newReader := newTestReader(fields,
+		testRange{"0.0.0.0", "1.0.0.127", []string{"CN", "X"}},
+		testRange{"1.0.0.128", "1.0.0.191", []string{"JP", "X"}},
+		testRange{"1.0.0.192", "1.0.1.127", []string{"JP", "X"}},
+		testRange{"1.0.1.128", "1.0.2.127", []string{"CN", "Y"}},
+		testRange{"1.0.2.128", "255.255.255.255", []string{"US", "Z"}})
+	newReader.ipv4 = true
+
+	var ranges []string
+	stats, err := NewDiffer(oldReader, newReader, fields).Diff(context.Background(), func(r *DiffRange) error {
+		ranges = append(ranges, fmt.Sprintf("%s-%s %v %v %v", r.IPNet.Start, r.IPNet.End, r.Old, r.New, r.Changed))
+		return nil
+	})
+	ast.Nil(err)
+	ast.Equal([]string{
+		"1.0.0.128-1.0.0.255 [CN X] [JP X] [country]",
+		"1.0.1.0-1.0.1.127 [CN Y] [JP X] [country isp]",
+		"1.0.2.0-1.0.2.127 [ ] [CN Y] [country isp]",
+		"1.0.2.128-1.0.2.255 [ ] [US Z] [country isp]",
+	}, ranges)
+
+	ast.Equal("4294967296", stats.Addresses.String())
+	ast.Equal("512", stats.Changed.String())
+	ast.Equal(4, stats.Ranges)
+	ast.Equal("country", stats.Fields[0].Field)
+	ast.Equal("512", stats.Fields[0].Changed.String())
+	ast.InDelta(512.0/4294967296, stats.Fields[0].Share, 1e-12)
+	ast.Equal("384", stats.Fields[1].Changed.String())
+
+	top := make([]string, 0, len(stats.Fields[0].Top))
+	for _, c := range stats.Fields[0].Top {
+		top = append(top, fmt.Sprintf("%s>%s:%s", c.Old, c.New, c.Addresses))
+	}
+	ast.Equal([]string{"CN>JP:256", ">CN:128", ">US:128"}, top)
+
+	// the same database has no differences
+	ranges = nil
+	sameReader := *oldReader
+	stats, err = NewDiffer(oldReader, &sameReader, fields).Diff(context.Background(), func(r *DiffRange) error {
+		ranges = append(ranges, r.IPNet.Start.String())
+		return nil
+	})
+	ast.Nil(err)
+	ast.Nil(ranges)
+	ast.Equal("0", stats.Changed.String())
+	ast.Equal("4294967040", stats.Addresses.String())
+}
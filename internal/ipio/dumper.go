@@ -18,6 +18,7 @@ package ipio
 
 import (
 	"context"
+	"io"
 	"net"
 	"runtime"
 	"slices"
@@ -61,7 +62,26 @@ func Dump(r format.Reader, w format.Writer) error {
 }
 
 // Dump transfers IP data from the Reader to the Writer.
+// If the Reader implements format.RangeIterator, its ranges are streamed in order,
+// otherwise the address space is probed with Find by readerJobs goroutines.
+// Ranges without data are skipped.
 func (d *StandardDumper) Dump(readerJobs int) error {
+	return d.DumpContext(context.Background(), readerJobs)
+}
+
+// DumpContext is like Dump but stops with ctx.Err() once ctx is done.
+func (d *StandardDumper) DumpContext(ctx context.Context, readerJobs int) error {
+	ipStart, ipEnd := addressSpace(d.Meta())
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if it, ok := d.Reader.(format.RangeIterator); ok {
+		if seq := it.Ranges(ctx, ipStart, ipEnd); seq != nil {
+			return d.dumpRanges(seq)
+		}
+	}
+
 	if readerJobs <= 0 {
 		switch d.WriterFormat() {
 		case plain.DBFormat:
@@ -71,17 +91,8 @@ func (d *StandardDumper) Dump(readerJobs int) error {
 		}
 	}
 
-	ipStart, ipEnd := net.IPv4(0, 0, 0, 0), ipnet.LastIPv4
-	if d.Meta().IsIPv6Support() {
-		ipStart, ipEnd = make(net.IP, net.IPv6len), ipnet.LastIPv6
-	}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	retChan := make(chan *model.IPInfo, ChannelBufferSize)
 	errChan := make(chan error, readerJobs)
-	defer close(errChan)
 	wg := sync.WaitGroup{}
 
 	split := ipnet.SplitIPNet(ipStart, ipEnd, readerJobs)
@@ -110,7 +121,13 @@ func (d *StandardDumper) Dump(readerJobs int) error {
 		select {
 		case ipInfo, ok := <-retChan:
 			if !ok {
-				return nil
+				// all jobs are done, their errors are already buffered
+				select {
+				case err := <-errChan:
+					return err
+				default:
+				}
+				return ctx.Err()
 			}
 			if err := d.Insert(ipInfo); err != nil {
 				log.Debug("StandardDumper Insert() failed ", ipInfo, err)
@@ -121,10 +138,74 @@ func (d *StandardDumper) Dump(readerJobs int) error {
 				log.Debug("StandardDumper Dump2() failed ", err)
 				return err
 			}
+
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 }
 
+// DumpTo transfers IP data from the Reader to the Writer and writes the result to w.
+// A format.StreamWriter is opened on w before the transfer and closed after it,
+// other writers are written to w by WriteTo once the transfer is done.
+// If the transfer fails, a format.StreamWriter is aborted instead of closed, so the output is never
+// finished with partial data; streaming writers may have written some records to w already.
+func (d *StandardDumper) DumpTo(ctx context.Context, readerJobs int, w io.Writer) error {
+	sw, ok := d.Writer.(format.StreamWriter)
+	if !ok {
+		if err := d.DumpContext(ctx, readerJobs); err != nil {
+			return err
+		}
+		_, err := d.Writer.WriteTo(w)
+		return err
+	}
+
+	if err := sw.Open(w); err != nil {
+		return err
+	}
+	if err := d.DumpContext(ctx, readerJobs); err != nil {
+		_ = sw.Abort()
+		return err
+	}
+	return sw.Close()
+}
+
+// dumpRanges writes the ranges from seq to the Writer, merging adjacent ranges with the same values.
+func (d *StandardDumper) dumpRanges(seq model.IPInfoSeq) error {
+	var current *model.IPInfo
+	var values []string
+	var err error
+
+	seq(func(info *model.IPInfo, e error) bool {
+		if e != nil {
+			err = e
+			return false
+		}
+
+		info.IPNet = &ipnet.Range{Start: info.IPNet.Start.To16(), End: info.IPNet.End.To16()}
+		infoValues := info.Values()
+		if current != nil && slices.Equal(values, infoValues) && current.IPNet.Join(info.IPNet) {
+			return true
+		}
+		if current != nil {
+			if err = d.Insert(current); err != nil {
+				log.Debug("StandardDumper Insert() failed ", current, err)
+				return false
+			}
+		}
+		current, values = info, infoValues
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if current != nil {
+		return d.Insert(current)
+	}
+	return nil
+}
+
 // SimpleDumper is a structure that facilitates the extraction of IP information within a specified range.
 type SimpleDumper struct {
 	format.Reader
@@ -141,7 +222,7 @@ func (d *SimpleDumper) Dump(ctx context.Context, retChan chan<- *model.IPInfo) e
 	}
 
 	marker := d.ipStart
-	info, err := d.Find(marker)
+	info, err := d.find(marker)
 	if err != nil {
 		return err
 	}
@@ -155,7 +236,7 @@ func (d *SimpleDumper) Dump(ctx context.Context, retChan chan<- *model.IPInfo) e
 	for done := d.done(marker, false); !done; done = d.done(marker, true) {
 		select {
 		case <-ctx.Done(): // Check if the operation was cancelled.
-			return nil
+			return ctx.Err()
 		default:
 		}
 		info, err = d.next(marker)
@@ -165,13 +246,60 @@ func (d *SimpleDumper) Dump(ctx context.Context, retChan chan<- *model.IPInfo) e
 		if info == nil {
 			break
 		}
-		retChan <- info // Send IP information to the channel.
+		// Send IP information to the channel.
+		select {
+		case retChan <- info:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 		marker = ipnet.NextIP(info.IPNet.End.To16())
 	}
 
 	return nil
 }
 
+// Ranges returns an iterator over the IP ranges in the specified range in ascending order,
+// merging adjacent ranges with the same values. Unlike Dump, the first range is not skipped
+// if it begins before the start of the range.
+func (d *SimpleDumper) Ranges(ctx context.Context) model.IPInfoSeq {
+	return func(yield func(*model.IPInfo, error) bool) {
+		if d.ipStart == nil || d.ipEnd == nil || ipnet.IPLess(d.ipEnd, d.ipStart) {
+			yield(nil, errors.ErrInvalidIPRange)
+			return
+		}
+
+		marker := d.ipStart
+		for started := false; !d.done(marker, started); started = true {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			info, err := d.next(marker)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if info == nil || !yield(info, nil) {
+				return
+			}
+			marker = ipnet.NextIP(info.IPNet.End.To16())
+		}
+	}
+}
+
+// Ranges returns an iterator over the IP ranges of the reader in [start, end] in ascending order.
+// Readers implementing format.RangeIterator enumerate their ranges natively,
+// the address space of the others is probed with Find.
+func Ranges(ctx context.Context, r format.Reader, start, end net.IP) model.IPInfoSeq {
+	if it, ok := r.(format.RangeIterator); ok {
+		if seq := it.Ranges(ctx, start, end); seq != nil {
+			return seq
+		}
+	}
+	d := &SimpleDumper{Reader: r, ipStart: start.To16(), ipEnd: end.To16()}
+	return d.Ranges(ctx)
+}
+
 // done checks whether the end of the IP range has been reached.
 func (d *SimpleDumper) done(marker net.IP, started bool) bool {
 	if marker == nil {
@@ -187,6 +315,16 @@ func (d *SimpleDumper) done(marker net.IP, started bool) bool {
 	return started && d.ipEnd.Equal(ipnet.PrevIP(marker))
 }
 
+// find retrieves the IP information of marker. If the Reader has no data for marker,
+// the unknown range containing it is returned as a result marked NotFound.
+func (d *SimpleDumper) find(marker net.IP) (*model.IPInfo, error) {
+	info, err := d.Find(marker)
+	if notFound, ok := errors.AsNotFound(err); ok && notFound.IPNet != nil {
+		return &model.IPInfo{IP: marker, IPNet: notFound.IPNet, NotFound: true}, nil
+	}
+	return info, err
+}
+
 // next retrieves the next IP information from the Reader based on the marker.
 func (d *SimpleDumper) next(marker net.IP) (*model.IPInfo, error) {
 	if marker == nil {
@@ -195,11 +333,23 @@ func (d *SimpleDumper) next(marker net.IP) (*model.IPInfo, error) {
 
 	var currentInfo *model.IPInfo
 	for {
-		info, err := d.Find(marker)
+		info, err := d.find(marker)
 		if err != nil {
 			return nil, err
 		}
 
+		// Skip unknown ranges, they end the current range.
+		if info.NotFound {
+			if currentInfo != nil {
+				break
+			}
+			marker = ipnet.NextIP(info.IPNet.End.To16())
+			if d.done(marker, true) {
+				break
+			}
+			continue
+		}
+
 		// Determine if a new IP range is encountered.
 		if currentInfo == nil {
 			currentInfo = info
@@ -17,16 +17,21 @@
 package ipio
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"net"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sjzar/ips/format"
 	"github.com/sjzar/ips/internal/operate"
+	"github.com/sjzar/ips/ipnet"
 	"github.com/sjzar/ips/pkg/errors"
 	"github.com/sjzar/ips/pkg/model"
 )
@@ -45,9 +50,38 @@ const (
 	// missing from one reader, it is supplemented by another, ensuring a more
 	// complete and cohesive dataset. This mode is ideal for creating a comprehensive
 	// and enriched view of IP information by leveraging the strengths of multiple databases.
+	// The readers each field is taken from can be set with HybridReaderOption.Priority.
 	HybridAggregationMode = "aggregation"
+
+	// HybridConsensusMode is designed for situations where the databases disagree. In this mode,
+	// each field takes the value agreed on by the most databases, weighted by HybridReaderOption.Weights,
+	// so that a single bad database does not win just because it is listed first. Values are compared
+	// after the rewriting and translation of each database, ignoring case and redundant spaces, and
+	// empty values do not vote. The agreement score of the result is output in HybridAgreementField.
+	HybridConsensusMode = "consensus"
+
+	// HybridCascadeMode is designed for high query rates where the first databases usually answer
+	// on their own. In this mode, the readers are queried one after another in order, and querying stops
+	// once every selected field has a non-empty value and the readers ahead of it in the priority of the field
+	// have been queried. Each field takes the first non-empty value, following HybridReaderOption.Priority
+	// like HybridAggregationMode.
+	HybridCascadeMode = "cascade"
+
+	// HybridTieBreakFirst picks the value of the first listed database among tied values. (default)
+	HybridTieBreakFirst = "first"
+
+	// HybridTieBreakEmpty leaves the fields with tied values empty.
+	HybridTieBreakEmpty = "empty"
+
+	// HybridAgreementField is the field holding the agreement score in HybridConsensusMode.
+	// It is the lowest share of the voting weight behind the value of a field, from 0 to 1.
+	HybridAgreementField = "agreement"
 )
 
+// hybridInlineReaders is the largest number of readers that are queried one after another
+// in the calling goroutine instead of in parallel goroutines, if no timeout is set.
+const hybridInlineReaders = 3
+
 // HybridReader integrates multiple IP database readers into a single entity.
 // It supports various operational modes (like comparison and aggregation) for querying
 // and combining results from different IP databases.
@@ -56,6 +90,12 @@ type HybridReader struct {
 	dbReaders    []format.Reader         // Collection of database readers.
 	meta         *model.Meta             // Combined metadata from all readers.
 	hybridMode   string                  // Operational mode of the HybridReader.
+	timeout      time.Duration           // Lookup timeout of each reader.
+	files        []string                // File names of the readers, recorded in the sources of the results.
+	order        []int                   // Indexes of the readers in order.
+	priority     map[string][]int        // Reader indexes of the fields with a priority in HybridAggregationMode.
+	weights      []float64               // Voting weight of each reader in HybridConsensusMode.
+	tieBreak     string                  // Tie-breaking rule in HybridConsensusMode.
 }
 
 // NewHybridReader constructs a new HybridReader with the provided IP operation chain and database readers.
@@ -84,6 +124,7 @@ func NewHybridReader(operateChain *operate.IPOperateChain, dbReaders ...format.R
 			hybridMeta.IPVersion = readerMeta.IPVersion
 			hybridMeta.Format = readerMeta.Format
 		}
+		hybridMeta.MergeInfo(readerMeta)
 
 		for _, field := range readerMeta.Fields {
 			if strings.HasPrefix(field, fmt.Sprintf("%d_", i)) {
@@ -106,10 +147,16 @@ func NewHybridReader(operateChain *operate.IPOperateChain, dbReaders ...format.R
 		}
 	}
 
+	order := make([]int, len(dbReaders))
+	for i := range order {
+		order[i] = i
+	}
+
 	return &HybridReader{
 		OperateChain: operateChain,
 		dbReaders:    dbReaders,
 		meta:         hybridMeta,
+		order:        order,
 	}, nil
 }
 
@@ -121,29 +168,60 @@ func (h *HybridReader) Meta() *model.Meta {
 // Find performs parallel queries across all underlying database readers using the specified IP address.
 // It combines the results based on the operational mode of the HybridReader: either comparing data or aggregating it.
 func (h *HybridReader) Find(ip net.IP) (*model.IPInfo, error) {
-	var wg sync.WaitGroup
+	return h.FindContext(context.Background(), ip)
+}
+
+// FindContext is like Find but honors ctx.
+// If a source timeout is set, each reader gets its own deadline, and readers that miss it
+// are left out of the result instead of failing the query. Readers without data for ip are
+// left out as well, and errors.NotFoundError is returned if none of the readers has data.
+func (h *HybridReader) FindContext(ctx context.Context, ip net.IP) (*model.IPInfo, error) {
 	results := make([]*model.IPInfo, len(h.dbReaders))
+	notFounds := make([]*errors.NotFoundError, len(h.dbReaders))
+	timeouts := make([]bool, len(h.dbReaders))
 	errs := make([]error, len(h.dbReaders))
+	find := func(index int) {
+		results[index], notFounds[index], timeouts[index], errs[index] = h.find(ctx, index, ip)
+	}
 
-	// Parallel query from each Reader
-	for i, reader := range h.dbReaders {
-		wg.Add(1)
-		go func(index int, rd format.Reader) {
-			defer wg.Done()
-			result, err := rd.Find(ip)
-			results[index] = result
-			if err != nil {
-				errs[index] = fmt.Errorf("error in Reader %d: %w", index, err)
+	switch {
+	case h.hybridMode == HybridCascadeMode:
+		// Sequential query until the selected fields are complete
+		queried := make([]bool, len(h.dbReaders))
+		for i := range h.dbReaders {
+			find(i)
+			queried[i] = true
+			if errs[i] != nil || ctx.Err() != nil || h.complete(results, queried) {
+				break
 			}
-		}(i, reader)
+		}
+	case h.timeout == 0 && len(h.dbReaders) <= hybridInlineReaders:
+		// Sequential query without goroutines for a few readers
+		for i := range h.dbReaders {
+			find(i)
+		}
+	default:
+		// Parallel query from each Reader
+		var wg sync.WaitGroup
+		for i := range h.dbReaders {
+			wg.Add(1)
+			go func(index int) {
+				defer wg.Done()
+				find(index)
+			}(i)
+		}
+		wg.Wait()
 	}
 
-	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// Check for errors and combine results
 	hybridIPInfo := &model.IPInfo{
 		IP:            ip,
 		Data:          make(map[string]string),
+		Typed:         make(map[string]model.Value),
 		FieldAlias:    h.Meta().FieldAlias,
 		Fields:        h.Meta().Fields,
 		ReplaceFields: make(map[string]string),
@@ -156,41 +234,59 @@ func (h *HybridReader) Find(ip net.IP) (*model.IPInfo, error) {
 		}
 	}
 
+	found, timedOut := false, false
 	for i, result := range results {
-		if i == 0 {
-			hybridIPInfo.IPNet = result.IPNet
-		} else if ok := hybridIPInfo.IPNet.CommonRange(ip, result.IPNet); !ok {
-			log.Debug("IPNet.CommonRange() failed ", hybridIPInfo.IPNet, ip, result.IPNet)
+		if result == nil {
+			// timed out, no data or not queried
+			timedOut = timedOut || timeouts[i]
+			continue
+		}
+		found = true
+		if err := narrowRange(hybridIPInfo, i, result.IPNet); err != nil {
+			return nil, err
 		}
 
 		for key, value := range result.Data {
 			prefixedKey := fmt.Sprintf("%d_%s", i, key)
 			hybridIPInfo.Data[prefixedKey] = value
 		}
+		for key, value := range result.Typed {
+			prefixedKey := fmt.Sprintf("%d_%s", i, key)
+			hybridIPInfo.Typed[prefixedKey] = value
+		}
 
 		for key, value := range result.ReplaceFields {
 			prefixedKey := fmt.Sprintf("%d_%s", i, key)
 			hybridIPInfo.ReplaceFields[prefixedKey] = value
 		}
 
-		switch h.hybridMode {
-		case HybridComparisonMode:
-		default:
-			// HybridAggregationMode
-			for _, field := range h.Meta().Fields {
-				if _, ok := hybridIPInfo.Data[field]; ok {
-					continue
-				}
-				val, ok := result.GetData(field)
-				if !ok {
-					continue
-				}
-				hybridIPInfo.Data[field] = val
-				if replaceVal, ok := result.ReplaceFields[field]; ok {
-					hybridIPInfo.Data[field] = replaceVal
-				}
-			}
+	}
+
+	// the result only holds within the unknown ranges of the readers without data
+	for i, notFound := range notFounds {
+		if notFound == nil {
+			continue
+		}
+		if err := narrowRange(hybridIPInfo, i, notFound.IPNet); err != nil {
+			return nil, err
+		}
+	}
+
+	if !found {
+		if timedOut {
+			return nil, context.DeadlineExceeded
 		}
+		return nil, errors.NewNotFoundError(ip, hybridIPInfo.IPNet)
+	}
+
+	switch h.hybridMode {
+	case HybridComparisonMode:
+	case HybridConsensusMode:
+		h.consensus(hybridIPInfo, results)
+	case HybridCascadeMode:
+		h.aggregate(hybridIPInfo, results, true)
+	default:
+		h.aggregate(hybridIPInfo, results, false)
 	}
 
 	if h.OperateChain != nil {
@@ -202,14 +298,314 @@ func (h *HybridReader) Find(ip net.IP) (*model.IPInfo, error) {
 	return hybridIPInfo, nil
 }
 
+// FindBatch retrieves IP information for a batch of IP addresses, in the same order.
+// Addresses in the range of the previous result reuse it instead of querying all readers again.
+func (h *HybridReader) FindBatch(ips []net.IP) ([]*model.IPInfo, error) {
+	return h.FindBatchContext(context.Background(), ips)
+}
+
+// FindBatchContext is like FindBatch but honors ctx.
+func (h *HybridReader) FindBatchContext(ctx context.Context, ips []net.IP) ([]*model.IPInfo, error) {
+	return findBatch(ctx, h.FindContext, ips)
+}
+
+// find looks up ip in the reader at index. If a timeout is set, the reader gets its own deadline,
+// and timedOut reports that it was missed. A reader without data for ip returns notFound.
+func (h *HybridReader) find(ctx context.Context, index int, ip net.IP) (result *model.IPInfo, notFound *errors.NotFoundError, timedOut bool, err error) {
+	if h.timeout > 0 {
+		readerCtx, cancel := context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+		result, err = findAsync(readerCtx, h.dbReaders[index], ip)
+	} else {
+		result, err = FindContext(ctx, h.dbReaders[index], ip)
+	}
+	if err != nil && h.timeout > 0 && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		log.Debugf("HybridReader reader %d timed out after %s", index, h.timeout)
+		return nil, nil, true, nil
+	}
+	if notFound, ok := errors.AsNotFound(err); ok {
+		return nil, notFound, false, nil
+	}
+	if err != nil {
+		return result, nil, false, fmt.Errorf("error in Reader %d: %w", index, err)
+	}
+	return result, nil, false, nil
+}
+
+// narrowRange narrows the range of info to its intersection with ipr, the range the reader at index
+// returned for info.IP. The intersection of the ranges of all readers is the largest range
+// in which none of them changes, so every IP in it gets the same hybrid result,
+// which keeps the ranges of a hybrid dump free of mixed data.
+// The range of info is a copy, the ranges of the readers are never modified.
+func narrowRange(info *model.IPInfo, index int, ipr *ipnet.Range) error {
+	if ipr == nil {
+		return nil
+	}
+	if info.IPNet == nil {
+		info.IPNet = &ipnet.Range{Start: ipr.Start.To16(), End: ipr.End.To16()}
+		if info.IPNet.Contains(info.IP.To16()) {
+			return nil
+		}
+	} else if info.IPNet.CommonRange(info.IP, ipr) {
+		return nil
+	}
+	return fmt.Errorf("error in Reader %d: %w: %s - %s does not contain %s", index, errors.ErrInvalidIPRange, ipr.Start, ipr.End, info.IP)
+}
+
+// pick returns the index and value of the result the field is taken from.
+// It is the first result that has the field, in the order of the priority of the field if it is set.
+// Empty values are skipped if skipEmpty is set or the field has a priority.
+func (h *HybridReader) pick(field string, results []*model.IPInfo, skipEmpty bool) (int, model.Value, bool) {
+	priority, hasPriority := h.priority[field]
+	if !hasPriority {
+		priority = h.order
+	}
+	for _, index := range priority {
+		result := results[index]
+		if result == nil {
+			continue
+		}
+		val, ok := result.GetValue(field)
+		if !ok || (skipEmpty || hasPriority) && len(val.String()) == 0 {
+			continue
+		}
+		return index, val, true
+	}
+	return 0, model.Value{}, false
+}
+
+// complete reports whether the queried readers settle every field, that is every reader
+// ahead of the non-empty value a field takes, in the order of its priority, has been queried.
+// Fields without a non-empty value are settled once all of their readers are queried.
+func (h *HybridReader) complete(results []*model.IPInfo, queried []bool) bool {
+	for _, field := range h.Meta().Fields {
+		priority, ok := h.priority[field]
+		if !ok {
+			priority = h.order
+		}
+		for _, index := range priority {
+			if !queried[index] {
+				return false
+			}
+			if result := results[index]; result != nil {
+				if val, ok := result.GetValue(field); ok && len(val.String()) != 0 {
+					break
+				}
+			}
+		}
+	}
+	return true
+}
+
+// aggregate sets each field of info to the value of the first result that has the field,
+// skipping empty values if skipEmpty is set. Fields with a priority take the first non-empty value
+// of the results in the order of the priority.
+func (h *HybridReader) aggregate(info *model.IPInfo, results []*model.IPInfo, skipEmpty bool) {
+	for _, field := range h.Meta().Fields {
+		if _, ok := info.Data[field]; ok {
+			continue
+		}
+
+		index, val, ok := h.pick(field, results, skipEmpty)
+		if !ok {
+			continue
+		}
+		info.SetValue(field, val)
+		if replaceVal, ok := results[index].ReplaceFields[field]; ok {
+			info.SetValue(field, model.StringValue(replaceVal))
+		}
+		h.setSource(info, field, index)
+	}
+}
+
+// consensus sets each field of info to the value with the most voting weight among the results,
+// and the agreement score to HybridAgreementField.
+func (h *HybridReader) consensus(info *model.IPInfo, results []*model.IPInfo) {
+	type candidate struct {
+		value  model.Value
+		index  int
+		weight float64
+	}
+
+	agreement, voted := 1.0, false
+	for _, field := range h.Meta().Fields {
+		if field == HybridAgreementField {
+			continue
+		}
+		if _, ok := info.Data[field]; ok {
+			continue
+		}
+
+		var candidates []*candidate
+		index := make(map[string]*candidate)
+		total := 0.0
+		for i, result := range results {
+			if result == nil {
+				continue
+			}
+			val, ok := result.GetValue(field)
+			if !ok || len(strings.TrimSpace(val.String())) == 0 {
+				continue
+			}
+			weight := h.weight(i)
+			total += weight
+			key := strings.ToLower(strings.Join(strings.Fields(val.String()), " "))
+			c, ok := index[key]
+			if !ok {
+				c = &candidate{value: val, index: i}
+				index[key] = c
+				candidates = append(candidates, c)
+			}
+			c.weight += weight
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		// candidates are in the order of the readers, so the first one wins ties
+		var winner *candidate
+		tied := false
+		for _, c := range candidates {
+			switch {
+			case winner == nil || c.weight > winner.weight:
+				winner, tied = c, false
+			case c.weight == winner.weight:
+				tied = true
+			}
+		}
+
+		voted = true
+		if total > 0 && winner.weight/total < agreement {
+			agreement = winner.weight / total
+		}
+		if tied && h.tieBreak == HybridTieBreakEmpty {
+			info.SetValue(field, model.StringValue(""))
+			continue
+		}
+		info.SetValue(field, winner.value)
+		if replaceVal, ok := results[winner.index].ReplaceFields[field]; ok {
+			info.SetValue(field, model.StringValue(replaceVal))
+		}
+		h.setSource(info, field, winner.index)
+	}
+
+	if voted {
+		info.SetValue(HybridAgreementField, model.FloatValue(math.Round(agreement*100)/100))
+	}
+}
+
+// setSource records that the value of field comes from the reader at index.
+func (h *HybridReader) setSource(info *model.IPInfo, field string, index int) {
+	if info.Sources == nil {
+		info.Sources = make(map[string]model.Source)
+	}
+	source := model.Source{Index: index, Format: h.dbReaders[index].Meta().Format}
+	if index < len(h.files) {
+		source.File = h.files[index]
+	}
+	info.Sources[field] = source
+}
+
+// weight returns the voting weight of the reader at index, 1 if it is not set.
+func (h *HybridReader) weight(index int) float64 {
+	if index < len(h.weights) {
+		return h.weights[index]
+	}
+	return 1
+}
+
 type HybridReaderOption struct {
 	Mode string
+
+	// Timeout limits the lookup time of each underlying reader, zero means no limit.
+	// Readers that time out are left out of the result.
+	Timeout time.Duration
+
+	// Files lists the file names of the underlying readers, recorded in the sources of the results.
+	Files []string
+
+	// Priority maps fields to the indexes of the readers they are taken from in HybridAggregationMode
+	// and HybridCascadeMode,
+	// e.g. {"country": {1, 0}}. A field takes the first non-empty value of the listed readers,
+	// fields without priority take the value of the first reader that has the field.
+	Priority map[string][]int
+
+	// Weights lists the voting weights of the underlying readers in HybridConsensusMode,
+	// in the order of the readers. Readers without weight vote with weight 1.
+	Weights []float64
+
+	// TieBreak specifies how ties are broken in HybridConsensusMode,
+	// HybridTieBreakFirst (default) or HybridTieBreakEmpty.
+	TieBreak string
 }
 
 // SetOption configures the HybridReader with the provided option, particularly the operational mode.
+// In HybridConsensusMode, HybridAgreementField is added to the fields of the metadata.
 func (h *HybridReader) SetOption(option interface{}) error {
-	if opt, ok := option.(HybridReaderOption); ok {
-		h.hybridMode = opt.Mode
+	opt, ok := option.(HybridReaderOption)
+	if !ok {
+		return errors.ErrUnsupportedOption
+	}
+
+	switch opt.Mode {
+	case "", HybridAggregationMode, HybridComparisonMode, HybridConsensusMode, HybridCascadeMode:
+	default:
+		return errors.ErrInvalidHybridMode
+	}
+	switch opt.TieBreak {
+	case "", HybridTieBreakFirst, HybridTieBreakEmpty:
+	default:
+		return errors.ErrInvalidTieBreak
+	}
+	for field, indexes := range opt.Priority {
+		if !h.hasField(field) {
+			return fmt.Errorf("%w: unknown field %s", errors.ErrInvalidHybridPriority, field)
+		}
+		for _, index := range indexes {
+			if index < 0 || index >= len(h.dbReaders) {
+				return errors.ErrInvalidHybridPriority
+			}
+		}
+	}
+	if len(opt.Weights) > len(h.dbReaders) {
+		return errors.ErrInvalidWeights
+	}
+	for _, weight := range opt.Weights {
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return errors.ErrInvalidWeights
+		}
+	}
+
+	h.hybridMode = opt.Mode
+	h.timeout = opt.Timeout
+	h.files = opt.Files
+	h.priority = opt.Priority
+	h.weights = opt.Weights
+	h.tieBreak = opt.TieBreak
+	if h.hybridMode == HybridConsensusMode && !slices.Contains(h.meta.Fields, HybridAgreementField) {
+		h.meta.Fields = append(h.meta.Fields, HybridAgreementField)
+	}
+	return nil
+}
+
+// hasField reports whether field is a field or a common field of any of the readers.
+func (h *HybridReader) hasField(field string) bool {
+	for _, reader := range h.dbReaders {
+		if reader.Meta().SupportFields()[field] {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPriority checks that the fields with a priority are among the fields of the metadata,
+// to be called once the fields are selected. The priority of other fields would never be used,
+// e.g. if no fields are selected, the fields keep the prefixes of their readers and are not aggregated.
+func (h *HybridReader) CheckPriority() error {
+	for field := range h.priority {
+		if !slices.Contains(h.meta.Fields, field) {
+			return fmt.Errorf("%w: field %s is not selected", errors.ErrInvalidHybridPriority, field)
+		}
 	}
 	return nil
 }
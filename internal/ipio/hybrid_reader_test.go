@@ -0,0 +1,373 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/internal/operate"
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// newTestHybridReader creates a HybridReader of test readers with the fields country and isp,
+// selecting the given fields.
+func newTestHybridReader(option HybridReaderOption, fields string, values ...[]string) (*HybridReader, error) {
+	readers := make([]format.Reader, 0, len(values))
+	for _, v := range values {
+		readers = append(readers, NewStandardReader(newTestReader([]string{"country", "isp"}, testRange{"0.0.0.0", "255.255.255.255", v}), nil))
+	}
+	reader, err := NewHybridReader(nil, readers...)
+	if err != nil {
+		return nil, err
+	}
+	if err := reader.SetOption(option); err != nil {
+		return nil, err
+	}
+	fs, err := operate.NewFieldSelector(reader.Meta(), fields)
+	if err != nil {
+		return nil, err
+	}
+	reader.OperateChain.Use(fs.Do)
+	if err := reader.CheckPriority(); err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+func TestHybridReaderPriority(t *testing.T) {
+	ast := assert.New(t)
+
+	values := [][]string{{"A", "X"}, {"", "Y"}, {"C", ""}}
+	reader, err := newTestHybridReader(HybridReaderOption{}, "country,isp", values...)
+	ast.Nil(err)
+	info, err := reader.Find(net.ParseIP("1.1.1.1"))
+	ast.Nil(err)
+	ast.Equal([]string{"A", "X"}, info.Values())
+
+	// empty values fall back to the next listed reader
+	reader, err = newTestHybridReader(HybridReaderOption{Priority: map[string][]int{"country": {1, 2, 0}, "isp": {2, 1}}}, "country,isp", values...)
+	ast.Nil(err)
+	info, err = reader.Find(net.ParseIP("1.1.1.1"))
+	ast.Nil(err)
+	ast.Equal([]string{"C", "Y"}, info.Values())
+	ast.Equal(map[string]model.Source{
+		"country": {Index: 2, Format: "test"},
+		"isp":     {Index: 1, Format: "test"},
+	}, info.Sources)
+
+	// unlisted readers are not used
+	reader, err = newTestHybridReader(HybridReaderOption{Priority: map[string][]int{"country": {1}}}, "country,isp", values...)
+	ast.Nil(err)
+	info, err = reader.Find(net.ParseIP("1.1.1.1"))
+	ast.Nil(err)
+	ast.Equal([]string{"", "X"}, info.Values())
+
+	_, err = newTestHybridReader(HybridReaderOption{Priority: map[string][]int{"country": {3}}}, "country,isp", values...)
+	ast.Equal(errors.ErrInvalidHybridPriority, err)
+	// fields unknown to the readers or not selected
+	_, err = newTestHybridReader(HybridReaderOption{Priority: map[string][]int{"city": {1, 0}}}, "country,isp", values...)
+	ast.ErrorIs(err, errors.ErrInvalidHybridPriority)
+	_, err = newTestHybridReader(HybridReaderOption{Priority: map[string][]int{"0_country": {1, 0}}}, "country,isp", values...)
+	ast.ErrorIs(err, errors.ErrInvalidHybridPriority)
+	_, err = newTestHybridReader(HybridReaderOption{Priority: map[string][]int{"isp": {1, 0}}}, "country", values...)
+	ast.ErrorIs(err, errors.ErrInvalidHybridPriority)
+	_, err = newTestHybridReader(HybridReaderOption{Priority: map[string][]int{"country": {1, 0}}}, "*", values...)
+	ast.ErrorIs(err, errors.ErrInvalidHybridPriority)
+}
+
+func TestHybridReaderCascade(t *testing.T) {
+	ast := assert.New(t)
+
+	newReaders := func(values ...[]string) []*testReader {
+		readers := make([]*testReader, 0, len(values))
+		for _, v := range values {
+			readers = append(readers, newTestReader([]string{"country", "isp"}, testRange{"0.0.0.0", "255.255.255.255", v}))
+		}
+		return readers
+	}
+	find := func(priority map[string][]int, fields string, readers ...*testReader) *model.IPInfo {
+		standardReaders := make([]format.Reader, 0, len(readers))
+		for _, r := range readers {
+			standardReaders = append(standardReaders, NewStandardReader(r, nil))
+		}
+		reader, err := NewHybridReader(nil, standardReaders...)
+		ast.Nil(err)
+		ast.Nil(reader.SetOption(HybridReaderOption{Mode: HybridCascadeMode, Priority: priority}))
+		fs, err := operate.NewFieldSelector(reader.Meta(), fields)
+		ast.Nil(err)
+		reader.OperateChain.Use(fs.Do)
+		info, err := reader.Find(net.ParseIP("1.1.1.1"))
+		ast.Nil(err)
+		return info
+	}
+
+	// querying stops once the selected fields are complete
+	readers := newReaders([]string{"A", ""}, []string{"", "Y"}, []string{"C", "Z"})
+	info := find(nil, "country,isp", readers...)
+	ast.Equal([]string{"A", "Y"}, info.Values())
+	ast.Equal([]int{1, 1, 0}, []int{readers[0].finds, readers[1].finds, readers[2].finds})
+
+	readers = newReaders([]string{"A", ""}, []string{"", "Y"}, []string{"C", "Z"})
+	info = find(nil, "country", readers...)
+	ast.Equal([]string{"A"}, info.Values())
+	ast.Equal([]int{1, 0, 0}, []int{readers[0].finds, readers[1].finds, readers[2].finds})
+
+	// incomplete fields query all readers
+	readers = newReaders([]string{"A", ""}, []string{"", ""})
+	info = find(nil, "country,isp", readers...)
+	ast.Equal([]string{"A", ""}, info.Values())
+	ast.Equal([]int{1, 1}, []int{readers[0].finds, readers[1].finds})
+
+	// readers ahead in the priority of a field are queried before stopping
+	readers = newReaders([]string{"A", "X"}, []string{"B", "Y"})
+	info = find(map[string][]int{"country": {1, 0}}, "country,isp", readers...)
+	ast.Equal([]string{"B", "X"}, info.Values())
+	ast.Equal([]int{1, 1}, []int{readers[0].finds, readers[1].finds})
+
+	readers = newReaders([]string{"A", "X"}, []string{"B", "Y"}, []string{"C", "Z"})
+	info = find(map[string][]int{"country": {1}}, "country,isp", readers...)
+	ast.Equal([]string{"B", "X"}, info.Values())
+	ast.Equal([]int{1, 1, 0}, []int{readers[0].finds, readers[1].finds, readers[2].finds})
+}
+
+func TestHybridReaderConsensus(t *testing.T) {
+	ast := assert.New(t)
+
+	newReader := func(option HybridReaderOption, values ...[]string) (*HybridReader, error) {
+		return newTestHybridReader(option, "country,isp,"+HybridAgreementField, values...)
+	}
+	find := func(reader *HybridReader) []string {
+		info, err := reader.Find(net.ParseIP("1.1.1.1"))
+		ast.Nil(err)
+		return info.Values()
+	}
+
+	// the majority wins over the first reader, values are compared ignoring case and spaces
+	reader, err := newReader(HybridReaderOption{Mode: HybridConsensusMode}, []string{"B", "X"}, []string{"a ", "X"}, []string{"A", "X"})
+	ast.Nil(err)
+	ast.Equal([]string{"a ", "X", "0.67"}, find(reader))
+
+	// weights
+	reader, err = newReader(HybridReaderOption{Mode: HybridConsensusMode, Weights: []float64{3}}, []string{"B", "X"}, []string{"A", "X"}, []string{"A", ""})
+	ast.Nil(err)
+	ast.Equal([]string{"B", "X", "0.6"}, find(reader))
+
+	// ties and empty values
+	reader, err = newReader(HybridReaderOption{Mode: HybridConsensusMode}, []string{"A", ""}, []string{"B", "Y"})
+	ast.Nil(err)
+	ast.Equal([]string{"A", "Y", "0.5"}, find(reader))
+	reader, err = newReader(HybridReaderOption{Mode: HybridConsensusMode, TieBreak: HybridTieBreakEmpty}, []string{"A", ""}, []string{"B", "Y"})
+	ast.Nil(err)
+	ast.Equal([]string{"", "Y", "0.5"}, find(reader))
+
+	// invalid options
+	_, err = newReader(HybridReaderOption{Mode: "vote"}, []string{"A", ""})
+	ast.Equal(errors.ErrInvalidHybridMode, err)
+	_, err = newReader(HybridReaderOption{Mode: HybridConsensusMode, Weights: []float64{1, 1}}, []string{"A", ""})
+	ast.Equal(errors.ErrInvalidWeights, err)
+	_, err = newReader(HybridReaderOption{Mode: HybridConsensusMode, Weights: []float64{-1}}, []string{"A", ""})
+	ast.Equal(errors.ErrInvalidWeights, err)
+	_, err = newReader(HybridReaderOption{Mode: HybridConsensusMode, TieBreak: "last"}, []string{"A", ""})
+	ast.Equal(errors.ErrInvalidTieBreak, err)
+}
+
+// rangeWriter collects the ranges and values inserted by a dump.
+type rangeWriter struct {
+	ranges []string
+}
+
+func (w *rangeWriter) SetOption(option interface{}) error { return nil }
+
+func (w *rangeWriter) Insert(info *model.IPInfo) error {
+	w.ranges = append(w.ranges, info.IPNet.Start.String()+"-"+info.IPNet.End.String()+" "+strings.Join(info.Values(), ","))
+	return nil
+}
+
+func (w *rangeWriter) WriteTo(io.Writer) (int64, error) { return 0, nil }
+
+func (w *rangeWriter) WriterFormat() string { return "test" }
+
+func TestHybridReaderDump(t *testing.T) {
+	ast := assert.New(t)
+
+	// the boundaries of the readers do not line up, and b returns IPv4 length ranges
+	a := newTestReader([]string{"country"},
+		testRange{"0.0.0.0", "1.0.0.255", []string{"A1"}},
+		testRange{"1.0.1.0", "255.255.255.255", []string{"A2"}})
+	b := newTestReader([]string{"country"},
+		testRange{"0.0.0.0", "1.0.0.127", []string{"B1"}},
+		testRange{"1.0.0.128", "1.0.1.127", []string{"B2"}},
+		testRange{"1.0.1.128", "255.255.255.255", []string{"B3"}})
+	b.ipv4 = true
+	c := newTestReader([]string{"country"},
+		testRange{"0.0.0.0", "1.0.0.191", []string{"C1"}},
+		testRange{"1.0.0.192", "1.0.0.223", nil},
+		testRange{"1.0.0.224", "255.255.255.255", []string{"C1"}})
+
+	reader, err := NewHybridReader(nil, NewStandardReader(a, nil), NewStandardReader(b, nil), NewStandardReader(c, nil))
+	ast.Nil(err)
+	ast.Nil(reader.SetOption(HybridReaderOption{Mode: HybridComparisonMode}))
+
+	// each range is the intersection of the source ranges, the readers are left untouched
+	info, err := reader.Find(net.ParseIP("1.0.0.200"))
+	ast.Nil(err)
+	ast.Equal("1.0.0.192", info.IPNet.Start.String())
+	ast.Equal("1.0.0.223", info.IPNet.End.String())
+	info, err = a.Find(net.ParseIP("1.0.0.200"))
+	ast.Nil(err)
+	ast.Equal("0.0.0.0", info.IPNet.Start.String())
+
+	// source returns a test reader with the field country, in IPv4 length if ipv4 is set
+	source := func(ipv4 bool, ranges ...testRange) *testReader {
+		r := newTestReader([]string{"country"}, ranges...)
+		r.ipv4 = ipv4
+		return r
+	}
+	tests := []struct {
+		name     string
+		sources  []*testReader
+		expected []string
+	}{
+		{
+			name:    "misaligned",
+			sources: []*testReader{a, b, c},
+			expected: []string{
+				"0.0.0.0-1.0.0.127 A1,B1,C1",
+				"1.0.0.128-1.0.0.191 A1,B2,C1",
+				"1.0.0.192-1.0.0.223 A1,B2,",
+				"1.0.0.224-1.0.0.255 A1,B2,C1",
+				"1.0.1.0-1.0.1.127 A2,B2,C1",
+				"1.0.1.128-255.255.255.255 A2,B3,C1",
+			},
+		},
+		{
+			name: "nested",
+			sources: []*testReader{
+				source(false, testRange{"0.0.0.0", "255.255.255.255", []string{"A"}}),
+				source(false,
+					testRange{"0.0.0.0", "0.255.255.255", []string{"B1"}},
+					testRange{"1.0.0.0", "1.0.0.255", []string{"B2"}},
+					testRange{"1.0.1.0", "255.255.255.255", []string{"B3"}}),
+			},
+			expected: []string{
+				"0.0.0.0-0.255.255.255 A,B1",
+				"1.0.0.0-1.0.0.255 A,B2",
+				"1.0.1.0-255.255.255.255 A,B3",
+			},
+		},
+		{
+			name: "shared start and end",
+			sources: []*testReader{
+				source(false,
+					testRange{"0.0.0.0", "1.0.0.255", []string{"A1"}},
+					testRange{"1.0.1.0", "255.255.255.255", []string{"A2"}}),
+				source(false,
+					testRange{"0.0.0.0", "1.0.0.127", []string{"B1"}},
+					testRange{"1.0.0.128", "1.0.0.255", []string{"B2"}},
+					testRange{"1.0.1.0", "1.0.1.255", []string{"B3"}},
+					testRange{"1.0.2.0", "255.255.255.255", []string{"B4"}}),
+			},
+			expected: []string{
+				"0.0.0.0-1.0.0.127 A1,B1",
+				"1.0.0.128-1.0.0.255 A1,B2",
+				"1.0.1.0-1.0.1.255 A2,B3",
+				"1.0.2.0-255.255.255.255 A2,B4",
+			},
+		},
+		{
+			name: "IPv4 and IPv6 length",
+			sources: []*testReader{
+				source(true,
+					testRange{"0.0.0.0", "1.0.0.255", []string{"A1"}},
+					testRange{"1.0.1.0", "255.255.255.255", []string{"A2"}}),
+				source(false,
+					testRange{"0.0.0.0", "1.0.0.63", []string{"B1"}},
+					testRange{"1.0.0.64", "255.255.255.255", []string{"B2"}}),
+			},
+			expected: []string{
+				"0.0.0.0-1.0.0.63 A1,B1",
+				"1.0.0.64-1.0.0.255 A1,B2",
+				"1.0.1.0-255.255.255.255 A2,B2",
+			},
+		},
+		{
+			name: "gaps",
+			sources: []*testReader{
+				source(false,
+					testRange{"0.0.0.0", "1.255.255.255", []string{"A1"}},
+					testRange{"2.0.0.0", "2.0.0.255", nil},
+					testRange{"2.0.1.0", "255.255.255.255", []string{"A2"}}),
+				source(false,
+					testRange{"0.0.0.0", "0.255.255.255", nil},
+					testRange{"1.0.0.0", "1.0.0.255", []string{"B"}},
+					testRange{"1.0.1.0", "255.255.255.255", nil}),
+			},
+			expected: []string{
+				"0.0.0.0-0.255.255.255 A1,",
+				"1.0.0.0-1.0.0.255 A1,B",
+				"1.0.1.0-1.255.255.255 A1,",
+				"2.0.1.0-255.255.255.255 A2,",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast := assert.New(t)
+			readers := make([]format.Reader, 0, len(tt.sources))
+			for _, r := range tt.sources {
+				readers = append(readers, NewStandardReader(r, nil))
+			}
+			reader, err := NewHybridReader(nil, readers...)
+			ast.Nil(err)
+			ast.Nil(reader.SetOption(HybridReaderOption{Mode: HybridComparisonMode}))
+			w := &rangeWriter{}
+			ast.Nil(NewStandardDumper(reader, w).Dump(1))
+			ast.Equal(tt.expected, w.ranges)
+		})
+	}
+
+	// a reader returning a range without the IP is an error
+	d := newTestReader([]string{"country"}, testRange{"0.0.0.0", "255.255.255.255", []string{"D"}})
+	reader, err = NewHybridReader(nil, NewStandardReader(a, nil), &shiftedReader{d})
+	ast.Nil(err)
+	ast.Nil(reader.SetOption(HybridReaderOption{}))
+	_, err = reader.Find(net.ParseIP("1.1.1.1"))
+	ast.ErrorIs(err, errors.ErrInvalidIPRange)
+}
+
+// shiftedReader returns results with a range that does not contain the IP.
+type shiftedReader struct {
+	*testReader
+}
+
+func (r *shiftedReader) Find(ip net.IP) (*model.IPInfo, error) {
+	info, err := r.testReader.Find(ip)
+	if err != nil {
+		return nil, err
+	}
+	start := ipnet.NextIP(ip.To16())
+	info.IPNet = &ipnet.Range{Start: start, End: start}
+	return info, nil
+}
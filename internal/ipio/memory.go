@@ -0,0 +1,539 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+const (
+	// MemoryDBFormat is the format of the memory snapshot written by MemoryWriter.
+	MemoryDBFormat = "ipsm"
+
+	// MemoryDBExt is the file extension of the memory snapshot.
+	MemoryDBExt = ".ipsm"
+
+	// memoryMagic is the header of the memory snapshot.
+	memoryMagic = "IPSM"
+
+	// memoryVersion is the version of the memory snapshot layout.
+	memoryVersion = 1
+
+	// memoryGap marks a range without data.
+	memoryGap = math.MaxUint32
+)
+
+func init() {
+	format.RegisterReaderFormat(MemoryDBFormat, func(file string) (format.Reader, error) { return LoadMemoryReader(file) })
+	format.RegisterReaderExt(MemoryDBExt, func(file string) (format.Reader, error) { return LoadMemoryReader(file) })
+	format.RegisterWriterFormat(MemoryDBFormat, func(meta *model.Meta) (format.Writer, error) { return NewMemoryWriter(meta) })
+	format.RegisterWriterExt(MemoryDBExt, func(meta *model.Meta) (format.Writer, error) { return NewMemoryWriter(meta) })
+	format.RegisterDescriptor(format.Descriptor{Name: MemoryDBFormat, Exts: []string{MemoryDBExt}})
+}
+
+// MemoryReader is a Reader that keeps the whole IP database in memory as a flat index.
+// The start IPs of the ranges are kept in sorted arrays and looked up by binary search,
+// and the values of each range point to an interned value tuple, one value for each field of the meta.
+// It is built from any Reader, including the output of its operate chain, so lookups no longer
+// depend on the speed of the original format.
+type MemoryReader struct {
+	meta *model.Meta
+
+	hi, lo []uint64   // start IP of each range, the end is the start of the next range
+	index  []uint32   // value tuple of each range, memoryGap for no data
+	values [][]string // interned value tuples
+}
+
+// NewMemoryReader reads all ranges of the reader into a new MemoryReader.
+// readerJobs is passed to StandardDumper when the reader cannot enumerate ranges natively.
+func NewMemoryReader(r format.Reader, readerJobs int) (*MemoryReader, error) {
+	return NewMemoryReaderContext(context.Background(), r, readerJobs)
+}
+
+// NewMemoryReaderContext is like NewMemoryReader but stops with ctx.Err() once ctx is done.
+func NewMemoryReaderContext(ctx context.Context, r format.Reader, readerJobs int) (*MemoryReader, error) {
+	w, err := NewMemoryWriter(r.Meta())
+	if err != nil {
+		return nil, err
+	}
+	if err := NewStandardDumper(r, w).DumpContext(ctx, readerJobs); err != nil {
+		return nil, err
+	}
+	return w.Reader()
+}
+
+// LoadMemoryReader loads a MemoryReader from a snapshot file written by MemoryWriter.
+func LoadMemoryReader(file string) (*MemoryReader, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMemoryReader(data)
+}
+
+// Meta returns the meta-information of the IP database.
+func (m *MemoryReader) Meta() *model.Meta {
+	return m.meta
+}
+
+// Find retrieves IP information based on the given IP address.
+func (m *MemoryReader) Find(ip net.IP) (*model.IPInfo, error) {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return nil, errors.ErrInvalidIP
+	}
+	hi, lo := binary.BigEndian.Uint64(ip16[:8]), binary.BigEndian.Uint64(ip16[8:])
+
+	i := sort.Search(len(m.hi), func(i int) bool {
+		return m.hi[i] > hi || (m.hi[i] == hi && m.lo[i] > lo)
+	}) - 1
+	if i < 0 || m.index[i] == memoryGap {
+		return nil, errors.NewNotFoundError(ip, m.rangeAt(i))
+	}
+
+	info := m.info(i)
+	info.IP = ip
+	return info, nil
+}
+
+// Ranges returns an iterator over the ranges overlapping [start, end] in ascending order.
+func (m *MemoryReader) Ranges(ctx context.Context, start, end net.IP) model.IPInfoSeq {
+	return func(yield func(*model.IPInfo, error) bool) {
+		start16, end16 := start.To16(), end.To16()
+		if start16 == nil || end16 == nil {
+			yield(nil, errors.ErrInvalidIPRange)
+			return
+		}
+		hi, lo := binary.BigEndian.Uint64(start16[:8]), binary.BigEndian.Uint64(start16[8:])
+		i := sort.Search(len(m.hi), func(i int) bool {
+			return m.hi[i] > hi || (m.hi[i] == hi && m.lo[i] > lo)
+		}) - 1
+		if i < 0 {
+			i = 0
+		}
+
+		for ; i < len(m.hi); i++ {
+			if ctx.Err() != nil {
+				yield(nil, ctx.Err())
+				return
+			}
+			if bytes.Compare(ipnet.Uint64ToIP2(m.hi[i], m.lo[i]), end16) > 0 {
+				return
+			}
+			if m.index[i] == memoryGap {
+				continue
+			}
+			info := m.info(i)
+			info.IP = info.IPNet.Start
+			if !yield(info, nil) {
+				return
+			}
+		}
+	}
+}
+
+// FindBatch retrieves IP information for a batch of IP addresses, in the same order.
+func (m *MemoryReader) FindBatch(ips []net.IP) ([]*model.IPInfo, error) {
+	return m.FindBatchContext(context.Background(), ips)
+}
+
+// FindContext is like Find but returns ctx.Err() if ctx is done.
+func (m *MemoryReader) FindContext(ctx context.Context, ip net.IP) (*model.IPInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Find(ip)
+}
+
+// FindBatchContext is like FindBatch but honors ctx.
+func (m *MemoryReader) FindBatchContext(ctx context.Context, ips []net.IP) ([]*model.IPInfo, error) {
+	return findBatch(ctx, m.FindContext, ips)
+}
+
+// SetOption configures the MemoryReader with the provided option. It has no options.
+func (m *MemoryReader) SetOption(option interface{}) error {
+	return errors.ErrUnsupportedOption
+}
+
+// Close releases the memory held by the MemoryReader.
+func (m *MemoryReader) Close() error {
+	m.hi, m.lo, m.index, m.values = nil, nil, nil, nil
+	return nil
+}
+
+// Len returns the number of ranges with data.
+func (m *MemoryReader) Len() int {
+	n := 0
+	for _, index := range m.index {
+		if index != memoryGap {
+			n++
+		}
+	}
+	return n
+}
+
+// info returns the IP information of the range at index i.
+func (m *MemoryReader) info(i int) *model.IPInfo {
+	values := m.values[m.index[i]]
+	data := make(map[string]string, len(values))
+	for j, field := range m.meta.Fields {
+		data[field] = values[j]
+	}
+
+	return &model.IPInfo{
+		IPNet:      m.rangeAt(i),
+		Data:       data,
+		FieldAlias: m.meta.FieldAlias,
+		Fields:     m.meta.Fields,
+	}
+}
+
+// rangeAt returns the range at index i, -1 is the space before the first range.
+func (m *MemoryReader) rangeAt(i int) *ipnet.Range {
+	start := make(net.IP, net.IPv6len)
+	if i >= 0 {
+		start = ipnet.Uint64ToIP2(m.hi[i], m.lo[i])
+	}
+	end := ipnet.LastIPv6
+	if i+1 < len(m.hi) {
+		end = ipnet.PrevIP(ipnet.Uint64ToIP2(m.hi[i+1], m.lo[i+1]))
+	}
+
+	if start.To4() != nil && end.To4() != nil {
+		start, end = start.To4(), end.To4()
+	}
+	return &ipnet.Range{Start: start, End: end}
+}
+
+// WriteTo writes the MemoryReader as a snapshot to w.
+//
+// Snapshot layout, integers are big endian:
+//
+//	magic "IPSM" | version uint16
+//	meta length uint32 | meta JSON
+//	string count uint32 | (length uvarint | bytes) ...
+//	tuple count uint32 | (string index uvarint * len(meta.Fields)) ...
+//	range count uint32 | (start hi uint64 | start lo uint64 | tuple index uint32) ...
+func (m *MemoryReader) WriteTo(w io.Writer) (int64, error) {
+	meta, err := json.Marshal(m.meta)
+	if err != nil {
+		return 0, err
+	}
+
+	strs := make([]string, 0)
+	strIndex := make(map[string]uint64)
+	for _, values := range m.values {
+		for _, v := range values {
+			if _, ok := strIndex[v]; !ok {
+				strIndex[v] = uint64(len(strs))
+				strs = append(strs, v)
+			}
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	var tmp [binary.MaxVarintLen64]byte
+	putUint32 := func(v uint32) {
+		binary.BigEndian.PutUint32(tmp[:4], v)
+		buf.Write(tmp[:4])
+	}
+	putUint64 := func(v uint64) {
+		binary.BigEndian.PutUint64(tmp[:8], v)
+		buf.Write(tmp[:8])
+	}
+	putUvarint := func(v uint64) {
+		buf.Write(tmp[:binary.PutUvarint(tmp[:], v)])
+	}
+
+	buf.WriteString(memoryMagic)
+	binary.BigEndian.PutUint16(tmp[:2], memoryVersion)
+	buf.Write(tmp[:2])
+	putUint32(uint32(len(meta)))
+	buf.Write(meta)
+
+	putUint32(uint32(len(strs)))
+	for _, s := range strs {
+		putUvarint(uint64(len(s)))
+		buf.WriteString(s)
+	}
+
+	putUint32(uint32(len(m.values)))
+	for _, values := range m.values {
+		for _, v := range values {
+			putUvarint(strIndex[v])
+		}
+	}
+
+	putUint32(uint32(len(m.hi)))
+	for i := range m.hi {
+		putUint64(m.hi[i])
+		putUint64(m.lo[i])
+		putUint32(m.index[i])
+	}
+
+	return buf.WriteTo(w)
+}
+
+// ParseMemoryReader parses a snapshot written by MemoryReader.WriteTo.
+func ParseMemoryReader(data []byte) (*MemoryReader, error) {
+	p := &memoryParser{data: data}
+	if string(p.next(len(memoryMagic))) != memoryMagic {
+		return nil, errors.ErrInvalidDatabase
+	}
+	if v := p.next(2); v == nil || binary.BigEndian.Uint16(v) != memoryVersion {
+		return nil, errors.ErrInvalidDatabase
+	}
+
+	meta := &model.Meta{}
+	if err := json.Unmarshal(p.next(int(p.uint32())), meta); err != nil {
+		return nil, errors.ErrInvalidDatabase
+	}
+
+	strs := make([]string, p.uint32())
+	for i := range strs {
+		strs[i] = string(p.next(int(p.uvarint())))
+	}
+
+	values := make([][]string, p.uint32())
+	for i := range values {
+		values[i] = make([]string, len(meta.Fields))
+		for j := range values[i] {
+			index := p.uvarint()
+			if index >= uint64(len(strs)) {
+				return nil, errors.ErrInvalidDatabase
+			}
+			values[i][j] = strs[index]
+		}
+	}
+
+	n := p.uint32()
+	m := &MemoryReader{
+		meta:   meta,
+		hi:     make([]uint64, n),
+		lo:     make([]uint64, n),
+		index:  make([]uint32, n),
+		values: values,
+	}
+	for i := range m.hi {
+		m.hi[i], m.lo[i], m.index[i] = p.uint64(), p.uint64(), p.uint32()
+		if m.index[i] != memoryGap && int(m.index[i]) >= len(values) {
+			return nil, errors.ErrInvalidDatabase
+		}
+	}
+	if p.err {
+		return nil, errors.ErrInvalidDatabase
+	}
+
+	return m, nil
+}
+
+// memoryParser reads the fields of a snapshot, err is set once the data is exhausted.
+type memoryParser struct {
+	data []byte
+	err  bool
+}
+
+func (p *memoryParser) next(n int) []byte {
+	if p.err || n < 0 || n > len(p.data) {
+		p.err = true
+		return nil
+	}
+	ret := p.data[:n]
+	p.data = p.data[n:]
+	return ret
+}
+
+func (p *memoryParser) uint32() uint32 {
+	if b := p.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (p *memoryParser) uint64() uint64 {
+	if b := p.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (p *memoryParser) uvarint() uint64 {
+	if p.err {
+		return 0
+	}
+	v, n := binary.Uvarint(p.data)
+	if n <= 0 {
+		p.err = true
+		return 0
+	}
+	p.data = p.data[n:]
+	return v
+}
+
+// MemoryWriter collects IP information into a MemoryReader.
+// As a format.Writer it writes the snapshot of the collected data, so any database can be packed into a snapshot.
+type MemoryWriter struct {
+	meta *model.Meta
+
+	ranges []memoryRange
+	tuples map[string]uint32
+	values [][]string
+	strs   map[string]string
+}
+
+// memoryRange is a collected range with its IPs normalized to 16 bytes.
+type memoryRange struct {
+	start, end net.IP
+	index      uint32
+}
+
+// NewMemoryWriter initializes and returns a new MemoryWriter.
+func NewMemoryWriter(meta *model.Meta) (*MemoryWriter, error) {
+	if meta == nil {
+		return nil, errors.ErrMetaMissing
+	}
+	if len(meta.Fields) == 0 {
+		return nil, errors.ErrMetaFieldsUndefined
+	}
+
+	fields := make([]string, len(meta.Fields))
+	copy(fields, meta.Fields)
+	fieldAlias := make(map[string]string, len(meta.FieldAlias))
+	for k, v := range meta.FieldAlias {
+		fieldAlias[k] = v
+	}
+
+	return &MemoryWriter{
+		meta: &model.Meta{
+			MetaVersion: model.MetaVersion,
+			Format:      MemoryDBFormat,
+			IPVersion:   meta.IPVersion,
+			Fields:      fields,
+			FieldAlias:  fieldAlias,
+		},
+		tuples: make(map[string]uint32),
+		strs:   make(map[string]string),
+	}, nil
+}
+
+// SetOption configures the MemoryWriter with the provided option. It has no options.
+func (w *MemoryWriter) SetOption(option interface{}) error {
+	return errors.ErrUnsupportedOption
+}
+
+// Insert adds the IP information to the MemoryWriter. The ranges may be inserted in any order.
+func (w *MemoryWriter) Insert(info *model.IPInfo) error {
+	if info.IPNet == nil {
+		return errors.ErrInvalidIPRange
+	}
+	start, end := info.IPNet.Start.To16(), info.IPNet.End.To16()
+	if start == nil || end == nil || bytes.Compare(start, end) > 0 {
+		return errors.ErrInvalidIPRange
+	}
+
+	values := info.Values()
+	if len(values) != len(w.meta.Fields) {
+		return errors.ErrMismatchedFieldsLength
+	}
+
+	key := strings.Join(values, "\x00")
+	index, ok := w.tuples[key]
+	if !ok {
+		tuple := make([]string, len(values))
+		for i, v := range values {
+			if s, ok := w.strs[v]; ok {
+				v = s
+			} else {
+				w.strs[v] = v
+			}
+			tuple[i] = v
+		}
+		index = uint32(len(w.values))
+		w.tuples[key] = index
+		w.values = append(w.values, tuple)
+	}
+
+	w.ranges = append(w.ranges, memoryRange{start: start, end: end, index: index})
+	return nil
+}
+
+// Reader builds a MemoryReader from the inserted ranges.
+// Adjacent ranges with the same values are merged, and the address space without data is marked as gaps.
+func (w *MemoryWriter) Reader() (*MemoryReader, error) {
+	sort.Slice(w.ranges, func(i, j int) bool {
+		return bytes.Compare(w.ranges[i].start, w.ranges[j].start) < 0
+	})
+
+	m := &MemoryReader{
+		meta:   w.meta,
+		values: w.values,
+	}
+	add := func(ip net.IP, index uint32) {
+		if n := len(m.index); n > 0 && m.index[n-1] == index {
+			return
+		}
+		m.hi = append(m.hi, binary.BigEndian.Uint64(ip[:8]))
+		m.lo = append(m.lo, binary.BigEndian.Uint64(ip[8:]))
+		m.index = append(m.index, index)
+	}
+
+	next := make(net.IP, net.IPv6len)
+	for i, r := range w.ranges {
+		if i > 0 && bytes.Compare(r.start, next) < 0 {
+			return nil, errors.ErrCIDROverlap
+		}
+		if !r.start.Equal(next) {
+			add(next, memoryGap)
+		}
+		add(r.start, r.index)
+		next = ipnet.NextIP(r.end)
+		if r.end.Equal(ipnet.LastIPv6) {
+			return m, nil
+		}
+	}
+	add(next, memoryGap)
+
+	return m, nil
+}
+
+// WriteTo writes the snapshot of the inserted ranges to w.
+func (w *MemoryWriter) WriteTo(iw io.Writer) (int64, error) {
+	m, err := w.Reader()
+	if err != nil {
+		return 0, err
+	}
+	return m.WriteTo(iw)
+}
+
+// WriterFormat returns the format of the writer.
+func (w *MemoryWriter) WriterFormat() string {
+	return MemoryDBFormat
+}
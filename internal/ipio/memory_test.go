@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+func TestMemoryReader(t *testing.T) {
+	ast := assert.New(t)
+
+	writer, err := NewMemoryWriter(&model.Meta{
+		IPVersion:  model.IPv4,
+		Fields:     []string{"country", "isp"},
+		FieldAlias: map[string]string{"country": "country"},
+	})
+	ast.Nil(err)
+	for _, r := range []testRange{
+		{"1.0.2.0", "1.0.2.255", []string{"B", "Y"}},
+		{"1.0.0.0", "1.0.0.255", []string{"A", "X"}},
+		{"1.0.1.0", "1.0.1.255", []string{"A", "X"}},
+		{"2.0.0.0", "255.255.255.255", []string{"C", "X"}},
+	} {
+		ast.Nil(writer.Insert(&model.IPInfo{
+			IPNet:  &ipnet.Range{Start: net.ParseIP(r.start), End: net.ParseIP(r.end)},
+			Data:   map[string]string{"country": r.values[0], "isp": r.values[1]},
+			Fields: []string{"country", "isp"},
+		}))
+	}
+	reader, err := writer.Reader()
+	ast.Nil(err)
+	ast.Equal(MemoryDBFormat, reader.Meta().Format)
+	ast.Equal(3, reader.Len())
+
+	buf := &bytes.Buffer{}
+	_, err = reader.WriteTo(buf)
+	ast.Nil(err)
+	loaded, err := ParseMemoryReader(buf.Bytes())
+	ast.Nil(err)
+	ast.Equal(reader.Meta(), loaded.Meta())
+
+	for _, r := range []*MemoryReader{reader, loaded} {
+		info, err := r.Find(net.ParseIP("1.0.1.1"))
+		ast.Nil(err)
+		ast.Equal("1.0.0.0", info.IPNet.Start.String())
+		ast.Equal("1.0.1.255", info.IPNet.End.String())
+		ast.Equal([]string{"A", "X"}, info.Values())
+
+		info, err = r.Find(net.ParseIP("255.255.255.255").To4())
+		ast.Nil(err)
+		ast.Equal([]string{"C", "X"}, info.Values())
+
+		for _, ip := range []string{"0.0.0.1", "1.0.3.0", "::1", "2001::1"} {
+			_, err = r.Find(net.ParseIP(ip))
+			ast.True(errors.Is(err, errors.ErrNotFound), ip)
+		}
+		_, err = r.Find(net.ParseIP("1.0.3.0"))
+		notFound, ok := errors.AsNotFound(err)
+		ast.True(ok)
+		ast.Equal("1.0.3.0", notFound.IPNet.Start.String())
+		ast.Equal("1.255.255.255", notFound.IPNet.End.String())
+
+		var starts []string
+		r.Ranges(context.Background(), net.ParseIP("1.0.1.0"), net.ParseIP("8.8.8.8"))(func(info *model.IPInfo, err error) bool {
+			ast.Nil(err)
+			starts = append(starts, info.IPNet.Start.String())
+			return true
+		})
+		ast.Equal([]string{"1.0.0.0", "1.0.2.0", "2.0.0.0"}, starts)
+	}
+
+	// rebuild from a reader
+	rebuilt, err := NewMemoryReader(loaded, 1)
+	ast.Nil(err)
+	ast.Equal(loaded.hi, rebuilt.hi)
+	ast.Equal(loaded.lo, rebuilt.lo)
+	ast.Equal(loaded.Len(), rebuilt.Len())
+	for i := range loaded.index {
+		if loaded.index[i] != memoryGap {
+			ast.Equal(loaded.values[loaded.index[i]], rebuilt.values[rebuilt.index[i]])
+		}
+	}
+
+	// overlap
+	ast.Nil(writer.Insert(&model.IPInfo{
+		IPNet:  &ipnet.Range{Start: net.ParseIP("1.0.0.128"), End: net.ParseIP("1.0.0.200")},
+		Data:   map[string]string{},
+		Fields: []string{"country", "isp"},
+	}))
+	_, err = writer.Reader()
+	ast.Equal(errors.ErrCIDROverlap, err)
+
+	_, err = ParseMemoryReader(buf.Bytes()[:buf.Len()-1])
+	ast.Equal(errors.ErrInvalidDatabase, err)
+}
@@ -0,0 +1,310 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// MergeReader is a Reader that combines readers covering different address families or disjoint ranges,
+// e.g. an IPv4 and an IPv6 database, into one dual-stack database.
+// The fields of the readers are unified by their common field names, fields a reader does not have are empty in its ranges.
+// A reader only supporting IPv6 is not consulted in the IPv4 address space.
+type MergeReader struct {
+	readers []format.Reader
+	spaces  [][]*ipnet.Range    // the address spaces of each reader
+	names   []map[string]string // the merged fields of the fields of each reader
+	meta    *model.Meta
+}
+
+// NewMergeReader initializes a MergeReader combining the readers.
+func NewMergeReader(readers ...format.Reader) (*MergeReader, error) {
+	if len(readers) == 0 {
+		return nil, errors.ErrNoDatabaseReaders
+	}
+
+	r := &MergeReader{
+		readers: readers,
+		spaces:  make([][]*ipnet.Range, 0, len(readers)),
+		names:   make([]map[string]string, 0, len(readers)),
+		meta: &model.Meta{
+			MetaVersion: model.MetaVersion,
+			Format:      readers[0].Meta().Format,
+			Fields:      make([]string, 0),
+			FieldAlias:  make(map[string]string),
+		},
+	}
+
+	for _, reader := range readers {
+		meta := reader.Meta()
+		r.meta.IPVersion |= meta.IPVersion
+		r.meta.MergeInfo(meta)
+		r.spaces = append(r.spaces, familySpaces(meta))
+
+		common := model.ReverseFieldAlias(meta.FieldAlias)
+		names := make(map[string]string, len(meta.Fields))
+		for _, field := range meta.Fields {
+			name := field
+			if commonField, ok := common[field]; ok {
+				name = commonField
+			}
+			names[field] = name
+			if !slices.Contains(r.meta.Fields, name) {
+				r.meta.Fields = append(r.meta.Fields, name)
+			}
+		}
+		r.names = append(r.names, names)
+	}
+
+	return r, nil
+}
+
+// familySpaces returns the address spaces of the IP versions a database supports, in ascending order.
+// The IPv4 space is excluded for databases only supporting IPv6.
+func familySpaces(meta *model.Meta) []*ipnet.Range {
+	ipv4 := &ipnet.Range{Start: ipnet.FirstIPv4.To16(), End: ipnet.LastIPv4.To16()}
+	switch {
+	case meta.IsIPv4Support() && meta.IsIPv6Support():
+		return []*ipnet.Range{{Start: make(net.IP, net.IPv6len), End: ipnet.LastIPv6}}
+	case meta.IsIPv6Support():
+		return []*ipnet.Range{
+			{Start: make(net.IP, net.IPv6len), End: ipnet.PrevIP(ipv4.Start)},
+			{Start: ipnet.NextIP(ipv4.End), End: ipnet.LastIPv6},
+		}
+	case meta.IsIPv4Support():
+		return []*ipnet.Range{ipv4}
+	}
+	return nil
+}
+
+// spaceOf returns the address space of spaces containing ip,
+// or the range between the address spaces containing ip and false.
+func spaceOf(spaces []*ipnet.Range, ip net.IP) (*ipnet.Range, bool) {
+	start := make(net.IP, net.IPv6len)
+	for _, space := range spaces {
+		if ipnet.IPLess(ip, space.Start) {
+			return &ipnet.Range{Start: start, End: ipnet.PrevIP(space.Start)}, false
+		}
+		if !ipnet.IPLess(space.End, ip) {
+			return space, true
+		}
+		start = ipnet.NextIP(space.End)
+	}
+	return &ipnet.Range{Start: start, End: ipnet.LastIPv6}, false
+}
+
+// Meta returns the combined meta-information of the readers, with the unified fields.
+func (r *MergeReader) Meta() *model.Meta {
+	return r.meta
+}
+
+// Find retrieves IP information based on the given IP address.
+func (r *MergeReader) Find(ip net.IP) (*model.IPInfo, error) {
+	return r.FindContext(context.Background(), ip)
+}
+
+// FindContext is like Find but honors ctx when the readers are queried.
+// The readers covering ip are queried in order, the result of the first reader with data is returned.
+func (r *MergeReader) FindContext(ctx context.Context, ip net.IP) (*model.IPInfo, error) {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return nil, errors.ErrInvalidIP
+	}
+
+	// ipr is narrowed to the range no reader queried so far has data for
+	ipr := &ipnet.Range{Start: make(net.IP, net.IPv6len), End: ipnet.LastIPv6}
+	for i, reader := range r.readers {
+		space, ok := spaceOf(r.spaces[i], ip16)
+		ipr.CommonRange(ip16, space)
+		if !ok {
+			continue
+		}
+
+		info, err := FindContext(ctx, reader, ip)
+		if notFound, isNotFound := errors.AsNotFound(err); isNotFound {
+			if notFound.IPNet == nil {
+				ipr = &ipnet.Range{Start: ip16, End: ip16}
+			} else {
+				ipr.CommonRange(ip16, notFound.IPNet)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		ret := r.convert(i, info, ip)
+		ret.IPNet = &ipnet.Range{Start: info.IPNet.Start, End: info.IPNet.End}
+		ret.IPNet.CommonRange(ip16, ipr)
+		return ret, nil
+	}
+
+	return nil, errors.NewNotFoundError(ip, ipr)
+}
+
+// Ranges returns an iterator over the ranges of all readers overlapping [start, end] in ascending order.
+// It fails with ErrOverlappingDatabases if ranges of the readers overlap.
+func (r *MergeReader) Ranges(ctx context.Context, start, end net.IP) model.IPInfoSeq {
+	return func(yield func(*model.IPInfo, error) bool) {
+		start16, end16 := start.To16(), end.To16()
+		if start16 == nil || end16 == nil {
+			yield(nil, errors.ErrInvalidIPRange)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		nexts := make([]func() (*model.IPInfo, error), len(r.readers))
+		heads := make([]*model.IPInfo, len(r.readers))
+		for i := range r.readers {
+			nexts[i] = pull(ctx, r.ranges(ctx, i, start16, end16))
+			var err error
+			if heads[i], err = nexts[i](); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+
+		var last *model.IPInfo
+		lastIndex := 0
+		for {
+			// the reader whose next range starts first
+			index := -1
+			for i, head := range heads {
+				if head != nil && (index < 0 || ipnet.IPLess(head.IPNet.Start, heads[index].IPNet.Start)) {
+					index = i
+				}
+			}
+			if index < 0 {
+				return
+			}
+
+			info := heads[index]
+			if last != nil && !ipnet.IPLess(last.IPNet.End, info.IPNet.Start) {
+				yield(nil, fmt.Errorf("%w: %s - %s of database %d and %s - %s of database %d", errors.ErrOverlappingDatabases,
+					last.IPNet.Start, last.IPNet.End, lastIndex, info.IPNet.Start, info.IPNet.End, index))
+				return
+			}
+			ret := r.convert(index, info, info.IP)
+			ret.IPNet = info.IPNet
+			if !yield(ret, nil) {
+				return
+			}
+			last, lastIndex = info, index
+
+			var err error
+			if heads[index], err = nexts[index](); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
+
+// ranges returns an iterator over the ranges of a reader overlapping [start, end] within its address spaces,
+// cut at the boundaries of the address spaces.
+func (r *MergeReader) ranges(ctx context.Context, index int, start, end net.IP) model.IPInfoSeq {
+	return func(yield func(*model.IPInfo, error) bool) {
+		for _, space := range r.spaces[index] {
+			s, e := space.Start, space.End
+			if ipnet.IPLess(s, start) {
+				s = start
+			}
+			if ipnet.IPLess(end, e) {
+				e = end
+			}
+			if ipnet.IPLess(e, s) {
+				continue
+			}
+
+			next := true
+			Ranges(ctx, r.readers[index], s, e)(func(info *model.IPInfo, err error) bool {
+				if err != nil {
+					next = false
+					return yield(nil, err)
+				}
+				ipr := &ipnet.Range{Start: info.IPNet.Start.To16(), End: info.IPNet.End.To16()}
+				if ipnet.IPLess(ipr.End, s) {
+					return true
+				}
+				if ipnet.IPLess(e, ipr.Start) {
+					return false
+				}
+				if ipnet.IPLess(ipr.Start, s) {
+					ipr.Start = s
+				}
+				if ipnet.IPLess(e, ipr.End) {
+					ipr.End = e
+				}
+				ret := *info
+				ret.IPNet = ipr
+				next = yield(&ret, nil)
+				return next
+			})
+			if !next {
+				return
+			}
+		}
+	}
+}
+
+// SetOption is not supported, the readers are configured before they are merged.
+func (r *MergeReader) SetOption(_ interface{}) error {
+	return errors.ErrUnsupportedOption
+}
+
+// Close closes all readers and returns the first error.
+func (r *MergeReader) Close() error {
+	var ret error
+	for _, reader := range r.readers {
+		if err := reader.Close(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+// convert returns the IP information of a reader for ip with the merged fields.
+func (r *MergeReader) convert(index int, info *model.IPInfo, ip net.IP) *model.IPInfo {
+	ret := &model.IPInfo{
+		IP:         ip,
+		Fields:     slices.Clone(r.meta.Fields),
+		Data:       make(map[string]string, len(r.meta.Fields)),
+		FieldAlias: r.meta.FieldAlias,
+		Sources:    info.Sources,
+	}
+	for _, field := range r.meta.Fields {
+		ret.Data[field] = ""
+	}
+
+	values := info.TypedValues()
+	for i, field := range info.Fields {
+		if name, ok := r.names[index][field]; ok {
+			ret.SetValue(name, values[i])
+		}
+	}
+	return ret
+}
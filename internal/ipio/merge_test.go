@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+func TestMergeReader(t *testing.T) {
+	ast := assert.New(t)
+
+	ipv4 := newTestReader([]string{"country", "area"},
+		testRange{"0.0.0.0", "1.255.255.255", []string{"CN", "a"}},
+		testRange{"2.0.0.0", "255.255.255.255", nil})
+	ipv4.meta.FieldAlias = map[string]string{"isp": "area"}
+	ipv6 := newTestReader([]string{"country", "city", "isp"},
+		testRange{"::", "1fff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", nil},
+		testRange{"2000::", "2000::ffff", []string{"US", "b", "c"}},
+		testRange{"2000::1:0", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", nil})
+	ipv6.meta.IPVersion = model.IPv6
+
+	r, err := NewMergeReader(ipv4, ipv6)
+	ast.Nil(err)
+	ast.Equal([]string{"country", "isp", "city"}, r.Meta().Fields)
+	ast.Equal(model.IPv4|model.IPv6, r.Meta().IPVersion)
+
+	info, err := r.Find(net.ParseIP("1.0.0.1"))
+	ast.Nil(err)
+	ast.Equal("1.0.0.1 0.0.0.0-1.255.255.255 [CN a ]", fmt.Sprintf("%s %s-%s %v", info.IP, info.IPNet.Start, info.IPNet.End, info.Values()))
+
+	// the ranges of a reader returning IPv4 length ranges keep their length
+	ipv4.ipv4 = true
+	info, err = r.Find(net.ParseIP("1.0.0.1"))
+	ast.Nil(err)
+	ast.Equal("0.0.0.0-1.255.255.255", fmt.Sprintf("%s-%s", info.IPNet.Start, info.IPNet.End))
+	ast.Len(info.IPNet.Start, net.IPv4len)
+	ast.Len(info.IPNet.End, net.IPv4len)
+	ipv4.ipv4 = false
+
+	info, err = r.Find(net.ParseIP("2000::1"))
+	ast.Nil(err)
+	ast.Equal("2000::-2000::ffff [US c b]", fmt.Sprintf("%s-%s %v", info.IPNet.Start, info.IPNet.End, info.Values()))
+
+	_, err = r.Find(net.ParseIP("3.0.0.1"))
+	notFound, ok := errors.AsNotFound(err)
+	ast.True(ok)
+	ast.Equal("2.0.0.0-255.255.255.255", fmt.Sprintf("%s-%s", notFound.IPNet.Start, notFound.IPNet.End))
+
+	var ranges []string
+	start, end := addressSpace(r.Meta())
+	r.Ranges(context.Background(), start, end)(func(info *model.IPInfo, err error) bool {
+		ast.Nil(err)
+		ranges = append(ranges, fmt.Sprintf("%s-%s %v", info.IPNet.Start, info.IPNet.End, info.Values()))
+		return true
+	})
+	ast.Equal([]string{
+		"0.0.0.0-1.255.255.255 [CN a ]",
+		"2000::-2000::ffff [US c b]",
+	}, ranges)
+
+	// overlapping databases
+	r, err = NewMergeReader(ipv4, newTestReader([]string{"country"},
+		testRange{"0.0.0.0", "1.0.0.255", nil},
+		testRange{"1.0.1.0", "1.0.1.255", []string{"JP"}},
+		testRange{"1.0.2.0", "255.255.255.255", nil}))
+	ast.Nil(err)
+	r.Ranges(context.Background(), start, end)(func(info *model.IPInfo, e error) bool {
+		err = e
+		return e == nil
+	})
+	ast.ErrorIs(err, errors.ErrOverlappingDatabases)
+}
@@ -0,0 +1,414 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/format/plain"
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// Overlay is a list of ranges with the values of fields, to be laid over a database with OverlayReader.
+type Overlay struct {
+	Fields []string
+	Ranges []*ValuedRange
+}
+
+// LoadOverlay loads an overlay from a file in plain format, see ParseOverlay.
+func LoadOverlay(file string) (*Overlay, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return ParseOverlay(f)
+}
+
+// ParseOverlay parses an overlay in plain format. The fields are taken from the meta line,
+// or the fields line for files written by hand, e.g.
+//
+//	# Fields: country,isp
+//	10.0.0.0/8	局域网,
+//	192.168.1.0-192.168.1.99	局域网,办公网
+//
+// A range is a CIDR, a start and an end IP separated by "-", or a single IP.
+func ParseOverlay(r io.Reader) (*Overlay, error) {
+	o := &Overlay{}
+	hasMeta := false
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		// values keep their spaces, a single empty value leaves a trailing tab
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		switch {
+		case len(strings.TrimSpace(text)) == 0:
+			continue
+		case strings.HasPrefix(text, plain.MetaPrefix):
+			meta := &model.Meta{}
+			if err := json.Unmarshal([]byte(text[len(plain.MetaPrefix):]), meta); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %s", errors.ErrInvalidFormat, line, err)
+			}
+			o.Fields, hasMeta = meta.Fields, true
+			continue
+		case strings.HasPrefix(text, plain.FieldsPrefix):
+			if !hasMeta {
+				o.Fields = strings.Split(strings.TrimSpace(text[len(plain.FieldsPrefix):]), plain.FieldSep)
+			}
+			continue
+		case strings.HasPrefix(text, "#"):
+			continue
+		}
+
+		if len(o.Fields) == 0 {
+			return nil, errors.ErrMetaMissing
+		}
+		split := strings.SplitN(text, "\t", 2)
+		ipr, err := parseOverlayRange(split[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %s", err, line, split[0])
+		}
+		var values []string
+		if len(split) == 2 {
+			values = strings.Split(split[1], plain.FieldSep)
+		}
+		if len(values) != len(o.Fields) {
+			return nil, fmt.Errorf("%w: line %d", errors.ErrMismatchedFieldsLength, line)
+		}
+		o.Ranges = append(o.Ranges, &ValuedRange{IPNet: ipr, Values: values})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(o.Fields) == 0 {
+		return nil, errors.ErrMetaMissing
+	}
+
+	return o, nil
+}
+
+// parseOverlayRange parses a CIDR, an IP range "start-end" or a single IP.
+func parseOverlayRange(s string) (*ipnet.Range, error) {
+	if _, ipNet, err := net.ParseCIDR(s); err == nil {
+		return ipnet.NewRange(ipNet), nil
+	}
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		end = start
+	}
+	ipr := &ipnet.Range{Start: net.ParseIP(strings.TrimSpace(start)), End: net.ParseIP(strings.TrimSpace(end))}
+	if ipr.Start == nil || ipr.End == nil || ipnet.IPLess(ipr.End.To16(), ipr.Start.To16()) {
+		return nil, errors.ErrInvalidIPRange
+	}
+	return ipr, nil
+}
+
+// Select returns the overlay of the given fields only.
+func (o *Overlay) Select(fields []string) (*Overlay, error) {
+	index := make([]int, len(fields))
+	for i, field := range fields {
+		if index[i] = slices.Index(o.Fields, field); index[i] < 0 {
+			return nil, fmt.Errorf("%w: %s", errors.ErrFieldInvalid, field)
+		}
+	}
+
+	ret := &Overlay{Fields: fields, Ranges: make([]*ValuedRange, len(o.Ranges))}
+	for i, r := range o.Ranges {
+		ret.Ranges[i] = &ValuedRange{IPNet: r.IPNet}
+		if r.Values == nil {
+			continue
+		}
+		ret.Ranges[i].Values = make([]string, len(fields))
+		for j := range fields {
+			ret.Ranges[i].Values[j] = r.Values[index[j]]
+		}
+	}
+	return ret, nil
+}
+
+// OverlayReader is a Reader that lays ranges with the values of some fields over another Reader.
+// Within an overlay range the values of its fields replace those of the underlying reader, the other fields are kept,
+// and the ranges of the underlying reader are split around it. An overlay range without values removes the data of its addresses.
+type OverlayReader struct {
+	Reader format.Reader
+
+	meta   *model.Meta
+	fields []string
+	keys   []string // the fields of the overlay in the data of the underlying reader
+	extra  []string // the fields of the overlay the underlying reader does not have
+	ranges []*ValuedRange
+}
+
+// NewOverlayReader initializes an OverlayReader laying ranges with the values of fields over reader.
+// Fields are looked up by the names of either the database or the common fields, ranges must not overlap.
+func NewOverlayReader(reader format.Reader, fields []string, ranges []*ValuedRange) (*OverlayReader, error) {
+	r := &OverlayReader{
+		Reader: reader,
+		fields: fields,
+		ranges: make([]*ValuedRange, 0, len(ranges)),
+	}
+
+	for _, vr := range ranges {
+		start, end := vr.IPNet.Start.To16(), vr.IPNet.End.To16()
+		if start == nil || end == nil || ipnet.IPLess(end, start) {
+			return nil, errors.ErrInvalidIPRange
+		}
+		if vr.Values != nil && len(vr.Values) != len(fields) {
+			return nil, errors.ErrMismatchedFieldsLength
+		}
+		r.ranges = append(r.ranges, &ValuedRange{IPNet: &ipnet.Range{Start: start, End: end}, Values: vr.Values})
+	}
+	sort.SliceStable(r.ranges, func(i, j int) bool {
+		return ipnet.IPLess(r.ranges[i].IPNet.Start, r.ranges[j].IPNet.Start)
+	})
+	for i := 1; i < len(r.ranges); i++ {
+		if !ipnet.IPLess(r.ranges[i-1].IPNet.End, r.ranges[i].IPNet.Start) {
+			return nil, errors.ErrCIDROverlap
+		}
+	}
+
+	meta := *reader.Meta()
+	meta.Fields = slices.Clone(meta.Fields)
+	for _, field := range fields {
+		key := field
+		if !slices.Contains(meta.Fields, field) {
+			if dbField, ok := meta.FieldAlias[field]; ok && slices.Contains(meta.Fields, dbField) {
+				key = dbField
+			} else {
+				r.extra = append(r.extra, field)
+				meta.Fields = append(meta.Fields, field)
+			}
+		}
+		r.keys = append(r.keys, key)
+	}
+	for _, vr := range r.ranges {
+		if vr.Values == nil {
+			continue
+		}
+		if vr.IPNet.Start.To4() != nil {
+			meta.IPVersion |= model.IPv4
+		}
+		if vr.IPNet.End.To4() == nil {
+			meta.IPVersion |= model.IPv6
+		}
+	}
+	r.meta = &meta
+
+	return r, nil
+}
+
+// Meta returns the meta-information of the underlying reader,
+// with the fields of the overlay it does not have and the IP versions of the overlay.
+func (r *OverlayReader) Meta() *model.Meta {
+	return r.meta
+}
+
+// Find retrieves IP information based on the given IP address.
+func (r *OverlayReader) Find(ip net.IP) (*model.IPInfo, error) {
+	return r.FindContext(context.Background(), ip)
+}
+
+// FindContext is like Find but honors ctx when the underlying reader is queried.
+// The range of the result is cut at the boundaries of the overlay ranges.
+func (r *OverlayReader) FindContext(ctx context.Context, ip net.IP) (*model.IPInfo, error) {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return nil, errors.ErrInvalidIP
+	}
+
+	var info *model.IPInfo
+	var err error
+	if ip16.To4() == nil && !r.Reader.Meta().IsIPv6Support() {
+		// the overlay extends an IPv4 database with IPv6 ranges
+		err = errors.NewNotFoundError(ip, &ipnet.Range{Start: ipnet.NextIP(ipnet.LastIPv4), End: ipnet.LastIPv6})
+		if ipnet.IPLess(ip16, ipnet.FirstIPv4) {
+			err = errors.NewNotFoundError(ip, &ipnet.Range{Start: make(net.IP, net.IPv6len), End: ipnet.PrevIP(ipnet.FirstIPv4)})
+		}
+	} else {
+		info, err = FindContext(ctx, r.Reader, ip)
+	}
+	notFound, isNotFound := errors.AsNotFound(err)
+	if err != nil && !isNotFound {
+		return nil, err
+	}
+	ipr := &ipnet.Range{Start: ip16, End: ip16}
+	switch {
+	case isNotFound && notFound.IPNet != nil:
+		ipr = &ipnet.Range{Start: notFound.IPNet.Start, End: notFound.IPNet.End}
+	case !isNotFound:
+		ipr = &ipnet.Range{Start: info.IPNet.Start, End: info.IPNet.End}
+	}
+
+	// the first overlay range ending at or after ip
+	i := sort.Search(len(r.ranges), func(i int) bool {
+		return !ipnet.IPLess(r.ranges[i].IPNet.End, ip16)
+	})
+	if i == len(r.ranges) || ipnet.IPLess(ip16, r.ranges[i].IPNet.Start) {
+		gap := &ipnet.Range{Start: make(net.IP, net.IPv6len), End: ipnet.LastIPv6}
+		if i > 0 {
+			gap.Start = ipnet.NextIP(r.ranges[i-1].IPNet.End)
+		}
+		if i < len(r.ranges) {
+			gap.End = ipnet.PrevIP(r.ranges[i].IPNet.Start)
+		}
+		ipr.CommonRange(ip16, gap)
+		if isNotFound {
+			return nil, errors.NewNotFoundError(ip, ipr)
+		}
+		info = r.copy(info, ip)
+		info.IPNet = ipr
+		return info, nil
+	}
+
+	vr := r.ranges[i]
+	ipr.CommonRange(ip16, vr.IPNet)
+	if vr.Values == nil {
+		return nil, errors.NewNotFoundError(ip, ipr)
+	}
+	if isNotFound {
+		info = nil
+	}
+	info = r.overlay(info, ip, vr.Values)
+	info.IPNet = ipr
+	return info, nil
+}
+
+// Ranges returns an iterator over the ranges overlapping [start, end] in ascending order,
+// the ranges of the underlying reader split at the boundaries of the overlay ranges.
+func (r *OverlayReader) Ranges(ctx context.Context, start, end net.IP) model.IPInfoSeq {
+	return func(yield func(*model.IPInfo, error) bool) {
+		start16, end16 := start.To16(), end.To16()
+		if start16 == nil || end16 == nil {
+			yield(nil, errors.ErrInvalidIPRange)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var infos []*model.IPInfo
+		values := make(map[*model.IPInfo][]string)
+		for _, vr := range r.ranges {
+			if ipnet.IPLess(vr.IPNet.End, start16) || ipnet.IPLess(end16, vr.IPNet.Start) {
+				continue
+			}
+			info := &model.IPInfo{IPNet: vr.IPNet}
+			infos = append(infos, info)
+			values[info] = vr.Values
+		}
+
+		// the underlying reader is only walked over its own address space
+		base := newSliceCursor(nil)
+		baseStart, baseEnd := addressSpace(r.Reader.Meta())
+		if ipnet.IPLess(baseStart, start16) {
+			baseStart = start16
+		}
+		if ipnet.IPLess(end16, baseEnd) {
+			baseEnd = end16
+		}
+		if !ipnet.IPLess(baseEnd, baseStart) {
+			base = newRangeCursor(ctx, r.Reader, baseStart, baseEnd)
+		}
+
+		alignCursors(base, newSliceCursor(infos), func(ar *AlignedRange, err error) bool {
+			if err != nil {
+				return yield(nil, err)
+			}
+			if ipnet.IPLess(ar.IPNet.End, start16) {
+				return true
+			}
+			if ipnet.IPLess(end16, ar.IPNet.Start) {
+				return false
+			}
+
+			var info *model.IPInfo
+			switch {
+			case ar.New == nil:
+				info = r.copy(ar.Old, ar.IPNet.Start)
+			case values[ar.New] == nil:
+				return true
+			default:
+				info = r.overlay(ar.Old, ar.IPNet.Start, values[ar.New])
+			}
+			info.IPNet = ar.IPNet
+			return yield(info, nil)
+		})
+	}
+}
+
+// SetOption passes the option to the underlying reader.
+func (r *OverlayReader) SetOption(option interface{}) error {
+	return r.Reader.SetOption(option)
+}
+
+// Close closes the underlying reader.
+func (r *OverlayReader) Close() error {
+	return r.Reader.Close()
+}
+
+// copy returns a copy of info for ip with the fields of the overlay the underlying reader does not have.
+func (r *OverlayReader) copy(info *model.IPInfo, ip net.IP) *model.IPInfo {
+	info = copyIPInfo(info, ip)
+	for _, field := range r.extra {
+		if !slices.Contains(info.Fields, field) {
+			info.Fields = append(slices.Clip(info.Fields), field)
+		}
+	}
+	return info
+}
+
+// overlay returns a copy of info for ip with the values of the overlay fields,
+// or a new IPInfo with only them if info is nil.
+func (r *OverlayReader) overlay(info *model.IPInfo, ip net.IP, values []string) *model.IPInfo {
+	if info == nil {
+		info = &model.IPInfo{
+			IP:         ip,
+			Fields:     slices.Clone(r.meta.Fields),
+			Data:       make(map[string]string, len(r.fields)),
+			FieldAlias: r.meta.FieldAlias,
+		}
+	} else {
+		info = r.copy(info, ip)
+	}
+
+	for i, key := range r.keys {
+		info.Data[key] = values[i]
+		delete(info.Typed, key)
+		if _, ok := info.ReplaceFields[key]; ok {
+			replaceFields := make(map[string]string, len(info.ReplaceFields))
+			for k, v := range info.ReplaceFields {
+				replaceFields[k] = v
+			}
+			delete(replaceFields, key)
+			info.ReplaceFields = replaceFields
+		}
+	}
+	return info
+}
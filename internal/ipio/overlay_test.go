@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+func TestOverlayReader(t *testing.T) {
+	ast := assert.New(t)
+
+	base := newTestReader([]string{"country", "isp"},
+		testRange{"0.0.0.0", "1.0.0.255", []string{"A", "a"}},
+		testRange{"1.0.1.0", "1.0.3.255", []string{"B", "b"}},
+		testRange{"1.0.4.0", "1.0.4.255", nil},
+		testRange{"1.0.5.0", "255.255.255.255", []string{"C", "c"}})
+	r, err := NewOverlayReader(base, []string{"isp", "asn"}, []*ValuedRange{
+		{IPNet: &ipnet.Range{Start: net.ParseIP("1.0.4.0"), End: net.ParseIP("1.0.4.127")}, Values: []string{"x", "1"}},
+		{IPNet: &ipnet.Range{Start: net.ParseIP("1.0.2.0"), End: net.ParseIP("1.0.2.255")}, Values: []string{"x", "1"}},
+		{IPNet: &ipnet.Range{Start: net.ParseIP("1.0.5.0"), End: net.ParseIP("1.0.5.255")}},
+	})
+	ast.Nil(err)
+	ast.Equal([]string{"country", "isp", "asn"}, r.Meta().Fields)
+
+	info, err := r.Find(net.ParseIP("1.0.1.1"))
+	ast.Nil(err)
+	ast.Equal("1.0.1.0-1.0.1.255 [B b ]", fmt.Sprintf("%s-%s %v", info.IPNet.Start, info.IPNet.End, info.Values()))
+
+	info, err = r.Find(net.ParseIP("1.0.2.1"))
+	ast.Nil(err)
+	ast.Equal("1.0.2.0-1.0.2.255 [B x 1]", fmt.Sprintf("%s-%s %v", info.IPNet.Start, info.IPNet.End, info.Values()))
+
+	info, err = r.Find(net.ParseIP("1.0.4.1"))
+	ast.Nil(err)
+	ast.Equal("1.0.4.0-1.0.4.127 [ x 1]", fmt.Sprintf("%s-%s %v", info.IPNet.Start, info.IPNet.End, info.Values()))
+
+	_, err = r.Find(net.ParseIP("1.0.4.200"))
+	notFound, ok := errors.AsNotFound(err)
+	ast.True(ok)
+	ast.Equal("1.0.4.128-1.0.4.255", fmt.Sprintf("%s-%s", notFound.IPNet.Start, notFound.IPNet.End))
+
+	_, err = r.Find(net.ParseIP("1.0.5.1"))
+	notFound, ok = errors.AsNotFound(err)
+	ast.True(ok)
+	ast.Equal("1.0.5.0-1.0.5.255", fmt.Sprintf("%s-%s", notFound.IPNet.Start, notFound.IPNet.End))
+
+	var ranges []string
+	r.Ranges(context.Background(), net.ParseIP("1.0.0.0"), net.ParseIP("1.0.6.255"))(func(info *model.IPInfo, err error) bool {
+		ast.Nil(err)
+		ranges = append(ranges, fmt.Sprintf("%s-%s %v", info.IPNet.Start, info.IPNet.End, info.Values()))
+		return true
+	})
+	ast.Equal([]string{
+		"0.0.0.0-1.0.0.255 [A a ]",
+		"1.0.1.0-1.0.1.255 [B b ]",
+		"1.0.2.0-1.0.2.255 [B x 1]",
+		"1.0.3.0-1.0.3.255 [B b ]",
+		"1.0.4.0-1.0.4.127 [ x 1]",
+		"1.0.6.0-255.255.255.255 [C c ]",
+	}, ranges)
+
+	// the ranges of a reader returning IPv4 length ranges keep their length
+	base.ipv4 = true
+	info, err = r.Find(net.ParseIP("1.0.2.1"))
+	ast.Nil(err)
+	ast.Equal("1.0.2.0-1.0.2.255 [B x 1]", fmt.Sprintf("%s-%s %v", info.IPNet.Start, info.IPNet.End, info.Values()))
+	ast.Len(info.IPNet.Start, net.IPv4len)
+	ast.Len(info.IPNet.End, net.IPv4len)
+	base.ipv4 = false
+
+	_, err = NewOverlayReader(base, []string{"isp"}, []*ValuedRange{
+		{IPNet: &ipnet.Range{Start: net.ParseIP("1.0.0.0"), End: net.ParseIP("1.0.2.255")}, Values: []string{"x"}},
+		{IPNet: &ipnet.Range{Start: net.ParseIP("1.0.2.0"), End: net.ParseIP("1.0.3.255")}, Values: []string{"y"}},
+	})
+	ast.ErrorIs(err, errors.ErrCIDROverlap)
+}
+
+func TestParseOverlay(t *testing.T) {
+	ast := assert.New(t)
+
+	overlay, err := ParseOverlay(strings.NewReader(`# Fields: country,isp
+# comment
+10.0.0.0/8	局域网,
+192.168.1.0-192.168.1.99	局域网,办公网
+192.168.2.1	局域网,网关
+`))
+	ast.Nil(err)
+	ast.Equal([]string{"country", "isp"}, overlay.Fields)
+	var ranges []string
+	for _, r := range overlay.Ranges {
+		ranges = append(ranges, fmt.Sprintf("%s-%s %q", r.IPNet.Start, r.IPNet.End, r.Values))
+	}
+	ast.Equal([]string{
+		`10.0.0.0-10.255.255.255 ["局域网" ""]`,
+		`192.168.1.0-192.168.1.99 ["局域网" "办公网"]`,
+		`192.168.2.1-192.168.2.1 ["局域网" "网关"]`,
+	}, ranges)
+
+	selected, err := overlay.Select([]string{"isp"})
+	ast.Nil(err)
+	ast.Equal([]string{"isp"}, selected.Fields)
+	ast.Equal([]string{"办公网"}, selected.Ranges[1].Values)
+	_, err = overlay.Select([]string{"city"})
+	ast.ErrorIs(err, errors.ErrFieldInvalid)
+
+	// the meta line takes precedence over the fields line
+	overlay, err = ParseOverlay(strings.NewReader("# Fields: country,province,city,isp\n" +
+		`# Meta: {"MetaVersion":1,"Format":"plain","IPVersion":1,"Fields":["isp"]}` + "\n1.0.0.0/24\t电信\n"))
+	ast.Nil(err)
+	ast.Equal([]string{"isp"}, overlay.Fields)
+
+	_, err = ParseOverlay(strings.NewReader("# Fields: country,isp\n1.0.0.0/24\t中国\n"))
+	ast.ErrorIs(err, errors.ErrMismatchedFieldsLength)
+	_, err = ParseOverlay(strings.NewReader("# Fields: country\n1.0.0.9-1.0.0.1\t中国\n"))
+	ast.ErrorIs(err, errors.ErrInvalidIPRange)
+	_, err = ParseOverlay(strings.NewReader("1.0.0.0/24\t中国\n"))
+	ast.ErrorIs(err, errors.ErrMetaMissing)
+}
@@ -0,0 +1,364 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"os"
+	"slices"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+const (
+	// PatchExt is the file extension of a patch.
+	PatchExt = ".ipsp"
+
+	// patchMagic is the header of a patch.
+	patchMagic = "IPSP"
+
+	// patchVersion is the version of the patch layout.
+	patchVersion = 1
+
+	// patchRemoved and patchValued mark whether a range of a patch has values.
+	patchRemoved = 0
+	patchValued  = 1
+)
+
+// Patch is an incremental update of a database, the ranges that changed between two releases
+// with the values of the new release. It carries the checksums of the release it applies to and of the release it yields,
+// see Checksum, so the old release can be updated without distributing the new release as a whole.
+type Patch struct {
+
+	// Fields lists the fields of the values.
+	Fields []string `json:"fields"`
+
+	// IPVersion is the IP version of the new release.
+	IPVersion int `json:"ip_version"`
+
+	// Base is the checksum of the release the patch applies to.
+	Base string `json:"base"`
+
+	// Result is the checksum of the release the patch yields.
+	Result string `json:"result"`
+
+	// Info holds the descriptive information of the new release, see model.Meta.CopyInfo.
+	Info *model.Meta `json:"info"`
+
+	// Ranges are the changed ranges in ascending order, with nil values where the new release has no data.
+	Ranges []*ValuedRange `json:"-"`
+}
+
+// CreatePatch creates the patch of the fields from the old to the new reader.
+// Fields are looked up by the names of either the database or the common fields.
+func CreatePatch(ctx context.Context, oldReader, newReader format.Reader, fields []string) (*Patch, error) {
+	p := &Patch{
+		Fields:    fields,
+		IPVersion: newReader.Meta().IPVersion,
+		Info:      &model.Meta{},
+	}
+	p.Info.CopyInfo(newReader.Meta())
+
+	base, result := newContentHash(fields), newContentHash(fields)
+	changelog := NewChangelog(oldReader, newReader, fields)
+	changelog.segment = func(seg *changeSegment) {
+		if seg.hasOld {
+			base.add(seg.IPNet, seg.Old)
+		}
+		if seg.hasNew {
+			result.add(seg.IPNet, seg.New)
+		}
+	}
+	err := changelog.Changes(ctx, func(change *Change) error {
+		for _, r := range change.Patch() {
+			p.Ranges = appendValuedRange(p.Ranges, r.IPNet, r.Values)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.Base, p.Result = base.sum(), result.sum()
+
+	return p, nil
+}
+
+// Apply verifies that base is the release the patch applies to,
+// and returns a reader of the release it yields. Closing the reader closes base.
+// The fields of base must be selected like the fields of the old release when the patch was created.
+func (p *Patch) Apply(ctx context.Context, base format.Reader) (*OverlayReader, error) {
+	sum, err := Checksum(ctx, base, p.Fields)
+	if err != nil {
+		return nil, err
+	}
+	if sum != p.Base {
+		return nil, fmt.Errorf("%w: the base database is %s, the patch applies to %s", errors.ErrChecksumMismatch, sum, p.Base)
+	}
+
+	r, err := NewOverlayReader(base, p.Fields, p.Ranges)
+	if err != nil {
+		return nil, err
+	}
+	r.meta.IPVersion = p.IPVersion
+	if p.Info != nil {
+		r.meta.CopyInfo(p.Info)
+	}
+	return r, nil
+}
+
+// Verify verifies that r is the release the patch yields.
+func (p *Patch) Verify(ctx context.Context, r format.Reader) error {
+	sum, err := Checksum(ctx, r, p.Fields)
+	if err != nil {
+		return err
+	}
+	if sum != p.Result {
+		return fmt.Errorf("%w: the result database is %s, the patch yields %s", errors.ErrChecksumMismatch, sum, p.Result)
+	}
+	return nil
+}
+
+// WriteTo writes the patch to w.
+//
+// Patch layout, integers are big endian:
+//
+//	magic "IPSP" | version uint16
+//	gzip stream of:
+//	header length uint32 | header JSON
+//	string count uint32 | (length uvarint | bytes) ...
+//	range count uint32 | (start 16 bytes | end 16 bytes | removed 0 | valued 1 (string index uvarint * len(fields))) ...
+func (p *Patch) WriteTo(w io.Writer) (int64, error) {
+	header, err := json.Marshal(p)
+	if err != nil {
+		return 0, err
+	}
+
+	strs := make([]string, 0)
+	strIndex := make(map[string]uint64)
+	for _, r := range p.Ranges {
+		if r.Values != nil && len(r.Values) != len(p.Fields) {
+			return 0, errors.ErrMismatchedFieldsLength
+		}
+		for _, v := range r.Values {
+			if _, ok := strIndex[v]; !ok {
+				strIndex[v] = uint64(len(strs))
+				strs = append(strs, v)
+			}
+		}
+	}
+
+	body := &bytes.Buffer{}
+	var tmp [binary.MaxVarintLen64]byte
+	putUint32 := func(v uint32) {
+		binary.BigEndian.PutUint32(tmp[:4], v)
+		body.Write(tmp[:4])
+	}
+	putUvarint := func(v uint64) {
+		body.Write(tmp[:binary.PutUvarint(tmp[:], v)])
+	}
+
+	putUint32(uint32(len(header)))
+	body.Write(header)
+
+	putUint32(uint32(len(strs)))
+	for _, s := range strs {
+		putUvarint(uint64(len(s)))
+		body.WriteString(s)
+	}
+
+	putUint32(uint32(len(p.Ranges)))
+	for _, r := range p.Ranges {
+		body.Write(r.IPNet.Start.To16())
+		body.Write(r.IPNet.End.To16())
+		if r.Values == nil {
+			body.WriteByte(patchRemoved)
+			continue
+		}
+		body.WriteByte(patchValued)
+		for _, v := range r.Values {
+			putUvarint(strIndex[v])
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(patchMagic)
+	binary.BigEndian.PutUint16(tmp[:2], patchVersion)
+	buf.Write(tmp[:2])
+	zw := gzip.NewWriter(buf)
+	if _, err := body.WriteTo(zw); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+
+	return buf.WriteTo(w)
+}
+
+// LoadPatch loads a patch from a file written by Patch.WriteTo.
+func LoadPatch(file string) (*Patch, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePatch(data)
+}
+
+// ParsePatch parses a patch written by Patch.WriteTo.
+func ParsePatch(data []byte) (*Patch, error) {
+	p := &memoryParser{data: data}
+	if string(p.next(len(patchMagic))) != patchMagic {
+		return nil, errors.ErrInvalidPatch
+	}
+	if v := p.next(2); v == nil || binary.BigEndian.Uint16(v) != patchVersion {
+		return nil, errors.ErrInvalidPatch
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(p.data))
+	if err != nil {
+		return nil, errors.ErrInvalidPatch
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, errors.ErrInvalidPatch
+	}
+	p = &memoryParser{data: body}
+
+	patch := &Patch{}
+	if err := json.Unmarshal(p.next(int(p.uint32())), patch); err != nil {
+		return nil, errors.ErrInvalidPatch
+	}
+
+	strs := make([]string, p.uint32())
+	for i := range strs {
+		strs[i] = string(p.next(int(p.uvarint())))
+	}
+
+	n := p.uint32()
+	for i := uint32(0); i < n && !p.err; i++ {
+		r := &ValuedRange{IPNet: &ipnet.Range{
+			Start: slices.Clone(net.IP(p.next(net.IPv6len))),
+			End:   slices.Clone(net.IP(p.next(net.IPv6len))),
+		}}
+		switch flag := p.next(1); {
+		case flag == nil:
+		case flag[0] == patchValued:
+			r.Values = make([]string, len(patch.Fields))
+			for j := range r.Values {
+				index := p.uvarint()
+				if index >= uint64(len(strs)) {
+					return nil, errors.ErrInvalidPatch
+				}
+				r.Values[j] = strs[index]
+			}
+		case flag[0] != patchRemoved:
+			return nil, errors.ErrInvalidPatch
+		}
+		if i > 0 && !ipnet.IPLess(patch.Ranges[i-1].IPNet.End, r.IPNet.Start) {
+			return nil, errors.ErrInvalidPatch
+		}
+		patch.Ranges = append(patch.Ranges, r)
+	}
+	if p.err {
+		return nil, errors.ErrInvalidPatch
+	}
+
+	return patch, nil
+}
+
+// Checksum returns the checksum of the data of the fields in the reader.
+// It only depends on the data, not on the format and the range layout of the database:
+// adjacent ranges with the same values are merged, and ranges whose values are all empty count as ranges without data.
+func Checksum(ctx context.Context, r format.Reader, fields []string) (string, error) {
+	c := newContentHash(fields)
+	start, end := addressSpace(r.Meta())
+	var err error
+	Ranges(ctx, r, start, end)(func(info *model.IPInfo, e error) bool {
+		if e != nil {
+			err = e
+			return false
+		}
+		c.add(info.IPNet, fieldValues(info, fields))
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	return c.sum(), nil
+}
+
+// contentHash hashes the ranges of a release in the form described by Checksum.
+type contentHash struct {
+	h    hash.Hash
+	last *ValuedRange
+	tmp  [binary.MaxVarintLen64]byte
+}
+
+func newContentHash(fields []string) *contentHash {
+	c := &contentHash{h: sha256.New()}
+	c.writeStrings(fields)
+	return c
+}
+
+// add adds the range ipr with values, ranges must be added in ascending order.
+func (c *contentHash) add(ipr *ipnet.Range, values []string) {
+	if !slices.ContainsFunc(values, func(v string) bool { return len(v) > 0 }) {
+		return
+	}
+	if c.last != nil && adjacent(c.last.IPNet, ipr) && slices.Equal(c.last.Values, values) {
+		c.last.IPNet.End = ipr.End.To16()
+		return
+	}
+	c.flush()
+	c.last = &ValuedRange{IPNet: &ipnet.Range{Start: ipr.Start.To16(), End: ipr.End.To16()}, Values: values}
+}
+
+// sum returns the checksum of the added ranges as a hex string.
+func (c *contentHash) sum() string {
+	c.flush()
+	return hex.EncodeToString(c.h.Sum(nil))
+}
+
+func (c *contentHash) flush() {
+	if c.last == nil {
+		return
+	}
+	c.h.Write(c.last.IPNet.Start)
+	c.h.Write(c.last.IPNet.End)
+	c.writeStrings(c.last.Values)
+	c.last = nil
+}
+
+func (c *contentHash) writeStrings(strs []string) {
+	c.h.Write(c.tmp[:binary.PutUvarint(c.tmp[:], uint64(len(strs)))])
+	for _, s := range strs {
+		c.h.Write(c.tmp[:binary.PutUvarint(c.tmp[:], uint64(len(s)))])
+		c.h.Write([]byte(s))
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+func TestPatch(t *testing.T) {
+	ast := assert.New(t)
+
+	fields := []string{"country", "isp"}
+	oldReader := newTestReader(fields,
+		testRange{"0.0.0.0", "1.0.0.255", []string{"A", "a"}},
+		testRange{"1.0.1.0", "1.0.1.255", []string{"B", "b"}},
+		testRange{"1.0.2.0", "1.0.3.255", []string{"C", "c"}},
+		testRange{"1.0.4.0", "1.0.4.255", []string{"D", "d"}},
+		testRange{"1.0.5.0", "255.255.255.255", []string{"E", "e"}})
+	newReader := newTestReader(fields,
+		testRange{"0.0.0.0", "1.0.0.255", []string{"A", "a"}},
+		testRange{"1.0.1.0", "1.0.1.255", []string{"B", "x"}},
+		testRange{"1.0.2.0", "1.0.2.255", []string{"C", "c"}},
+		testRange{"1.0.3.0", "1.0.3.255", []string{"F", "f"}},
+		testRange{"1.0.4.0", "1.0.4.255", nil},
+		testRange{"1.0.5.0", "255.255.255.255", []string{"E", "e"}})
+	ctx := context.Background()
+
+	patch, err := CreatePatch(ctx, oldReader, newReader, fields)
+	ast.Nil(err)
+	ast.Len(patch.Ranges, 4)
+	ast.Nil(patch.Ranges[3].Values)
+
+	buf := &bytes.Buffer{}
+	_, err = patch.WriteTo(buf)
+	ast.Nil(err)
+	parsed, err := ParsePatch(buf.Bytes())
+	ast.Nil(err)
+	ast.Equal(patch.Fields, parsed.Fields)
+	ast.Equal(patch.Base, parsed.Base)
+	ast.Equal(patch.Result, parsed.Result)
+	ast.Equal(len(patch.Ranges), len(parsed.Ranges))
+	for i := range patch.Ranges {
+		ast.True(patch.Ranges[i].IPNet.Start.Equal(parsed.Ranges[i].IPNet.Start))
+		ast.True(patch.Ranges[i].IPNet.End.Equal(parsed.Ranges[i].IPNet.End))
+		ast.Equal(patch.Ranges[i].Values, parsed.Ranges[i].Values)
+	}
+
+	oldSum, err := Checksum(ctx, oldReader, fields)
+	ast.Nil(err)
+	ast.Equal(oldSum, patch.Base)
+	newSum, err := Checksum(ctx, newReader, fields)
+	ast.Nil(err)
+	ast.Equal(newSum, patch.Result)
+
+	// the checksum does not depend on the range layout
+	split := newTestReader(fields,
+		testRange{"0.0.0.0", "0.255.255.255", []string{"A", "a"}},
+		testRange{"1.0.0.0", "1.0.0.255", []string{"A", "a"}},
+		testRange{"1.0.1.0", "1.0.1.255", []string{"B", "b"}},
+		testRange{"1.0.2.0", "1.0.2.255", []string{"C", "c"}},
+		testRange{"1.0.3.0", "1.0.3.255", []string{"C", "c"}},
+		testRange{"1.0.4.0", "1.0.4.255", []string{"D", "d"}},
+		testRange{"1.0.5.0", "255.255.255.255", []string{"E", "e"}})
+	split.ipv4 = true
+	applied, err := parsed.Apply(ctx, split)
+	ast.Nil(err)
+	result, err := NewMemoryReader(applied, 1)
+	ast.Nil(err)
+	ast.Nil(parsed.Verify(ctx, result))
+
+	_, err = parsed.Apply(ctx, newReader)
+	ast.ErrorIs(err, errors.ErrChecksumMismatch)
+	ast.ErrorIs(parsed.Verify(ctx, oldReader), errors.ErrChecksumMismatch)
+
+	_, err = ParsePatch(buf.Bytes()[:buf.Len()/2])
+	ast.ErrorIs(err, errors.ErrInvalidPatch)
+}
@@ -17,6 +17,7 @@
 package ipio
 
 import (
+	"context"
 	"net"
 
 	"github.com/sjzar/ips/format"
@@ -32,7 +33,8 @@ type StandardReader struct {
 	DBReader     format.Reader
 	OperateChain *operate.IPOperateChain
 
-	meta *model.Meta
+	meta       *model.Meta
+	fieldAlias map[string]string // user-defined aliases of common fields
 }
 
 // NewStandardReader initializes and returns a new StandardReader.
@@ -60,15 +62,22 @@ func (s *StandardReader) Meta() *model.Meta {
 		Fields:      meta.Fields,
 		FieldAlias:  meta.FieldAlias,
 	}
+	s.meta.CopyInfo(meta)
 	return s.meta
 }
 
 // Find retrieves IP information based on the given IP address.
 func (s *StandardReader) Find(ip net.IP) (*model.IPInfo, error) {
-	info, err := s.DBReader.Find(ip)
+	return s.FindContext(context.Background(), ip)
+}
+
+// FindContext retrieves IP information based on the given IP address, honoring ctx.
+func (s *StandardReader) FindContext(ctx context.Context, ip net.IP) (*model.IPInfo, error) {
+	info, err := FindContext(ctx, s.DBReader, ip)
 	if err != nil {
 		return nil, err
 	}
+	s.addFieldAlias(info)
 
 	if s.OperateChain != nil {
 		if err := s.OperateChain.Do(info); err != nil {
@@ -79,26 +88,77 @@ func (s *StandardReader) Find(ip net.IP) (*model.IPInfo, error) {
 	return info, nil
 }
 
+// FindBatch retrieves IP information for a batch of IP addresses, in the same order.
+// The addresses are looked up in sorted order, and addresses in the range of the previous
+// result reuse it, so the operate chain runs once per distinct range.
+func (s *StandardReader) FindBatch(ips []net.IP) ([]*model.IPInfo, error) {
+	return s.FindBatchContext(context.Background(), ips)
+}
+
+// FindBatchContext is like FindBatch but honors ctx.
+func (s *StandardReader) FindBatchContext(ctx context.Context, ips []net.IP) ([]*model.IPInfo, error) {
+	return findBatch(ctx, s.FindContext, ips)
+}
+
+// Ranges returns an iterator over the IP ranges of the underlying database with the operate chain applied.
+// It returns nil if the underlying database reader cannot enumerate ranges natively.
+func (s *StandardReader) Ranges(ctx context.Context, start, end net.IP) model.IPInfoSeq {
+	it, ok := s.DBReader.(format.RangeIterator)
+	if !ok {
+		return nil
+	}
+	seq := it.Ranges(ctx, start, end)
+	if seq == nil {
+		return nil
+	}
+
+	return func(yield func(*model.IPInfo, error) bool) {
+		seq(func(info *model.IPInfo, err error) bool {
+			if err != nil {
+				return yield(nil, err)
+			}
+			s.addFieldAlias(info)
+			if s.OperateChain != nil {
+				if err := s.OperateChain.Do(info); err != nil {
+					yield(nil, err)
+					return false
+				}
+			}
+			return yield(info, nil)
+		})
+	}
+}
+
 type StandardReaderOption struct {
 	IPVersion int
 
 	Fields []string
+
+	// FieldAlias maps common fields to database fields, in addition to the aliases of the database format.
+	FieldAlias map[string]string
 }
 
 // SetOption configures the StandardReader with the provided option.
+// Other options are passed to the database reader.
 func (s *StandardReader) SetOption(option interface{}) error {
-	if opt, ok := option.(StandardReaderOption); ok {
-		if opt.IPVersion > 0 {
-			if err := s.setIPVersion(opt.IPVersion); err != nil {
-				return err
-			}
+	opt, ok := option.(StandardReaderOption)
+	if !ok {
+		return s.DBReader.SetOption(option)
+	}
+
+	if opt.IPVersion > 0 {
+		if err := s.setIPVersion(opt.IPVersion); err != nil {
+			return err
 		}
-		if len(opt.Fields) > 0 {
-			if err := s.setFields(opt.Fields); err != nil {
-				return err
-			}
+	}
+	if len(opt.Fields) > 0 {
+		if err := s.setFields(opt.Fields); err != nil {
+			return err
 		}
 	}
+	if len(opt.FieldAlias) > 0 {
+		s.setFieldAlias(opt.FieldAlias)
+	}
 
 	return nil
 }
@@ -136,3 +196,37 @@ func (s *StandardReader) setFields(fields []string) error {
 	s.meta = meta
 	return nil
 }
+
+// setFieldAlias adds user-defined aliases of common fields.
+// The aliases override those of the database format, the meta only keeps aliases of the fields in the database.
+func (s *StandardReader) setFieldAlias(fieldAlias map[string]string) {
+	meta := s.Meta()
+	// copy the aliases, they are shared with the database reader
+	aliases := make(map[string]string, len(meta.FieldAlias)+len(fieldAlias))
+	for commonField, dbField := range meta.FieldAlias {
+		aliases[commonField] = dbField
+	}
+	meta.FieldAlias = aliases
+	meta.AddCommonFieldAlias(fieldAlias)
+
+	if s.fieldAlias == nil {
+		s.fieldAlias = make(map[string]string, len(fieldAlias))
+	}
+	for commonField, dbField := range fieldAlias {
+		s.fieldAlias[commonField] = dbField
+	}
+}
+
+// addFieldAlias adds the user-defined aliases to the IP information.
+func (s *StandardReader) addFieldAlias(info *model.IPInfo) {
+	if len(s.fieldAlias) == 0 {
+		return
+	}
+	// copy the aliases, they may be shared with the database reader
+	aliases := make(map[string]string, len(info.FieldAlias)+len(s.fieldAlias))
+	for commonField, dbField := range info.FieldAlias {
+		aliases[commonField] = dbField
+	}
+	info.FieldAlias = aliases
+	info.AddCommonFieldAlias(s.fieldAlias)
+}
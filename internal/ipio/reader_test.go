@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/internal/operate"
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// testReader is an IPv4 reader backed by a list of ranges, used in tests.
+type testReader struct {
+	meta   *model.Meta
+	ranges []testRange
+	finds  int
+	delay  time.Duration
+	ipv4   bool // return ranges in IPv4 length, like the qqwry and ip2region readers
+}
+
+type testRange struct {
+	start, end string
+	values     []string
+}
+
+func newTestReader(fields []string, ranges ...testRange) *testReader {
+	return &testReader{
+		meta: &model.Meta{
+			MetaVersion: model.MetaVersion,
+			Format:      "test",
+			IPVersion:   model.IPv4,
+			Fields:      fields,
+		},
+		ranges: ranges,
+	}
+}
+
+func (r *testReader) Meta() *model.Meta {
+	return r.meta
+}
+
+func (r *testReader) Find(ip net.IP) (*model.IPInfo, error) {
+	r.finds++
+	time.Sleep(r.delay)
+	for _, rg := range r.ranges {
+		ipr := &ipnet.Range{Start: net.ParseIP(rg.start), End: net.ParseIP(rg.end)}
+		if !ipr.Contains(ip.To16()) {
+			continue
+		}
+		if r.ipv4 {
+			ipr = &ipnet.Range{Start: ipr.Start.To4(), End: ipr.End.To4()}
+		}
+		if rg.values == nil {
+			return nil, errors.NewNotFoundError(ip, ipr)
+		}
+		data := make(map[string]string, len(r.meta.Fields))
+		for i, field := range r.meta.Fields {
+			data[field] = rg.values[i]
+		}
+		return &model.IPInfo{
+			IP:     ip,
+			IPNet:  ipr,
+			Data:   data,
+			Fields: r.meta.Fields,
+		}, nil
+	}
+	return nil, errors.ErrInvalidDatabase
+}
+
+func (r *testReader) SetOption(option interface{}) error {
+	return nil
+}
+
+func (r *testReader) Close() error {
+	return nil
+}
+
+func TestStandardReaderFindBatch(t *testing.T) {
+	ast := assert.New(t)
+
+	dbReader := newTestReader([]string{"country"},
+		testRange{"0.0.0.0", "1.0.0.255", []string{"A"}},
+		testRange{"1.0.1.0", "1.0.1.255", []string{"B"}},
+		testRange{"1.0.2.0", "255.255.255.255", []string{"C"}},
+	)
+	operates := 0
+	chain := operate.NewIPOperateChain()
+	chain.Use(func(info *model.IPInfo) error {
+		operates++
+		return nil
+	})
+	reader := NewStandardReader(dbReader, chain)
+
+	ips := []net.IP{
+		net.ParseIP("1.0.1.1"),
+		net.ParseIP("1.0.0.1"),
+		net.ParseIP("8.8.8.8"),
+		net.ParseIP("1.0.1.2"),
+		net.ParseIP("1.0.0.2").To4(),
+		net.ParseIP("1.0.1.1"),
+	}
+	infos, err := reader.FindBatch(ips)
+	ast.Nil(err)
+	ast.Equal(len(ips), len(infos))
+	for i, want := range []string{"B", "A", "C", "B", "A", "B"} {
+		ast.Equal(ips[i], infos[i].IP)
+		ast.Equal([]string{want}, infos[i].Values())
+	}
+	ast.Equal(3, dbReader.finds)
+	ast.Equal(3, operates)
+
+	// reused results do not share their data
+	infos[0].Data["country"] = "X"
+	ast.Equal([]string{"B"}, infos[3].Values())
+	ast.Equal([]string{"B"}, infos[5].Values())
+
+	// generic readers go through Find
+	dbReader.finds = 0
+	infos, err = FindBatch(dbReader, ips)
+	ast.Nil(err)
+	ast.Equal([]string{"C"}, infos[2].Values())
+	ast.Equal(3, dbReader.finds)
+
+	_, err = reader.FindBatch([]net.IP{nil})
+	ast.Equal(errors.ErrInvalidIP, err)
+
+	infos, err = reader.FindBatch(nil)
+	ast.Nil(err)
+	ast.Empty(infos)
+}
+
+func TestNotFound(t *testing.T) {
+	ast := assert.New(t)
+
+	dbReader := newTestReader([]string{"country"},
+		testRange{"0.0.0.0", "0.255.255.255", []string{"A"}},
+		testRange{"1.0.0.0", "1.255.255.255", nil},
+		testRange{"2.0.0.0", "255.255.255.255", []string{"B"}},
+	)
+
+	// batch lookups mark the result
+	infos, err := FindBatch(dbReader, []net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("2.2.2.2")})
+	ast.Nil(err)
+	ast.True(infos[0].NotFound)
+	ast.Equal("1.0.0.0", infos[0].IPNet.Start.String())
+	ast.Empty(infos[0].Values())
+	ast.False(infos[1].NotFound)
+
+	// cached unknown ranges
+	cached := NewCachedReader(dbReader, 0, 0)
+	for i := 0; i < 2; i++ {
+		_, err = cached.Find(net.ParseIP("1.1.1.1"))
+		ast.True(errors.Is(err, errors.ErrNotFound))
+	}
+	ast.Equal(1, cached.Len())
+
+	// hybrid readers skip readers without data
+	otherReader := newTestReader([]string{"isp"},
+		testRange{"0.0.0.0", "1.0.255.255", []string{"X"}},
+		testRange{"1.1.0.0", "255.255.255.255", nil},
+	)
+	hybrid, err := NewHybridReader(nil, dbReader, otherReader)
+	ast.Nil(err)
+	info, err := hybrid.Find(net.ParseIP("1.0.0.1"))
+	ast.Nil(err)
+	ast.Equal([]string{"", "X"}, info.Values())
+	ast.Equal("1.0.0.0", info.IPNet.Start.String())
+	ast.Equal("1.0.255.255", info.IPNet.End.String())
+	_, err = hybrid.Find(net.ParseIP("1.1.1.1"))
+	notFound, ok := errors.AsNotFound(err)
+	ast.True(ok)
+	ast.Equal("1.1.0.0", notFound.IPNet.Start.String())
+	ast.Equal("1.255.255.255", notFound.IPNet.End.String())
+
+	// dumps skip unknown ranges
+	writer, err := NewMemoryWriter(dbReader.Meta())
+	ast.Nil(err)
+	ast.Nil(NewStandardDumper(dbReader, writer).Dump(2))
+	memReader, err := writer.Reader()
+	ast.Nil(err)
+	ast.Equal(2, memReader.Len())
+	_, err = memReader.Find(net.ParseIP("1.1.1.1"))
+	ast.True(errors.Is(err, errors.ErrNotFound))
+	info, err = memReader.Find(net.ParseIP("9.9.9.9"))
+	ast.Nil(err)
+	ast.Equal([]string{"B"}, info.Values())
+}
@@ -0,0 +1,319 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/format/plain"
+	"github.com/sjzar/ips/internal/ipio"
+	"github.com/sjzar/ips/internal/util"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// changeTypes lists the types of changes in the order of the summary.
+var changeTypes = []string{
+	ipio.ChangeAdded, ipio.ChangeRemoved, ipio.ChangeReassigned, ipio.ChangeSplit, ipio.ChangeMerged, ipio.ChangeResized,
+}
+
+// Changelog lists the changes between the old and the new release of a database as text or json,
+// and writes them to outputFile, or standard output if outputFile is empty.
+// If patchFile is set, the changed ranges with the values of the new release are written to it in plain format,
+// ranges without data in the new release have empty values.
+// The fields of dp_fields are compared, or DefaultFields if it is empty.
+func (m *Manager) Changelog(_format, file []string, outputType, outputFile, patchFile string) error {
+	return m.ChangelogContext(context.Background(), _format, file, outputType, outputFile, patchFile)
+}
+
+// ChangelogContext is like Changelog but stops with ctx.Err() once ctx is done.
+// The output and patch files are only created if the changelog succeeds.
+func (m *Manager) ChangelogContext(ctx context.Context, _format, file []string, outputType, outputFile, patchFile string) error {
+	write := func(patch io.Writer) error {
+		if len(outputFile) == 0 {
+			return m.ChangelogToContext(ctx, _format, file, outputType, os.Stdout, patch)
+		}
+		return util.WriteFileAtomic(outputFile, func(w io.Writer) error {
+			return m.ChangelogToContext(ctx, _format, file, outputType, w, patch)
+		})
+	}
+
+	var err error
+	if len(patchFile) == 0 {
+		err = write(nil)
+	} else {
+		err = util.WriteFileAtomic(patchFile, write)
+	}
+	if err != nil {
+		log.Debug("util.WriteFileAtomic error: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// ChangelogTo is like Changelog but writes the changes to w, and the patch to patch if it is not nil.
+func (m *Manager) ChangelogTo(_format, file []string, outputType string, w, patch io.Writer) error {
+	return m.ChangelogToContext(context.Background(), _format, file, outputType, w, patch)
+}
+
+// ChangelogToContext is like ChangelogTo but stops with ctx.Err() once ctx is done.
+func (m *Manager) ChangelogToContext(ctx context.Context, _format, file []string, outputType string, w, patch io.Writer) error {
+	var out changelogOutput
+	switch outputType {
+	case "", OutputTypeText:
+		out = &changelogTextOutput{w: w}
+	case OutputTypeJSON:
+		out = &changelogJSONOutput{w: w, indent: m.Conf.JsonIndent}
+	default:
+		return errors.ErrUnsupportedOutput
+	}
+
+	oldReader, newReader, err := m.createDiffReaders(_format, file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = oldReader.Close()
+		_ = newReader.Close()
+	}()
+
+	fields := m.diffFields()
+	var pw *plain.Writer
+	if patch != nil {
+		meta := &model.Meta{
+			MetaVersion: model.MetaVersion,
+			Format:      plain.DBFormat,
+			IPVersion:   oldReader.Meta().IPVersion | newReader.Meta().IPVersion,
+			Fields:      fields,
+		}
+		if pw, err = plain.NewWriter(meta); err != nil {
+			return err
+		}
+		if err := pw.Open(patch); err != nil {
+			return err
+		}
+	}
+
+	summary := newChangelogSummary()
+	if err := out.Begin(file, fields); err != nil {
+		return err
+	}
+	err = ipio.NewChangelog(oldReader, newReader, fields).Changes(ctx, func(change *ipio.Change) error {
+		summary.add(change)
+		if pw != nil {
+			for _, r := range change.Patch() {
+				if err := pw.Insert(&model.IPInfo{IPNet: r.IPNet, Fields: fields, Data: valuesData(fields, r.Values)}); err != nil {
+					return err
+				}
+			}
+		}
+		return out.Change(change)
+	})
+	if err != nil {
+		log.Debug("Changelog.Changes error: ", err)
+		return err
+	}
+	if pw != nil {
+		if err := pw.Close(); err != nil {
+			return err
+		}
+	}
+	return out.End(summary)
+}
+
+// valuesData maps the fields to the values, nil values map to empty values.
+func valuesData(fields, values []string) map[string]string {
+	data := make(map[string]string, len(fields))
+	for i, field := range fields {
+		if values != nil {
+			data[field] = values[i]
+		}
+	}
+	return data
+}
+
+// changelogSummary counts the changes and their addresses by type.
+type changelogSummary map[string]*changeCount
+
+// changeCount is the number of changes of a type and the number of addresses they cover.
+type changeCount struct {
+	Changes   int      `json:"changes"`
+	Addresses *big.Int `json:"addresses"`
+}
+
+func newChangelogSummary() changelogSummary {
+	summary := make(changelogSummary, len(changeTypes))
+	for _, t := range changeTypes {
+		summary[t] = &changeCount{Addresses: new(big.Int)}
+	}
+	return summary
+}
+
+func (s changelogSummary) add(change *ipio.Change) {
+	count := s[change.Type]
+	count.Changes++
+	count.Addresses.Add(count.Addresses, change.Range().Size())
+}
+
+// changelogOutput writes a changelog.
+type changelogOutput interface {
+
+	// Begin starts the output of the changelog of the files.
+	Begin(file []string, fields []string) error
+
+	// Change writes a change.
+	Change(change *ipio.Change) error
+
+	// End writes the summary and finishes the output.
+	End(summary changelogSummary) error
+}
+
+// changelogTextOutput writes each change as its type and range, followed by the ranges of the old release (-),
+// the ranges of the new release (+) and the field changes, and a summary table.
+type changelogTextOutput struct {
+	w      io.Writer
+	fields []string
+}
+
+func (o *changelogTextOutput) Begin(file []string, fields []string) error {
+	o.fields = fields
+	_, err := fmt.Fprintf(o.w, "--- %s\n+++ %s\n", file[0], file[1])
+	return err
+}
+
+func (o *changelogTextOutput) Change(change *ipio.Change) error {
+	buf := &strings.Builder{}
+	ipr := change.Range()
+	fmt.Fprintf(buf, "\n%s %s - %s\n", change.Type, ipr.Start, ipr.End)
+	for _, r := range change.Old {
+		fmt.Fprintf(buf, "\t- %s - %s [%s]\n", r.IPNet.Start, r.IPNet.End, strings.Join(r.Values, ","))
+	}
+	for _, r := range change.New {
+		fmt.Fprintf(buf, "\t+ %s - %s [%s]\n", r.IPNet.Start, r.IPNet.End, strings.Join(r.Values, ","))
+	}
+	for _, fc := range change.Fields {
+		fmt.Fprintf(buf, "\t%s: %q -> %q\n", fc.Field, fc.Old, fc.New)
+	}
+	_, err := io.WriteString(o.w, buf.String())
+	return err
+}
+
+func (o *changelogTextOutput) End(summary changelogSummary) error {
+	buf := &strings.Builder{}
+	buf.WriteString("\n")
+	table := tablewriter.NewWriter(buf)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Type", "Changes", "Addresses"})
+	for _, t := range changeTypes {
+		table.Append([]string{t, fmt.Sprint(summary[t].Changes), summary[t].Addresses.String()})
+	}
+	table.Render()
+	_, err := io.WriteString(o.w, buf.String())
+	return err
+}
+
+// changelogJSONOutput writes the changes and the summary as a JSON object.
+type changelogJSONOutput struct {
+	w      io.Writer
+	indent bool
+	fields []string
+	result changelogResult
+}
+
+// changelogResult is the JSON output of a changelog.
+type changelogResult struct {
+	Old     string           `json:"old"`
+	New     string           `json:"new"`
+	Fields  []string         `json:"fields"`
+	Changes []changeOutput   `json:"changes"`
+	Summary changelogSummary `json:"summary"`
+}
+
+// changeOutput is a change in the JSON output of a changelog.
+type changeOutput struct {
+	Type   string              `json:"type"`
+	Start  string              `json:"start"`
+	End    string              `json:"end"`
+	Old    []valuedRangeOutput `json:"old"`
+	New    []valuedRangeOutput `json:"new"`
+	Fields []fieldChangeOutput `json:"fields"`
+}
+
+// valuedRangeOutput is a range with values in the JSON output of a changelog.
+type valuedRangeOutput struct {
+	Start  string            `json:"start"`
+	End    string            `json:"end"`
+	Values map[string]string `json:"values"`
+}
+
+// fieldChangeOutput is a field change in the JSON output of a changelog.
+type fieldChangeOutput struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+func (o *changelogJSONOutput) Begin(file []string, fields []string) error {
+	o.fields = fields
+	o.result = changelogResult{Old: file[0], New: file[1], Fields: fields, Changes: []changeOutput{}}
+	return nil
+}
+
+func (o *changelogJSONOutput) Change(change *ipio.Change) error {
+	ipr := change.Range()
+	co := changeOutput{
+		Type:   change.Type,
+		Start:  ipr.Start.String(),
+		End:    ipr.End.String(),
+		Old:    o.ranges(change.Old),
+		New:    o.ranges(change.New),
+		Fields: make([]fieldChangeOutput, 0, len(change.Fields)),
+	}
+	for _, fc := range change.Fields {
+		co.Fields = append(co.Fields, fieldChangeOutput{Field: fc.Field, Old: fc.Old, New: fc.New})
+	}
+	o.result.Changes = append(o.result.Changes, co)
+	return nil
+}
+
+func (o *changelogJSONOutput) ranges(ranges []*ipio.ValuedRange) []valuedRangeOutput {
+	ret := make([]valuedRangeOutput, 0, len(ranges))
+	for _, r := range ranges {
+		ret = append(ret, valuedRangeOutput{Start: r.IPNet.Start.String(), End: r.IPNet.End.String(), Values: valuesData(o.fields, r.Values)})
+	}
+	return ret
+}
+
+func (o *changelogJSONOutput) End(summary changelogSummary) error {
+	o.result.Summary = summary
+	encoder := json.NewEncoder(o.w)
+	encoder.SetEscapeHTML(false)
+	if o.indent {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(o.result)
+}
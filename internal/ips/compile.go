@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"context"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/internal/ipio"
+	"github.com/sjzar/ips/internal/util"
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+// Compile reads a database the same way as queries do, including field selection, data rewriting
+// and translation, and writes it to outputFile as a memory snapshot that is loaded by ipio.MemoryReader.
+func (m *Manager) Compile(_format, file []string, outputFile string) error {
+	return m.CompileContext(context.Background(), _format, file, outputFile)
+}
+
+// CompileContext is like Compile but stops with ctx.Err() once ctx is done.
+// The output file is only created if compiling succeeds.
+func (m *Manager) CompileContext(ctx context.Context, _format, file []string, outputFile string) error {
+
+	if len(_format) == 0 {
+		_format = make([]string, len(file))
+	} else if len(file) != len(_format) {
+		return errors.ErrInvalidFormat
+	}
+
+	reader, err := m.createReader(_format, file, false)
+	if err != nil {
+		log.Debug("m.createReader error: ", err)
+		return err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	memoryReader, err := ipio.NewMemoryReaderContext(ctx, reader, m.Conf.ReaderJobs)
+	if err != nil {
+		log.Debug("ipio.NewMemoryReaderContext error: ", err)
+		return err
+	}
+
+	if err := util.WriteFileAtomic(outputFile, func(w io.Writer) error {
+		_, err := memoryReader.WriteTo(w)
+		return err
+	}); err != nil {
+		log.Debug("util.WriteFileAtomic error: ", err)
+		return err
+	}
+
+	return nil
+}
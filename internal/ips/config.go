@@ -36,6 +36,9 @@ const (
 	// OutputTypeAlfred represents the Alfred output format.
 	OutputTypeAlfred = "alfred"
 
+	// OutputTypeCSV represents the CSV output format.
+	OutputTypeCSV = "csv"
+
 	// DefaultFields represents the default output fields.
 	DefaultFields = "country,province,city,isp"
 )
@@ -65,11 +68,29 @@ type Config struct {
 
 	// HybridMode specifies the operational mode of the HybridReader.
 	// It determines how the HybridReader processes and combines data from multiple IP database readers.
-	// Accepted values are "comparison" and "aggregation":
+	// Accepted values are "comparison", "aggregation", "consensus" and "cascade":
 	// - "comparison": Used for comparing data across different databases, where the output includes data from all readers.
 	// - "aggregation": Used for creating a unified view of data by aggregating and supplementing missing fields from multiple databases. (default)
+	// - "consensus": Used for picking, per field, the value agreed on by the most databases, with an agreement score.
+	// - "cascade": Used for querying the databases one after another until every selected field has a value.
 	HybridMode string `mapstructure:"hybrid_mode"`
 
+	// HybridPriority maps fields to the indexes of the databases they are taken from in aggregation and cascade mode,
+	// e.g. "country=1,0;isp=2,0". A field takes the first non-empty value of the listed databases.
+	HybridPriority string `mapstructure:"hybrid_priority"`
+
+	// HybridWeights lists the voting weights of the databases in consensus mode, separated by commas.
+	// Databases without weight vote with weight 1.
+	HybridWeights string `mapstructure:"hybrid_weights"`
+
+	// HybridTieBreak specifies how ties are broken in consensus mode,
+	// "first" to pick the value of the first listed database (default) or "empty" to leave the field empty.
+	HybridTieBreak string `mapstructure:"hybrid_tie_break"`
+
+	// HybridTimeoutMs limits the lookup time (in milliseconds) of each database of the HybridReader.
+	// Databases that time out are left out of the result. (default is 0, no limit)
+	HybridTimeoutMs int `mapstructure:"hybrid_timeout_ms"`
+
 	// Fields lists the output fields.
 	// default is country, province, city, isp
 	Fields string `mapstructure:"fields" default:"country,province,city,isp"`
@@ -80,6 +101,12 @@ type Config struct {
 	// RewriteFiles lists the files for data rewriting.
 	RewriteFiles string `mapstructure:"rewrite_files"`
 
+	// Overlay specifies a file in plain format with ranges whose values take precedence over the databases of lookups.
+	Overlay string `mapstructure:"overlay"`
+
+	// OverlayFields lists the fields of the overlay file to apply, all fields if empty.
+	OverlayFields string `mapstructure:"overlay_fields"`
+
 	// OutputType specifies the type of the output. (default is text)
 	OutputType string `mapstructure:"output_type"`
 
@@ -101,6 +128,16 @@ type Config struct {
 	// DPRewriterFiles lists the files for rewriting during dump and pack operations.
 	DPRewriterFiles string `mapstructure:"dp_rewriter_files"`
 
+	// DPSources indicates whether to add the source of each field as an extra field "<field>_source"
+	// when dumping and packing multiple databases, for auditing merged databases.
+	DPSources bool `mapstructure:"dp_sources"`
+
+	// DPOverlay specifies a file in plain format with ranges whose values take precedence during dump and pack operations.
+	DPOverlay string `mapstructure:"dp_overlay"`
+
+	// DPOverlayFields lists the fields of the dump and pack overlay file to apply, all fields if empty.
+	DPOverlayFields string `mapstructure:"dp_overlay_fields"`
+
 	// Database
 	// ReaderOption specifies the options for the reader.
 	ReaderOption string `mapstructure:"reader_option"`
@@ -108,10 +145,21 @@ type Config struct {
 	// WriterOption specifies the options for the writer.
 	WriterOption string `mapstructure:"writer_option"`
 
+	// FieldAliasFile specifies the file mapping database fields to common fields,
+	// for databases whose field names are not known by their format.
+	FieldAliasFile string `mapstructure:"field_alias_file"`
+
 	// ReaderJobs specifies the number of concurrent jobs for the reader.
 	// It controls how many reading operations can be performed in parallel.
 	ReaderJobs int `mapstructure:"reader_jobs"`
 
+	// CacheSize specifies the number of IP ranges cached for queries. (default is 0, no cache)
+	// Every cached range answers all IPs in it, it is used in query and server mode.
+	CacheSize int `mapstructure:"cache_size"`
+
+	// CacheTTLS specifies the lifetime (in seconds) of the cached IP ranges. (default is 0, no expiration)
+	CacheTTLS int `mapstructure:"cache_ttl_s"`
+
 	// MyIP
 	// LocalAddr specifies the local address (in IP format) that should be used for outbound connections.
 	// Useful in systems with multiple network interfaces.
@@ -166,6 +214,18 @@ func (c *Config) ShowConfig(allKeys bool) string {
 	if allKeys || len(c.HybridMode) > 0 {
 		str += fmt.Sprintf("hybrid_mode:\t\t[%s]\n", c.HybridMode)
 	}
+	if allKeys || len(c.HybridPriority) > 0 {
+		str += fmt.Sprintf("hybrid_priority:\t[%s]\n", c.HybridPriority)
+	}
+	if allKeys || len(c.HybridWeights) > 0 {
+		str += fmt.Sprintf("hybrid_weights:\t\t[%s]\n", c.HybridWeights)
+	}
+	if allKeys || len(c.HybridTieBreak) > 0 {
+		str += fmt.Sprintf("hybrid_tie_break:\t[%s]\n", c.HybridTieBreak)
+	}
+	if allKeys || c.HybridTimeoutMs > 0 {
+		str += fmt.Sprintf("hybrid_timeout_ms:\t[%d]\n", c.HybridTimeoutMs)
+	}
 	if allKeys || len(c.Fields) > 0 {
 		str += fmt.Sprintf("fields:\t\t\t[%s]\n", c.Fields)
 	}
@@ -175,6 +235,12 @@ func (c *Config) ShowConfig(allKeys bool) string {
 	if allKeys || len(c.RewriteFiles) > 0 {
 		str += fmt.Sprintf("rewrite_files:\t\t[%s]\n", c.RewriteFiles)
 	}
+	if allKeys || len(c.Overlay) > 0 {
+		str += fmt.Sprintf("overlay:\t\t[%s]\n", c.Overlay)
+	}
+	if allKeys || len(c.OverlayFields) > 0 {
+		str += fmt.Sprintf("overlay_fields:\t\t[%s]\n", c.OverlayFields)
+	}
 	if allKeys || len(c.OutputType) > 0 {
 		str += fmt.Sprintf("output_type:\t\t[%s]\n", c.OutputType)
 	}
@@ -193,12 +259,30 @@ func (c *Config) ShowConfig(allKeys bool) string {
 	if allKeys || len(c.DPRewriterFiles) > 0 {
 		str += fmt.Sprintf("dp_rewriter_files:\t[%s]\n", c.DPRewriterFiles)
 	}
+	if allKeys || c.DPSources {
+		str += fmt.Sprintf("dp_sources:\t\t[%v]\n", c.DPSources)
+	}
+	if allKeys || len(c.DPOverlay) > 0 {
+		str += fmt.Sprintf("dp_overlay:\t\t[%s]\n", c.DPOverlay)
+	}
+	if allKeys || len(c.DPOverlayFields) > 0 {
+		str += fmt.Sprintf("dp_overlay_fields:\t[%s]\n", c.DPOverlayFields)
+	}
 	if allKeys || len(c.ReaderOption) > 0 {
 		str += fmt.Sprintf("reader_option:\t\t[%s]\n", c.ReaderOption)
 	}
 	if allKeys || len(c.WriterOption) > 0 {
 		str += fmt.Sprintf("writer_option:\t\t[%s]\n", c.WriterOption)
 	}
+	if allKeys || len(c.FieldAliasFile) > 0 {
+		str += fmt.Sprintf("field_alias_file:\t[%s]\n", c.FieldAliasFile)
+	}
+	if allKeys || c.CacheSize > 0 {
+		str += fmt.Sprintf("cache_size:\t\t[%d]\n", c.CacheSize)
+	}
+	if allKeys || c.CacheTTLS > 0 {
+		str += fmt.Sprintf("cache_ttl_s:\t\t[%d]\n", c.CacheTTLS)
+	}
 	if allKeys || len(c.LocalAddr) > 0 {
 		str += fmt.Sprintf("local_addr:\t\t[%s]\n", c.LocalAddr)
 	}
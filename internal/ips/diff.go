@@ -0,0 +1,290 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/internal/ipio"
+	"github.com/sjzar/ips/internal/operate"
+	"github.com/sjzar/ips/internal/util"
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+// Diff compares two database files range by range and writes the differing ranges
+// with the values of both databases and the statistics to outputFile, or standard output if outputFile is empty.
+// The output type is text, csv or json, csv only lists the ranges.
+// The fields of dp_fields are compared, or DefaultFields if it is empty.
+func (m *Manager) Diff(_format, file []string, outputType, outputFile string) error {
+	return m.DiffContext(context.Background(), _format, file, outputType, outputFile)
+}
+
+// DiffContext is like Diff but stops with ctx.Err() once ctx is done.
+func (m *Manager) DiffContext(ctx context.Context, _format, file []string, outputType, outputFile string) error {
+	if len(outputFile) == 0 {
+		return m.DiffToContext(ctx, _format, file, outputType, os.Stdout)
+	}
+
+	if err := util.WriteFileAtomic(outputFile, func(w io.Writer) error {
+		return m.DiffToContext(ctx, _format, file, outputType, w)
+	}); err != nil {
+		log.Debug("util.WriteFileAtomic error: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// DiffTo is like Diff but writes the output to w.
+func (m *Manager) DiffTo(_format, file []string, outputType string, w io.Writer) error {
+	return m.DiffToContext(context.Background(), _format, file, outputType, w)
+}
+
+// DiffToContext is like DiffTo but stops with ctx.Err() once ctx is done.
+func (m *Manager) DiffToContext(ctx context.Context, _format, file []string, outputType string, w io.Writer) error {
+	var out diffOutput
+	switch outputType {
+	case "", OutputTypeText:
+		out = &diffTextOutput{w: w}
+	case OutputTypeCSV:
+		out = &diffCSVOutput{w: csv.NewWriter(w)}
+	case OutputTypeJSON:
+		out = &diffJSONOutput{w: w, indent: m.Conf.JsonIndent}
+	default:
+		return errors.ErrUnsupportedOutput
+	}
+
+	oldReader, newReader, err := m.createDiffReaders(_format, file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = oldReader.Close()
+		_ = newReader.Close()
+	}()
+
+	fields := m.diffFields()
+	if err := out.Begin(file, fields); err != nil {
+		return err
+	}
+	stats, err := ipio.NewDiffer(oldReader, newReader, fields).Diff(ctx, out.Range)
+	if err != nil {
+		log.Debug("Differ.Diff error: ", err)
+		return err
+	}
+	return out.End(stats)
+}
+
+// createDiffReaders creates the readers of the old and the new database file.
+func (m *Manager) createDiffReaders(_format, file []string) (format.Reader, format.Reader, error) {
+	if len(file) != 2 {
+		return nil, nil, errors.ErrMissingDatabases
+	}
+	if len(_format) == 0 {
+		_format = make([]string, len(file))
+	} else if len(file) != len(_format) {
+		return nil, nil, errors.ErrInvalidFormat
+	}
+
+	if err := m.checkReaderOption(_format, file); err != nil {
+		return nil, nil, err
+	}
+
+	oldReader, err := m.createStandardReader(_format[0], file[0], true)
+	if err != nil {
+		log.Debug("m.createStandardReader error: ", err)
+		return nil, nil, err
+	}
+	newReader, err := m.createStandardReader(_format[1], file[1], true)
+	if err != nil {
+		log.Debug("m.createStandardReader error: ", err)
+		_ = oldReader.Close()
+		return nil, nil, err
+	}
+	return oldReader, newReader, nil
+}
+
+// diffFields returns the fields to compare, the fields of dp_fields without rules, or DefaultFields.
+func (m *Manager) diffFields() []string {
+	fields := m.Conf.DPFields
+	if len(fields) == 0 || fields == operate.SelectorWildcardArg {
+		fields = DefaultFields
+	}
+	fields = strings.SplitN(fields, operate.SelectorGroupSep, 2)[0]
+	return strings.Split(fields, operate.SelectorFieldSep)
+}
+
+// diffOutput writes the result of a diff.
+type diffOutput interface {
+
+	// Begin starts the output of the diff of the files.
+	Begin(file []string, fields []string) error
+
+	// Range writes a differing range.
+	Range(r *ipio.DiffRange) error
+
+	// End writes the statistics and finishes the output.
+	End(stats *ipio.DiffStats) error
+}
+
+// diffTextOutput writes the differing ranges as lines of changed fields, followed by summary tables.
+type diffTextOutput struct {
+	w      io.Writer
+	fields []string
+}
+
+func (o *diffTextOutput) Begin(file []string, fields []string) error {
+	o.fields = fields
+	_, err := fmt.Fprintf(o.w, "--- %s\n+++ %s\n", file[0], file[1])
+	return err
+}
+
+func (o *diffTextOutput) Range(r *ipio.DiffRange) error {
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "%s - %s", r.IPNet.Start, r.IPNet.End)
+	for i, field := range o.fields {
+		if r.Old[i] != r.New[i] {
+			fmt.Fprintf(buf, "\t%s: %q -> %q", field, r.Old[i], r.New[i])
+		}
+	}
+	buf.WriteString("\n")
+	_, err := io.WriteString(o.w, buf.String())
+	return err
+}
+
+func (o *diffTextOutput) End(stats *ipio.DiffStats) error {
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "\nAddresses: %s, changed: %s (%.4f%%), ranges: %d\n",
+		stats.Addresses, stats.Changed, stats.Share*100, stats.Ranges)
+
+	table := tablewriter.NewWriter(buf)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Field", "Changed", "Share"})
+	for _, f := range stats.Fields {
+		table.Append([]string{f.Field, f.Changed.String(), fmt.Sprintf("%.4f%%", f.Share*100)})
+	}
+	table.Render()
+
+	table = tablewriter.NewWriter(buf)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Field", "Old", "New", "Addresses"})
+	for _, f := range stats.Fields {
+		for _, c := range f.Top {
+			table.Append([]string{f.Field, c.Old, c.New, c.Addresses.String()})
+		}
+	}
+	if table.NumLines() > 0 {
+		buf.WriteString("\nTop changes:\n")
+		table.Render()
+	}
+
+	_, err := io.WriteString(o.w, buf.String())
+	return err
+}
+
+// diffCSVOutput writes the differing ranges as CSV records.
+type diffCSVOutput struct {
+	w *csv.Writer
+}
+
+func (o *diffCSVOutput) Begin(file []string, fields []string) error {
+	header := []string{"start", "end", "changed"}
+	for _, field := range fields {
+		header = append(header, "old_"+field, "new_"+field)
+	}
+	return o.w.Write(header)
+}
+
+func (o *diffCSVOutput) Range(r *ipio.DiffRange) error {
+	record := []string{r.IPNet.Start.String(), r.IPNet.End.String(), strings.Join(r.Changed, ";")}
+	for i := range r.Old {
+		record = append(record, r.Old[i], r.New[i])
+	}
+	return o.w.Write(record)
+}
+
+func (o *diffCSVOutput) End(*ipio.DiffStats) error {
+	o.w.Flush()
+	return o.w.Error()
+}
+
+// diffJSONOutput writes the differing ranges and the statistics as a JSON object.
+type diffJSONOutput struct {
+	w      io.Writer
+	indent bool
+	fields []string
+	result diffResult
+}
+
+// diffResult is the JSON output of a diff.
+type diffResult struct {
+	Old    string          `json:"old"`
+	New    string          `json:"new"`
+	Fields []string        `json:"fields"`
+	Ranges []diffRange     `json:"ranges"`
+	Stats  *ipio.DiffStats `json:"stats"`
+}
+
+// diffRange is a differing range in the JSON output of a diff.
+type diffRange struct {
+	Start   string            `json:"start"`
+	End     string            `json:"end"`
+	Changed []string          `json:"changed"`
+	Old     map[string]string `json:"old"`
+	New     map[string]string `json:"new"`
+}
+
+func (o *diffJSONOutput) Begin(file []string, fields []string) error {
+	o.fields = fields
+	o.result = diffResult{Old: file[0], New: file[1], Fields: fields, Ranges: []diffRange{}}
+	return nil
+}
+
+func (o *diffJSONOutput) Range(r *ipio.DiffRange) error {
+	dr := diffRange{
+		Start:   r.IPNet.Start.String(),
+		End:     r.IPNet.End.String(),
+		Changed: r.Changed,
+		Old:     make(map[string]string, len(o.fields)),
+		New:     make(map[string]string, len(o.fields)),
+	}
+	for i, field := range o.fields {
+		dr.Old[field], dr.New[field] = r.Old[i], r.New[i]
+	}
+	o.result.Ranges = append(o.result.Ranges, dr)
+	return nil
+}
+
+func (o *diffJSONOutput) End(stats *ipio.DiffStats) error {
+	o.result.Stats = stats
+	encoder := json.NewEncoder(o.w)
+	encoder.SetEscapeHTML(false)
+	if o.indent {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(o.result)
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"bufio"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// The field alias file maps database fields to common fields, for databases whose
+// field names are not known by their format. Each line is formatted as: <database>\t<mappings>
+// @ <database> - a format name, a database file name or path, or "*" for all databases
+// @ <mappings> - database field to common field mappings, in `url.Values` format
+// Lines starting with "#" are comments. Later lines override earlier ones.
+// Example:
+//	# map the owner field of awdb databases to isp
+//	awdb	owner=isp
+//	# map the fields of a custom mmdb database
+//	custom.mmdb	org=isp&cc=countryCode
+
+const (
+	// FieldAliasAll matches all databases in the field alias file.
+	FieldAliasAll = "*"
+
+	fieldAliasSep     = "\t"
+	fieldAliasComment = "#"
+)
+
+// fieldAlias returns the user-defined aliases of the database file in the format,
+// mapping common fields to database fields.
+func (m *Manager) fieldAlias(_format, file string) (map[string]string, error) {
+	if len(m.Conf.FieldAliasFile) == 0 {
+		return nil, nil
+	}
+
+	f, err := os.Open(m.Conf.FieldAliasFile)
+	if err != nil {
+		log.Debug("open field alias file error: ", err)
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	ret := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, fieldAliasComment) {
+			continue
+		}
+		split := strings.SplitN(line, fieldAliasSep, 2)
+		if len(split) != 2 {
+			log.Debugf("invalid field alias line: %s", line)
+			return nil, errors.ErrInvalidFieldAlias
+		}
+
+		database := strings.TrimSpace(split[0])
+		if database != FieldAliasAll && database != _format && database != file && database != filepath.Base(file) {
+			continue
+		}
+
+		mappings, err := url.ParseQuery(strings.TrimSpace(split[1]))
+		if err != nil {
+			log.Debugf("parse field alias [%s] error: %v", split[1], err)
+			return nil, err
+		}
+		for dbField, commonFields := range mappings {
+			commonField := commonFields[len(commonFields)-1]
+			if !isCommonField(commonField) {
+				log.Debugf("invalid common field: %s", commonField)
+				return nil, errors.ErrFieldInvalid
+			}
+			for c, d := range ret {
+				if d == dbField {
+					delete(ret, c)
+				}
+			}
+			ret[commonField] = dbField
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// isCommonField checks if the field is a common field.
+func isCommonField(field string) bool {
+	for _, f := range model.CommonFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
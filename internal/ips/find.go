@@ -18,17 +18,20 @@ package ips
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net"
-	"net/url"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sjzar/ips/domainlist"
 	"github.com/sjzar/ips/format"
-	"github.com/sjzar/ips/format/mmdb"
+	"github.com/sjzar/ips/format/option"
 	"github.com/sjzar/ips/format/qqwry"
 	"github.com/sjzar/ips/internal/data"
 	"github.com/sjzar/ips/internal/ipio"
@@ -42,17 +45,18 @@ import (
 // ParseText takes a text input, parses it into segments, and returns the serialized result
 // based on the Manager configuration. It returns the combined result as a string.
 func (m *Manager) ParseText(text string) (string, error) {
+	return m.ParseTextContext(context.Background(), text)
+}
+
+// ParseTextContext is like ParseText but the IP lookups honor ctx.
+func (m *Manager) ParseTextContext(ctx context.Context, text string) (string, error) {
 
 	tp := parser.NewTextParser(text).Parse()
 
-	infoList := make([]interface{}, 0, len(tp.Segments))
-	for _, segment := range tp.Segments {
-		info, err := m.parseSegment(segment)
-		if err != nil {
-			log.Debug("m.parseSegment error: ", err)
-			return "", err
-		}
-		infoList = append(infoList, info)
+	infoList, err := m.parseSegments(ctx, tp.Segments)
+	if err != nil {
+		log.Debug("m.parseSegments error: ", err)
+		return "", err
 	}
 
 	result, err := m.serialize(infoList)
@@ -66,10 +70,10 @@ func (m *Manager) ParseText(text string) (string, error) {
 
 // parseSegment processes the provided segment and returns the corresponding data.
 // This could be IP information, domain information, or raw text.
-func (m *Manager) parseSegment(segment parser.Segment) (interface{}, error) {
+func (m *Manager) parseSegment(ctx context.Context, segment parser.Segment) (interface{}, error) {
 	switch segment.Type {
 	case parser.TextTypeIPv4, parser.TextTypeIPv6:
-		return m.parseIP(segment.Content)
+		return m.parseIP(ctx, segment.Content)
 	case parser.TextTypeDomain:
 		return m.parseDomain(segment.Content)
 	case parser.TextTypeText:
@@ -78,46 +82,158 @@ func (m *Manager) parseSegment(segment parser.Segment) (interface{}, error) {
 	return nil, nil
 }
 
+// parseSegments processes the provided segments and returns the corresponding data in the same order.
+// IP addresses are looked up together with FindBatch.
+func (m *Manager) parseSegments(ctx context.Context, segments []parser.Segment) ([]interface{}, error) {
+	ips := make([]net.IP, 0, len(segments))
+	for _, segment := range segments {
+		switch segment.Type {
+		case parser.TextTypeIPv4, parser.TextTypeIPv6:
+			ip := net.ParseIP(segment.Content)
+			if ip == nil {
+				return nil, errors.ErrInvalidIP
+			}
+			ips = append(ips, ip)
+		}
+	}
+
+	ipInfos, err := m.FindBatchContext(ctx, ips)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]interface{}, 0, len(segments))
+	for _, segment := range segments {
+		switch segment.Type {
+		case parser.TextTypeIPv4, parser.TextTypeIPv6:
+			ret = append(ret, ipInfos[0])
+			ipInfos = ipInfos[1:]
+		default:
+			info, err := m.parseSegment(ctx, segment)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, info)
+		}
+	}
+
+	return ret, nil
+}
+
 // parseIP determines the type of IP (IPv4 or IPv6) and fetches the corresponding information.
-func (m *Manager) parseIP(content string) (*model.IPInfo, error) {
+func (m *Manager) parseIP(ctx context.Context, content string) (*model.IPInfo, error) {
 	if ip := net.ParseIP(content); ip != nil {
 		if ip.To4() != nil {
-			return m.parseIPv4(ip)
+			return m.parseIPv4(ctx, ip)
 		}
-		return m.parseIPv6(ip)
+		return m.parseIPv6(ctx, ip)
 	}
 
 	return nil, errors.ErrInvalidIP
 }
 
 // parseIPv4 finds and returns the information associated with the provided IPv4 address.
-func (m *Manager) parseIPv4(ip net.IP) (*model.IPInfo, error) {
+func (m *Manager) parseIPv4(ctx context.Context, ip net.IP) (*model.IPInfo, error) {
+	reader, err := m.ipv4Reader()
+	if err != nil {
+		return nil, err
+	}
+
+	return ipio.FindContext(ctx, reader, ip)
+}
+
+// parseIPv6 finds and returns the information associated with the provided IPv6 address.
+func (m *Manager) parseIPv6(ctx context.Context, ip net.IP) (*model.IPInfo, error) {
+	reader, err := m.ipv6Reader()
+	if err != nil {
+		return nil, err
+	}
+
+	return ipio.FindContext(ctx, reader, ip)
+}
 
-	// lazyLoad initializes the IP readers if they haven't been initialized yet.
+// ipv4Reader returns the IPv4 reader, creating it on first use.
+func (m *Manager) ipv4Reader() (format.Reader, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.ipv4 == nil {
 		var err error
-		if m.ipv4, err = m.createReader(m.Conf.IPv4Format, m.Conf.IPv4File, false); err != nil {
-			log.Debug("createReader error: ", err)
+		if m.ipv4, err = m.createQueryReader(m.Conf.IPv4Format, m.Conf.IPv4File); err != nil {
+			log.Debug("createQueryReader error: ", err)
 			return nil, err
 		}
 	}
-
-	return m.ipv4.Find(ip)
+	return m.ipv4, nil
 }
 
-// parseIPv6 finds and returns the information associated with the provided IPv6 address.
-func (m *Manager) parseIPv6(ip net.IP) (*model.IPInfo, error) {
-
-	// lazyLoad initializes the IP readers if they haven't been initialized yet.
+// ipv6Reader returns the IPv6 reader, creating it on first use.
+func (m *Manager) ipv6Reader() (format.Reader, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.ipv6 == nil {
 		var err error
-		if m.ipv6, err = m.createReader(m.Conf.IPv6Format, m.Conf.IPv6File, false); err != nil {
-			log.Debug("createReader error: ", err)
+		if m.ipv6, err = m.createQueryReader(m.Conf.IPv6Format, m.Conf.IPv6File); err != nil {
+			log.Debug("createQueryReader error: ", err)
 			return nil, err
 		}
 	}
+	return m.ipv6, nil
+}
 
-	return m.ipv6.Find(ip)
+// FindBatch finds the information associated with a batch of IP addresses and returns it in the same order.
+// The addresses are sorted before the lookup, so addresses sharing a range are resolved only once.
+func (m *Manager) FindBatch(ips []net.IP) ([]*model.IPInfo, error) {
+	return m.FindBatchContext(context.Background(), ips)
+}
+
+// FindBatchContext is like FindBatch but the lookups honor ctx.
+func (m *Manager) FindBatchContext(ctx context.Context, ips []net.IP) ([]*model.IPInfo, error) {
+	ret := make([]*model.IPInfo, len(ips))
+
+	var ipv4, ipv6 []net.IP
+	var ipv4Index, ipv6Index []int
+	for i, ip := range ips {
+		switch {
+		case ip.To4() != nil:
+			ipv4 = append(ipv4, ip)
+			ipv4Index = append(ipv4Index, i)
+		case ip.To16() != nil:
+			ipv6 = append(ipv6, ip)
+			ipv6Index = append(ipv6Index, i)
+		default:
+			return nil, errors.ErrInvalidIP
+		}
+	}
+
+	if len(ipv4) > 0 {
+		reader, err := m.ipv4Reader()
+		if err != nil {
+			return nil, err
+		}
+		infos, err := ipio.FindBatchContext(ctx, reader, ipv4)
+		if err != nil {
+			return nil, err
+		}
+		for i, info := range infos {
+			ret[ipv4Index[i]] = info
+		}
+	}
+
+	if len(ipv6) > 0 {
+		reader, err := m.ipv6Reader()
+		if err != nil {
+			return nil, err
+		}
+		infos, err := ipio.FindBatchContext(ctx, reader, ipv6)
+		if err != nil {
+			return nil, err
+		}
+		for i, info := range infos {
+			ret[ipv6Index[i]] = info
+		}
+	}
+
+	return ret, nil
 }
 
 // parseDomain fetches the information for the given domain. Implementation is pending.
@@ -237,20 +353,148 @@ func (m *Manager) serializeDataToJSON(values *model.DataList) (string, error) {
 // createReader decides whether to create a standard or hybrid reader based on the number of files provided.
 // It creates a standard reader for a single file and a hybrid reader for multiple files.
 func (m *Manager) createReader(_format, file []string, isPackMode bool) (format.Reader, error) {
+	if err := m.checkReaderOption(_format, file); err != nil {
+		return nil, err
+	}
+
+	var reader format.Reader
+	var err error
 	if len(file) == 1 {
-		return m.createStandardReader(_format[0], file[0], isPackMode)
+		reader, err = m.createStandardReader(_format[0], file[0], isPackMode)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		reader, err = m.createHybridReader(_format, file, isPackMode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	overlay, err := m.overlay(reader, isPackMode)
+	if err != nil {
+		_ = reader.Close()
+		return nil, err
+	}
+	return overlay, nil
+}
+
+// overlay lays the overlay file over the reader, if one is configured.
+// If the fields of the reader are selected, the overlay is limited to the selected fields,
+// otherwise the fields of the overlay the reader does not have are added.
+func (m *Manager) overlay(reader format.Reader, isPackMode bool) (format.Reader, error) {
+	file, fields, selected := m.Conf.Overlay, m.Conf.OverlayFields, m.Conf.Fields
+	if isPackMode {
+		file, fields, selected = m.Conf.DPOverlay, m.Conf.DPOverlayFields, m.Conf.DPFields
+	}
+	if len(file) == 0 {
+		return reader, nil
+	}
+
+	overlay, err := ipio.LoadOverlay(file)
+	if err != nil {
+		log.Debug("ipio.LoadOverlay error: ", err)
+		return nil, err
+	}
+	if len(fields) > 0 {
+		if overlay, err = overlay.Select(strings.Split(fields, operate.SelectorFieldSep)); err != nil {
+			return nil, err
+		}
 	}
-	return m.createHybridReader(_format, file, isPackMode)
+	if len(selected) > 0 && selected != operate.SelectorWildcardArg {
+		meta := reader.Meta()
+		var kept []string
+		for _, field := range overlay.Fields {
+			if slices.Contains(meta.Fields, field) || slices.Contains(meta.Fields, meta.FieldAlias[field]) {
+				kept = append(kept, field)
+			}
+		}
+		if len(kept) == 0 {
+			log.Debug("the overlay has none of the selected fields")
+			return reader, nil
+		}
+		if overlay, err = overlay.Select(kept); err != nil {
+			return nil, err
+		}
+	}
+
+	return ipio.NewOverlayReader(reader, overlay.Fields, overlay.Ranges)
+}
+
+// checkReaderOption validates the reader options against the options of the formats of the database files,
+// before the files are opened. An option is accepted if any of the formats supports it,
+// so options can be shared by the readers of a hybrid reader.
+// If the format of a file has no name, the options of all formats are accepted.
+func (m *Manager) checkReaderOption(_format, file []string) error {
+	if len(m.Conf.ReaderOption) == 0 {
+		return nil
+	}
+
+	var specs option.Specs
+	add := func(opts format.Options) {
+		for _, spec := range opts.Specs {
+			if _, ok := specs.Get(spec.Name); !ok {
+				specs = append(specs, spec)
+			}
+		}
+	}
+	for i := range file {
+		var f string
+		if i < len(_format) {
+			f = _format[i]
+		}
+		name := format.ReaderFormat(f, file[i])
+		if len(name) == 0 {
+			for _, opts := range format.ReaderOptions {
+				add(opts)
+			}
+			break
+		}
+		add(format.ReaderOptions[name])
+	}
+	if _, err := specs.Parse(m.Conf.ReaderOption); err != nil {
+		log.Debug("checkReaderOption error: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// createQueryReader creates the reader used for queries.
+// It is wrapped in a CachedReader if cache_size is set.
+func (m *Manager) createQueryReader(_format, file []string) (format.Reader, error) {
+	reader, err := m.createReader(_format, file, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Conf.CacheSize > 0 {
+		reader = ipio.NewCachedReader(reader, m.Conf.CacheSize, time.Duration(m.Conf.CacheTTLS)*time.Second)
+	}
+
+	return reader, nil
 }
 
 // createHybridReader constructs a hybrid reader using multiple IP database formats and files.
 // It handles reader creation for each database file and aggregates them into a single hybrid reader.
 func (m *Manager) createHybridReader(_format, file []string, isPackMode bool) (format.Reader, error) {
+	priority, err := parseHybridPriority(m.Conf.HybridPriority)
+	if err != nil {
+		return nil, err
+	}
+	weights, err := parseHybridWeights(m.Conf.HybridWeights)
+	if err != nil {
+		return nil, err
+	}
+
 	readers := make([]format.Reader, 0, len(file))
 	for i := range file {
 		reader, err := m.createStandardReader(_format[i], file[i], isPackMode)
 		if err != nil {
 			log.Debug("createStandardReader error: ", err)
+			for _, reader := range readers {
+				_ = reader.Close()
+			}
 			return nil, err
 		}
 		readers = append(readers, reader)
@@ -259,28 +503,47 @@ func (m *Manager) createHybridReader(_format, file []string, isPackMode bool) (f
 	reader, err := ipio.NewHybridReader(nil, readers...)
 	if err != nil {
 		log.Debug("ipio.NewHybridReader error: ", err)
+		for _, reader := range readers {
+			_ = reader.Close()
+		}
 		return nil, err
 	}
 
-	if len(m.Conf.HybridMode) != 0 {
-		if err := reader.SetOption(ipio.HybridReaderOption{
-			Mode: m.Conf.HybridMode,
-		}); err != nil {
-			log.Debug("reader.SetOption error: ", err)
-			return nil, err
-		}
+	files := make([]string, 0, len(file))
+	for _, f := range file {
+		files = append(files, filepath.Base(f))
+	}
+	if err := reader.SetOption(ipio.HybridReaderOption{
+		Mode:     m.Conf.HybridMode,
+		Timeout:  time.Duration(m.Conf.HybridTimeoutMs) * time.Millisecond,
+		Files:    files,
+		Priority: priority,
+		Weights:  weights,
+		TieBreak: m.Conf.HybridTieBreak,
+	}); err != nil {
+		log.Debug("reader.SetOption error: ", err)
+		_ = reader.Close()
+		return nil, err
 	}
 
 	if m.Conf.HybridMode != ipio.HybridComparisonMode {
 		fs, err := m.newFieldSelector(reader.Meta(), isPackMode)
 		if err != nil {
+			_ = reader.Close()
 			return nil, err
 		}
 		reader.OperateChain.Use(fs.Do)
+
+		if err := reader.CheckPriority(); err != nil {
+			log.Debug("reader.CheckPriority error: ", err)
+			_ = reader.Close()
+			return nil, err
+		}
 	}
 
 	rw, err := m.newDataRewriter(isPackMode)
 	if err != nil {
+		_ = reader.Close()
 		return nil, err
 	}
 	reader.OperateChain.Use(rw.Do)
@@ -289,14 +552,66 @@ func (m *Manager) createHybridReader(_format, file []string, isPackMode bool) (f
 		tl, err := operate.NewTranslator(m.Conf.Lang)
 		if err != nil {
 			log.Debug("operate.NewTranslator error: ", err)
+			_ = reader.Close()
 			return nil, err
 		}
 		reader.OperateChain.Use(tl.Do)
 	}
 
+	if isPackMode && m.Conf.DPSources {
+		reader.OperateChain.Use(operate.NewSourceFields(reader.Meta()).Do)
+	}
+
 	return reader, nil
 }
 
+// parseHybridPriority parses the databases of each field, e.g. "country=1,0;isp=2,0".
+func parseHybridPriority(str string) (map[string][]int, error) {
+	if len(str) == 0 {
+		return nil, nil
+	}
+	priority := make(map[string][]int)
+	for _, item := range strings.Split(str, ";") {
+		if len(strings.TrimSpace(item)) == 0 {
+			continue
+		}
+		split := strings.SplitN(item, "=", 2)
+		field := strings.TrimSpace(split[0])
+		if len(split) != 2 || len(field) == 0 {
+			return nil, errors.ErrInvalidHybridPriority
+		}
+		indexes := make([]int, 0)
+		for _, s := range strings.Split(split[1], ",") {
+			index, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				log.Debug("strconv.Atoi error: ", err)
+				return nil, errors.ErrInvalidHybridPriority
+			}
+			indexes = append(indexes, index)
+		}
+		priority[field] = indexes
+	}
+	return priority, nil
+}
+
+// parseHybridWeights parses the voting weights of the databases, separated by commas.
+func parseHybridWeights(str string) ([]float64, error) {
+	if len(str) == 0 {
+		return nil, nil
+	}
+	split := strings.Split(str, ",")
+	weights := make([]float64, 0, len(split))
+	for _, s := range split {
+		weight, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			log.Debug("strconv.ParseFloat error: ", err)
+			return nil, errors.ErrInvalidWeights
+		}
+		weights = append(weights, weight)
+	}
+	return weights, nil
+}
+
 // createStandardReader sets up and returns an IP reader based on the specified format and file.
 // It includes additional processing for field selection and data rewriting based on the configuration.
 func (m *Manager) createStandardReader(_format, file string, isPackMode bool) (format.Reader, error) {
@@ -307,14 +622,28 @@ func (m *Manager) createStandardReader(_format, file string, isPackMode bool) (f
 
 	reader := ipio.NewStandardReader(dbr, nil)
 
+	fieldAlias, err := m.fieldAlias(dbr.Meta().Format, file)
+	if err != nil {
+		_ = dbr.Close()
+		return nil, err
+	}
+	if len(fieldAlias) > 0 {
+		if err := reader.SetOption(ipio.StandardReaderOption{FieldAlias: fieldAlias}); err != nil {
+			_ = dbr.Close()
+			return nil, err
+		}
+	}
+
 	fs, err := m.newFieldSelector(reader.Meta(), isPackMode)
 	if err != nil {
+		_ = dbr.Close()
 		return nil, err
 	}
 	reader.OperateChain.Use(fs.Do)
 
 	rw, err := m.newDataRewriter(isPackMode)
 	if err != nil {
+		_ = dbr.Close()
 		return nil, err
 	}
 
@@ -328,10 +657,16 @@ func (m *Manager) createStandardReader(_format, file string, isPackMode bool) (f
 
 	reader.OperateChain.Use(rw.Do)
 
+	// derived fields are only filled in queries, packed and dumped data stay as in the database
+	if !isPackMode {
+		reader.OperateChain.Use(operate.NewFieldDeriver().Do)
+	}
+
 	if len(m.Conf.Lang) != 0 {
 		tl, err := operate.NewTranslator(m.Conf.Lang)
 		if err != nil {
 			log.Debug("operate.NewTranslator error: ", err)
+			_ = dbr.Close()
 			return nil, err
 		}
 		reader.OperateChain.Use(tl.Do)
@@ -359,25 +694,39 @@ func (m *Manager) createDatabaseReader(_format, file string) (format.Reader, err
 		file = fullpath
 	}
 
+	// options are validated against all readers by checkReaderOption
+	if name := format.ReaderFormat(_format, file); name != "" {
+		option, err := format.ParseReaderOptionKnown(name, m.Conf.ReaderOption)
+		if err != nil {
+			log.Debug("format.ParseReaderOptionKnown error: ", err)
+			return nil, err
+		}
+		// options such as mmap take effect while the file is loaded
+		dbr, err := format.NewReaderWithOption(_format, file, option)
+		if err != nil {
+			log.Debug("format.NewReaderWithOption error: ", _format, file, err)
+			return nil, err
+		}
+		return dbr, nil
+	}
+
 	dbr, err := format.NewReader(_format, file)
 	if err != nil {
 		log.Debug("format.NewReader error: ", _format, file, err)
 		return nil, err
 	}
 
-	switch dbr.(type) {
-	case *mmdb.Reader:
-		readerOptionArg, err := url.ParseQuery(m.Conf.ReaderOption)
-		if err != nil {
-			log.Debug("url.ParseQuery error: ", err)
-			return nil, err
-		}
-		option := mmdb.ReaderOption{
-			DisableExtraData: readerOptionArg.Get("disable_extra_data") == "true",
-			UseFullField:     readerOptionArg.Get("use_full_field") == "true",
-		}
+	// the format is only known once the file is opened
+	option, err := format.ParseReaderOptionKnown(dbr.Meta().Format, m.Conf.ReaderOption)
+	if err != nil {
+		log.Debug("format.ParseReaderOptionKnown error: ", err)
+		_ = dbr.Close()
+		return nil, err
+	}
+	if option != nil {
 		if err := dbr.SetOption(option); err != nil {
 			log.Debug("reader.SetOption error: ", err)
+			_ = dbr.Close()
 			return nil, err
 		}
 	}
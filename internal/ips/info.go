@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// Info opens the database files and returns their metadata.
+// Multiple files are combined as a hybrid reader. Formats are detected by file name if _format is empty.
+func (m *Manager) Info(_format, file []string) (*model.Meta, error) {
+	if len(_format) == 0 {
+		_format = make([]string, len(file))
+	} else if len(file) != len(_format) {
+		return nil, errors.ErrInvalidFormat
+	}
+
+	reader, err := m.createReader(_format, file, true)
+	if err != nil {
+		log.Debug("m.createReader error: ", err)
+		return nil, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	return reader.Meta(), nil
+}
+
+// InfoFormatTable formats the metadata into a readable table string.
+func (m *Manager) InfoFormatTable(meta *model.Meta) string {
+	writer := &strings.Builder{}
+	table := tablewriter.NewWriter(writer)
+	table.SetAutoWrapText(false)
+
+	ipVersion := make([]string, 0, 2)
+	if meta.IsIPv4Support() {
+		ipVersion = append(ipVersion, "IPv4")
+	}
+	if meta.IsIPv6Support() {
+		ipVersion = append(ipVersion, "IPv6")
+	}
+
+	buildTime := ""
+	if !meta.BuildTime.IsZero() {
+		buildTime = meta.BuildTime.Format(time.RFC3339)
+	}
+
+	table.AppendBulk([][]string{
+		{"Format", meta.Format},
+		{"IP Version", strings.Join(ipVersion, ",")},
+		{"Fields", strings.Join(meta.Fields, ",")},
+		{"Build Time", buildTime},
+		{"Version", meta.Version},
+		{"Description", meta.Description},
+		{"Languages", strings.Join(meta.Languages, ",")},
+		{"Source", meta.Source},
+		{"License", meta.License},
+	})
+	table.Render()
+
+	return writer.String()
+}
+
+// InfoText returns the metadata of the database files formatted as a table.
+func (m *Manager) InfoText(_format, file []string) (string, error) {
+	meta, err := m.Info(_format, file)
+	if err != nil {
+		return "", err
+	}
+	return m.InfoFormatTable(meta), nil
+}
@@ -17,6 +17,8 @@
 package ips
 
 import (
+	"sync"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/sjzar/ips/format"
@@ -28,6 +30,8 @@ type Manager struct {
 	Conf *Config
 
 	// IPv4 and IPv6 are the IP readers for their respective IP versions.
+	// They are created on first use, guarded by mu.
+	mu   sync.Mutex
 	ipv4 format.Reader
 	ipv6 format.Reader
 
@@ -43,3 +47,21 @@ func NewManager(conf *Config) *Manager {
 		Conf: conf,
 	}
 }
+
+// Close closes the IP readers opened by the Manager.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var err error
+	for _, reader := range []format.Reader{m.ipv4, m.ipv6} {
+		if reader == nil {
+			continue
+		}
+		if e := reader.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	m.ipv4, m.ipv6 = nil, nil
+	return err
+}
@@ -81,7 +81,7 @@ func (m *Manager) MDNSFormatTable(ret *MdnsResponse) string {
 		}
 		for i := range item.Result {
 			ipMap[item.Result[i]] = struct{}{}
-			if info, err := m.parseIP(item.Result[i]); err == nil {
+			if info, err := m.parseIP(context.Background(), item.Result[i]); err == nil {
 				if text, err := m.serializeIPInfoToText(info); err == nil {
 					item.Result[i] = text
 				}
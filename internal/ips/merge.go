@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"context"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/internal/ipio"
+	"github.com/sjzar/ips/internal/util"
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+// Merge combines database files covering different address families or disjoint ranges,
+// e.g. an IPv4 and an IPv6 database, into one database, and writes it to outputFile,
+// or standard output if outputFile is empty.
+// The fields of the databases are unified by their common field names, and fields a database does not have are left empty.
+func (m *Manager) Merge(_format, file []string, _outputFormat, outputFile string) error {
+	return m.MergeContext(context.Background(), _format, file, _outputFormat, outputFile)
+}
+
+// MergeContext is like Merge but stops with ctx.Err() once ctx is done.
+// The output file is only created if merging succeeds.
+func (m *Manager) MergeContext(ctx context.Context, _format, file []string, _outputFormat, outputFile string) error {
+
+	reader, writer, err := m.createMergeReaderWriter(_format, file, _outputFormat, outputFile)
+	if err != nil {
+		return err
+	}
+
+	if len(outputFile) == 0 {
+		return m.pack(ctx, reader, writer, os.Stdout)
+	}
+
+	if err := util.WriteFileAtomic(outputFile, func(w io.Writer) error {
+		return m.pack(ctx, reader, writer, w)
+	}); err != nil {
+		log.Debug("util.WriteFileAtomic error: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// MergeTo is like Merge but writes the merged database to w in the given output format.
+func (m *Manager) MergeTo(_format, file []string, _outputFormat string, w io.Writer) error {
+	return m.MergeToContext(context.Background(), _format, file, _outputFormat, w)
+}
+
+// MergeToContext is like MergeTo but stops with ctx.Err() once ctx is done.
+func (m *Manager) MergeToContext(ctx context.Context, _format, file []string, _outputFormat string, w io.Writer) error {
+
+	reader, writer, err := m.createMergeReaderWriter(_format, file, _outputFormat, "")
+	if err != nil {
+		return err
+	}
+
+	return m.pack(ctx, reader, writer, w)
+}
+
+// createMergeReaderWriter creates the merge reader of the database files and the writer of the output.
+func (m *Manager) createMergeReaderWriter(_format, file []string, _outputFormat, outputFile string) (format.Reader, format.Writer, error) {
+
+	if len(_format) == 0 {
+		_format = make([]string, len(file))
+	} else if len(file) != len(_format) {
+		return nil, nil, errors.ErrInvalidFormat
+	}
+
+	reader, err := m.createMergeReader(_format, file)
+	if err != nil {
+		log.Debug("m.createMergeReader error: ", err)
+		return nil, nil, err
+	}
+
+	writer, err := format.NewWriter(_outputFormat, outputFile, reader.Meta())
+	if err != nil {
+		log.Debug("format.NewWriter error: ", err)
+		_ = reader.Close()
+		return nil, nil, err
+	}
+
+	return reader, writer, nil
+}
+
+// createMergeReader creates a reader merging the database files.
+// The databases are read with all their fields, dp_fields selects the merged fields,
+// so fields only some of the databases have can be selected.
+func (m *Manager) createMergeReader(_format, file []string) (format.Reader, error) {
+	if err := m.checkReaderOption(_format, file); err != nil {
+		return nil, err
+	}
+
+	conf := *m.Conf
+	conf.DPFields = ""
+	sm := NewManager(&conf)
+
+	readers := make([]format.Reader, 0, len(file))
+	closeReaders := func() {
+		for _, reader := range readers {
+			_ = reader.Close()
+		}
+	}
+	for i := range file {
+		reader, err := sm.createStandardReader(_format[i], file[i], true)
+		if err != nil {
+			log.Debug("createStandardReader error: ", err)
+			closeReaders()
+			return nil, err
+		}
+		readers = append(readers, reader)
+	}
+
+	merged, err := ipio.NewMergeReader(readers...)
+	if err != nil {
+		log.Debug("ipio.NewMergeReader error: ", err)
+		closeReaders()
+		return nil, err
+	}
+
+	reader := ipio.NewStandardReader(merged, nil)
+	fs, err := m.newFieldSelector(reader.Meta(), true)
+	if err != nil {
+		_ = reader.Close()
+		return nil, err
+	}
+	reader.OperateChain.Use(fs.Do)
+
+	overlay, err := m.overlay(reader, true)
+	if err != nil {
+		_ = reader.Close()
+		return nil, err
+	}
+	return overlay, nil
+}
@@ -17,86 +17,111 @@
 package ips
 
 import (
-	"net/url"
+	"context"
+	"io"
 	"os"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sjzar/ips/format"
-	"github.com/sjzar/ips/format/mmdb"
-	"github.com/sjzar/ips/format/plain"
 	"github.com/sjzar/ips/internal/ipio"
+	"github.com/sjzar/ips/internal/util"
 	"github.com/sjzar/ips/pkg/errors"
 )
 
 // Pack reads data from a database file, processes it, and writes it to an output.
+// The output is written to standard output if outputFile is empty.
 func (m *Manager) Pack(_format, file []string, _outputFormat, outputFile string) error {
+	return m.PackContext(context.Background(), _format, file, _outputFormat, outputFile)
+}
+
+// PackContext is like Pack but stops with ctx.Err() once ctx is done.
+// The output file is only created if packing succeeds, an interrupted pack leaves no partial file.
+func (m *Manager) PackContext(ctx context.Context, _format, file []string, _outputFormat, outputFile string) error {
+
+	reader, writer, err := m.createPackReaderWriter(_format, file, _outputFormat, outputFile)
+	if err != nil {
+		return err
+	}
+
+	if len(outputFile) == 0 {
+		return m.pack(ctx, reader, writer, os.Stdout)
+	}
+
+	if err := util.WriteFileAtomic(outputFile, func(w io.Writer) error {
+		return m.pack(ctx, reader, writer, w)
+	}); err != nil {
+		log.Debug("util.WriteFileAtomic error: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// PackTo reads data from a database file, processes it, and writes it to w in the given output format.
+func (m *Manager) PackTo(_format, file []string, _outputFormat string, w io.Writer) error {
+	return m.PackToContext(context.Background(), _format, file, _outputFormat, w)
+}
+
+// PackToContext is like PackTo but stops with ctx.Err() once ctx is done.
+func (m *Manager) PackToContext(ctx context.Context, _format, file []string, _outputFormat string, w io.Writer) error {
+
+	reader, writer, err := m.createPackReaderWriter(_format, file, _outputFormat, "")
+	if err != nil {
+		return err
+	}
+
+	return m.pack(ctx, reader, writer, w)
+}
+
+// createPackReaderWriter creates the reader of the database files and the writer of the output for packing.
+func (m *Manager) createPackReaderWriter(_format, file []string, _outputFormat, outputFile string) (format.Reader, format.Writer, error) {
 
 	if len(_format) == 0 {
 		_format = make([]string, len(file))
 	} else if len(file) != len(_format) {
-		return errors.ErrInvalidFormat
+		return nil, nil, errors.ErrInvalidFormat
 	}
 
 	reader, err := m.createReader(_format, file, true)
 	if err != nil {
 		log.Debug("m.createReader error: ", err)
-		return err
+		return nil, nil, err
 	}
 
 	// Setup the writer
 	writer, err := format.NewWriter(_outputFormat, outputFile, reader.Meta())
 	if err != nil {
 		log.Debug("format.NewWriter error: ", err)
-		return err
+		_ = reader.Close()
+		return nil, nil, err
 	}
 
-	// Setup output destination
-	output := os.Stdout
-	if len(outputFile) != 0 {
-		var err error
-		output, err = os.Create(outputFile)
-		if err != nil {
-			log.Debug("os.Create error: ", err)
-			return err
-		}
-		defer func() {
-			_ = output.Close()
-		}()
-	}
+	return reader, writer, nil
+}
 
-	// Add specific logic based on the writer type
-	switch writer.(type) {
-	case *mmdb.Writer:
-		writerOptionArg, err := url.ParseQuery(m.Conf.WriterOption)
-		if err != nil {
-			log.Debug("url.ParseQuery error: ", err)
-			return err
-		}
-		option := mmdb.WriterOption{
-			SelectLanguages: writerOptionArg.Get("select_languages"),
-		}
+// pack dumps the data of the reader into the writer and writes it to output.
+func (m *Manager) pack(ctx context.Context, reader format.Reader, writer format.Writer, output io.Writer) error {
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	option, err := format.ParseWriterOption(writer.WriterFormat(), m.Conf.WriterOption)
+	if err != nil {
+		log.Debug("format.ParseWriterOption error: ", err)
+		return err
+	}
+	if option != nil {
 		if err := writer.SetOption(option); err != nil {
 			log.Debug("writer.SetOption error: ", err)
 			return err
 		}
-	case *plain.Writer:
-		if err := writer.SetOption(plain.WriterOption{IW: output}); err != nil {
-			log.Debug("writer.SetOption error: ", err)
-			return err
-		}
 	}
 
-	// Dump data using the dumper
+	// Dump data using the dumper, streaming writers are written to the output directly
 	dumper := ipio.NewStandardDumper(reader, writer)
-	if err := dumper.Dump(m.Conf.ReaderJobs); err != nil {
-		log.Debug("dumper.Dump error: ", err)
-		return err
-	}
-
-	// Write to the output destination
-	if _, err := dumper.WriteTo(output); err != nil {
-		log.Debug("dumper.WriteTo error: ", err)
+	if err := dumper.DumpTo(ctx, m.Conf.ReaderJobs, output); err != nil {
+		log.Debug("dumper.DumpTo error: ", err)
 		return err
 	}
 
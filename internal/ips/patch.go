@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/internal/ipio"
+	"github.com/sjzar/ips/internal/operate"
+	"github.com/sjzar/ips/internal/util"
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+// CreatePatch creates a patch from the old to the new release of a database,
+// and writes it to outputFile, or standard output if outputFile is empty.
+// The fields of dp_fields are included, or DefaultFields if it is empty.
+func (m *Manager) CreatePatch(_format, file []string, outputFile string) error {
+	return m.CreatePatchContext(context.Background(), _format, file, outputFile)
+}
+
+// CreatePatchContext is like CreatePatch but stops with ctx.Err() once ctx is done.
+// The output file is only created if the patch is created successfully.
+func (m *Manager) CreatePatchContext(ctx context.Context, _format, file []string, outputFile string) error {
+	if len(outputFile) == 0 {
+		return m.CreatePatchToContext(ctx, _format, file, os.Stdout)
+	}
+
+	if err := util.WriteFileAtomic(outputFile, func(w io.Writer) error {
+		return m.CreatePatchToContext(ctx, _format, file, w)
+	}); err != nil {
+		log.Debug("util.WriteFileAtomic error: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// CreatePatchTo is like CreatePatch but writes the patch to w.
+func (m *Manager) CreatePatchTo(_format, file []string, w io.Writer) error {
+	return m.CreatePatchToContext(context.Background(), _format, file, w)
+}
+
+// CreatePatchToContext is like CreatePatchTo but stops with ctx.Err() once ctx is done.
+func (m *Manager) CreatePatchToContext(ctx context.Context, _format, file []string, w io.Writer) error {
+	oldReader, newReader, err := m.createDiffReaders(_format, file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = oldReader.Close()
+		_ = newReader.Close()
+	}()
+
+	patch, err := ipio.CreatePatch(ctx, oldReader, newReader, m.diffFields())
+	if err != nil {
+		log.Debug("ipio.CreatePatch error: ", err)
+		return err
+	}
+	if _, err := patch.WriteTo(w); err != nil {
+		log.Debug("patch.WriteTo error: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// ApplyPatch applies a patch to the base database file and writes the resulting release to outputFile,
+// in the output format or the format detected by the name of outputFile.
+// The base database and the result are verified with the checksums of the patch,
+// the output file is only created if both match. The fields of the patch are selected from the base database,
+// which must be read with the same rewrite files and language as the old release when the patch was created.
+func (m *Manager) ApplyPatch(_format, file, patchFile, _outputFormat, outputFile string) error {
+	return m.ApplyPatchContext(context.Background(), _format, file, patchFile, _outputFormat, outputFile)
+}
+
+// ApplyPatchContext is like ApplyPatch but stops with ctx.Err() once ctx is done.
+func (m *Manager) ApplyPatchContext(ctx context.Context, _format, file, patchFile, _outputFormat, outputFile string) error {
+	if len(outputFile) == 0 {
+		return errors.ErrMissingOutputFile
+	}
+
+	patch, err := ipio.LoadPatch(patchFile)
+	if err != nil {
+		log.Debug("ipio.LoadPatch error: ", err)
+		return err
+	}
+
+	if err := m.checkReaderOption([]string{_format}, []string{file}); err != nil {
+		return err
+	}
+
+	conf := *m.Conf
+	conf.DPFields = strings.Join(patch.Fields, operate.SelectorFieldSep)
+	pm := NewManager(&conf)
+	base, err := pm.createStandardReader(_format, file, true)
+	if err != nil {
+		log.Debug("m.createStandardReader error: ", err)
+		return err
+	}
+
+	reader, err := patch.Apply(ctx, base)
+	if err != nil {
+		log.Debug("patch.Apply error: ", err)
+		_ = base.Close()
+		return err
+	}
+	writer, err := format.NewWriter(_outputFormat, outputFile, reader.Meta())
+	if err != nil {
+		log.Debug("format.NewWriter error: ", err)
+		_ = reader.Close()
+		return err
+	}
+
+	// the result is already rewritten and translated
+	resultConf := conf
+	resultConf.DPRewriterFiles, resultConf.Lang = "", ""
+	verify := func(tmp string) error {
+		result, err := NewManager(&resultConf).createStandardReader(writer.WriterFormat(), tmp, true)
+		if err != nil {
+			log.Debug("m.createStandardReader error: ", err)
+			return err
+		}
+		defer func() {
+			_ = result.Close()
+		}()
+		return patch.Verify(ctx, result)
+	}
+	if err := util.WriteFileVerified(outputFile, func(w io.Writer) error {
+		return m.pack(ctx, reader, writer, w)
+	}, verify); err != nil {
+		log.Debug("util.WriteFileVerified error: ", err)
+		return err
+	}
+
+	return nil
+}
@@ -17,6 +17,7 @@
 package ips
 
 import (
+	"context"
 	"embed"
 	"io/fs"
 	"net/http"
@@ -26,6 +27,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sjzar/ips/internal/parser"
+	"github.com/sjzar/ips/pkg/errors"
 	"github.com/sjzar/ips/pkg/model"
 )
 
@@ -92,15 +94,22 @@ func (m *Manager) NoRoute(c *gin.Context) {
 }
 
 // GetIP handles the GET /v1/ip endpoint. It takes an IP as a query parameter
-// and returns its associated information in JSON format.
+// and returns its associated information in JSON format, or 404 if the database has no data for it.
 // Example:
 // GET /v1/ip?ip=<ip>
 // Response:
 // {}
 func (m *Manager) GetIP(c *gin.Context) {
-	info, err := m.parseIP(c.Query("ip"))
+	info, err := m.parseIP(c.Request.Context(), c.Query("ip"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, errors.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, context.DeadlineExceeded):
+			status = http.StatusGatewayTimeout
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -109,6 +118,7 @@ func (m *Manager) GetIP(c *gin.Context) {
 
 // GetQuery handles the GET /v1/query endpoint. It takes a text string as a query
 // parameter, parses it into segments, and returns the information associated with each segment.
+// IP addresses without data are marked with "not_found".
 // Example:
 // GET /v1/query?text=<text>
 // Response:
@@ -124,13 +134,17 @@ func (m *Manager) GetQuery(c *gin.Context) {
 
 	tp := parser.NewTextParser(text).Parse()
 
-	for _, segment := range tp.Segments {
-		info, err := m.parseSegment(segment)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
+	infoList, err := m.parseSegments(c.Request.Context(), tp.Segments)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, context.DeadlineExceeded) {
+			status = http.StatusGatewayTimeout
 		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
 
+	for _, info := range infoList {
 		switch v := info.(type) {
 		case *model.IPInfo:
 			ret.AddItem(v.Output(m.Conf.UseDBFields))
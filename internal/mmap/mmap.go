@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package mmap provides read-only access to database files, either mapped
+// into memory or loaded into the heap.
+package mmap
+
+import (
+	"os"
+)
+
+// File is a read-only view of a database file.
+type File struct {
+	data   []byte // File content
+	mapped bool   // Whether data is memory-mapped
+}
+
+// Open maps the named file into memory.
+// On platforms without mmap support, the file is read into the heap instead.
+func Open(name string) (*File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := fi.Size()
+	if size == 0 || !Supported || int64(int(size)) != size {
+		return ReadFile(name)
+	}
+
+	data, err := mmap(f, int(size))
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		data:   data,
+		mapped: true,
+	}, nil
+}
+
+// ReadFile reads the named file into the heap.
+func ReadFile(name string) (*File, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		data: data,
+	}, nil
+}
+
+// Bytes returns the file content.
+// The returned slice must not be modified, and must not be used after Close.
+func (f *File) Bytes() []byte {
+	return f.data
+}
+
+// Mapped reports whether the file content is memory-mapped.
+func (f *File) Mapped() bool {
+	return f.mapped
+}
+
+// Close releases the file content.
+func (f *File) Close() error {
+	if f == nil || f.data == nil {
+		return nil
+	}
+	data := f.data
+	f.data = nil
+	if !f.mapped {
+		return nil
+	}
+	return munmap(data)
+}
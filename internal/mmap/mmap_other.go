@@ -0,0 +1,34 @@
+//go:build !linux
+
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mmap
+
+import (
+	"os"
+)
+
+// Supported reports whether memory-mapped loading is available on this platform.
+const Supported = false
+
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, os.ErrInvalid
+}
+
+func munmap(data []byte) error {
+	return nil
+}
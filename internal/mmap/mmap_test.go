@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mmap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen(t *testing.T) {
+	ast := assert.New(t)
+
+	name := filepath.Join(t.TempDir(), "test.db")
+	ast.Nil(os.WriteFile(name, []byte("ips database"), 0644))
+
+	f, err := Open(name)
+	ast.Nil(err)
+	ast.Equal(Supported, f.Mapped())
+	ast.Equal("ips database", string(f.Bytes()))
+	ast.Nil(f.Close())
+	ast.Nil(f.Bytes())
+
+	// closing twice is harmless
+	ast.Nil(f.Close())
+
+	f, err = ReadFile(name)
+	ast.Nil(err)
+	ast.False(f.Mapped())
+	ast.Equal("ips database", string(f.Bytes()))
+	ast.Nil(f.Close())
+
+	// empty file falls back to heap
+	empty := filepath.Join(t.TempDir(), "empty.db")
+	ast.Nil(os.WriteFile(empty, nil, 0644))
+	f, err = Open(empty)
+	ast.Nil(err)
+	ast.False(f.Mapped())
+	ast.Nil(f.Close())
+
+	_, err = Open(filepath.Join(t.TempDir(), "not_exist.db"))
+	ast.NotNil(err)
+}
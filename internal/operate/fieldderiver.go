@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operate
+
+import (
+	"strings"
+
+	"github.com/sjzar/ips/format/geo"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// FieldDeriver derives the selected common fields that the database lacks from other fields,
+// e.g. countryCode and timezone from country, and provinceCode from country and province.
+// It should run after the data rewriter, whose rewritten names it derives from.
+type FieldDeriver struct{}
+
+// NewFieldDeriver initializes and returns a new FieldDeriver.
+func NewFieldDeriver() *FieldDeriver {
+	return &FieldDeriver{}
+}
+
+// Do fills the derivable fields that are selected but empty in the provided IPInfo.
+func (d *FieldDeriver) Do(info *model.IPInfo) error {
+	selected := make(map[string]bool, len(info.Fields))
+	for _, field := range info.Fields {
+		selected[field] = true
+	}
+
+	// derive maps the derivable common fields to the selected fields to fill
+	derive := make(map[string]string)
+	for _, field := range model.DerivedFields {
+		names := []string{field}
+		if alias, ok := info.FieldAlias[field]; ok {
+			names = append(names, alias)
+		}
+		for _, name := range names {
+			if !selected[name] {
+				continue
+			}
+			if v, _ := info.GetData(name); len(v) == 0 {
+				derive[field] = name
+			}
+			break
+		}
+	}
+	if len(derive) == 0 {
+		return nil
+	}
+
+	countryCode, _ := info.GetData(model.CountryCode)
+	if len(countryCode) == 0 {
+		country, _ := info.GetData(model.Country)
+		countryCode = geo.CountryCode(country)
+	}
+	if len(countryCode) == 0 {
+		return nil
+	}
+
+	if info.Data == nil {
+		info.Data = make(map[string]string)
+	}
+	if field, ok := derive[model.CountryCode]; ok {
+		info.Data[field] = countryCode
+	}
+	if field, ok := derive[model.ProvinceCode]; ok {
+		province, _ := info.GetData(model.Province)
+		// multiple subdivisions are separated by ",", the first one is the province
+		province = strings.SplitN(province, ",", 2)[0]
+		if code := geo.SubdivisionCode(countryCode, province); len(code) != 0 {
+			info.Data[field] = code
+		}
+	}
+	if field, ok := derive[model.TimeZone]; ok {
+		if tz := geo.TimeZone(countryCode); len(tz) != 0 {
+			info.Data[field] = tz
+		}
+	}
+
+	return nil
+}
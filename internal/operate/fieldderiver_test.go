@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/model"
+)
+
+func TestFieldDeriver(t *testing.T) {
+	ast := assert.New(t)
+
+	deriver := NewFieldDeriver()
+
+	// derive from names
+	info := &model.IPInfo{
+		Data: map[string]string{
+			"country":  "中国",
+			"province": "广东省",
+		},
+		Fields: []string{"country", "countryCode", "provinceCode", "timezone"},
+	}
+	ast.Nil(deriver.Do(info))
+	ast.Equal([]string{"中国", "CN", "CN-GD", "Asia/Shanghai"}, info.Values())
+
+	// native values are kept, empty native fields are filled
+	info = &model.IPInfo{
+		Data: map[string]string{
+			"country_name": "日本",
+			"country_code": "JP",
+			"time_zone":    "",
+		},
+		FieldAlias: map[string]string{
+			"country":     "country_name",
+			"countryCode": "country_code",
+			"timezone":    "time_zone",
+		},
+		Fields: []string{"country_code", "time_zone"},
+	}
+	ast.Nil(deriver.Do(info))
+	ast.Equal([]string{"JP", "Asia/Tokyo"}, info.Values())
+
+	// fields that are not selected are not derived
+	info = &model.IPInfo{
+		Data:   map[string]string{"country": "中国"},
+		Fields: []string{"country"},
+	}
+	ast.Nil(deriver.Do(info))
+	ast.Equal(map[string]string{"country": "中国"}, info.Data)
+
+	// unknown country
+	info = &model.IPInfo{
+		Data:   map[string]string{"country": "保留地址"},
+		Fields: []string{"countryCode"},
+	}
+	ast.Nil(deriver.Do(info))
+	ast.Equal([]string{""}, info.Values())
+}
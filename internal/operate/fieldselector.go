@@ -18,6 +18,7 @@ package operate
 
 import (
 	"net/url"
+	"strconv"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -46,6 +47,10 @@ const (
 	SelectorWildcardArg = "*"
 )
 
+// selectorComparisons are the prefixes of the values compared as numbers, longest first,
+// e.g. "accuracy_radius=<=100" matches an accuracy radius of at most 100.
+var selectorComparisons = []string{">=", "<=", ">", "<"}
+
 // magicArgs is a map that defines shortcuts for commonly used selector arguments.
 var magicArgs = map[string]string{
 	"find":           "country,province,city,isp",
@@ -222,7 +227,7 @@ type FieldSelectorRule struct {
 // IsMatch determines if the given IPInfo matches the conditions set in the rule.
 func (r *FieldSelectorRule) IsMatch(info *model.IPInfo) bool {
 	for key, values := range r.Condition {
-		actual, _ := info.GetData(key)
+		actual, _ := info.GetValue(key)
 		for _, value := range values {
 			not := false
 			if strings.HasPrefix(value, SelectorValueNot) {
@@ -232,7 +237,7 @@ func (r *FieldSelectorRule) IsMatch(info *model.IPInfo) bool {
 
 			match := false
 			for _, v := range strings.Split(value, SelectorValueOr) {
-				if v == actual {
+				if matchValue(actual, v) {
 					match = true
 					break
 				}
@@ -246,3 +251,39 @@ func (r *FieldSelectorRule) IsMatch(info *model.IPInfo) bool {
 	}
 	return true
 }
+
+// matchValue reports whether the actual value matches the expected value of a condition.
+// Expected values with a comparison prefix, e.g. ">=100", match numbers in the range,
+// and numeric values also match expected values of the same number, e.g. 1.5 matches "1.5".
+func matchValue(actual model.Value, expected string) bool {
+	for _, op := range selectorComparisons {
+		if !strings.HasPrefix(expected, op) {
+			continue
+		}
+		want, err := strconv.ParseFloat(strings.TrimPrefix(expected, op), 64)
+		got, ok := actual.Float()
+		if err != nil || !ok {
+			return false
+		}
+		switch op {
+		case ">=":
+			return got >= want
+		case "<=":
+			return got <= want
+		case ">":
+			return got > want
+		default:
+			return got < want
+		}
+	}
+
+	if actual.String() == expected {
+		return true
+	}
+	if actual.Type() == model.TypeInt || actual.Type() == model.TypeFloat {
+		want, err := strconv.ParseFloat(expected, 64)
+		got, _ := actual.Float()
+		return err == nil && got == want
+	}
+	return false
+}
@@ -57,3 +57,30 @@ func TestFieldSelector(t *testing.T) {
 	ast.Nil(err)
 	ast.Equal([]string{"日本", "", "", ""}, info.Values())
 }
+
+func TestFieldSelectorNumeric(t *testing.T) {
+	ast := assert.New(t)
+
+	meta := &model.Meta{
+		Fields:     []string{"country", "accuracy_radius"},
+		FieldAlias: make(map[string]string),
+	}
+	selector, err := NewFieldSelector(meta, "country,accuracy_radius|accuracy_radius=<=100:country,accuracy_radius|accuracy_radius=150.5/>=1000:accuracy_radius|country")
+	ast.Nil(err)
+
+	find := func(radius model.Value) []string {
+		info := &model.IPInfo{Data: map[string]string{"country": "A"}}
+		info.SetValue("accuracy_radius", radius)
+		ast.Nil(selector.Do(info))
+		return info.Values()
+	}
+	ast.Equal([]string{"A", "100"}, find(model.IntValue(100)))
+	ast.Equal([]string{"A", "20"}, find(model.StringValue("20")))
+	ast.Equal([]string{"", "1000"}, find(model.IntValue(1000)))
+	ast.Equal([]string{"A", ""}, find(model.IntValue(500)))
+	ast.Equal([]string{"A", ""}, find(model.StringValue("far")))
+
+	// numbers match expected values of the same number
+	ast.Equal([]string{"", "150.500000"}, find(model.FloatValue(150.5).WithString("150.500000")))
+	ast.Equal([]string{"A", ""}, find(model.StringValue("150.500000")))
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operate
+
+import (
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// SourceFieldSuffix is appended to the name of a field to name the field holding its source.
+const SourceFieldSuffix = "_source"
+
+// SourceFields adds a field holding the source of each field, e.g. "country_source" with the value
+// "1:ipdb:city.ipdb", so that merged databases can be audited. Sources are set by readers combining
+// several databases, fields without source get an empty value.
+// It should run last, after the field selector, whose fields it adds the sources of.
+type SourceFields struct {
+	fields       []string
+	sourceFields []string
+}
+
+// NewSourceFields initializes a SourceFields for the fields of meta, and adds the source fields to meta.Fields.
+// MAKE SURE that the incoming meta comes from StandardReader or HybridReader, not DB Reader, since it modifies the *model.Meta.
+func NewSourceFields(meta *model.Meta) *SourceFields {
+	ret := &SourceFields{
+		fields:       meta.Fields,
+		sourceFields: make([]string, 0, len(meta.Fields)),
+	}
+	for _, field := range meta.Fields {
+		ret.sourceFields = append(ret.sourceFields, field+SourceFieldSuffix)
+	}
+
+	fields := make([]string, 0, len(ret.fields)+len(ret.sourceFields))
+	fields = append(fields, ret.fields...)
+	meta.Fields = append(fields, ret.sourceFields...)
+
+	return ret
+}
+
+// Do sets the source fields of the provided IPInfo and adds them to the output fields.
+func (s *SourceFields) Do(info *model.IPInfo) error {
+	if info.Data == nil {
+		info.Data = make(map[string]string)
+	}
+	for i, field := range s.fields {
+		value := ""
+		if source, ok := info.Sources[field]; ok {
+			value = source.String()
+		}
+		info.Data[s.sourceFields[i]] = value
+	}
+
+	fields := make([]string, 0, len(info.Fields)+len(s.sourceFields))
+	fields = append(fields, info.Fields...)
+	info.Fields = append(fields, s.sourceFields...)
+	return nil
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/model"
+)
+
+func TestSourceFields(t *testing.T) {
+	ast := assert.New(t)
+
+	meta := &model.Meta{Fields: []string{"country", "isp"}}
+	sf := NewSourceFields(meta)
+	ast.Equal([]string{"country", "isp", "country_source", "isp_source"}, meta.Fields)
+
+	info := &model.IPInfo{
+		Data:   map[string]string{"country": "中国", "isp": ""},
+		Fields: []string{"country", "isp"},
+		Sources: map[string]model.Source{
+			"country": {Index: 1, Format: "ipdb", File: "city.ipdb"},
+		},
+	}
+	ast.Nil(sf.Do(info))
+	ast.Equal([]string{"中国", "", "1:ipdb:city.ipdb", ""}, info.Values())
+}
@@ -17,7 +17,9 @@
 package util
 
 import (
+	"io"
 	"os"
+	"path/filepath"
 
 	log "github.com/sirupsen/logrus"
 
@@ -51,3 +53,46 @@ func IsFileExist(file string) bool {
 	}
 	return fi.Mode().IsRegular()
 }
+
+// WriteFileAtomic writes a file with fn through a temporary file in the same directory,
+// and renames it to file only if fn succeeds, so a failed or interrupted write leaves no partial file.
+func WriteFileAtomic(file string, fn func(w io.Writer) error) error {
+	return WriteFileVerified(file, fn, nil)
+}
+
+// WriteFileVerified is like WriteFileAtomic, but calls verify with the path of the temporary file once it is written,
+// and renames it to file only if verify succeeds as well. verify may be nil.
+func WriteFileVerified(file string, fn func(w io.Writer) error, verify func(tmp string) error) error {
+	f, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() {
+		if tmp != "" {
+			_ = f.Close()
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	if err := fn(f); err != nil {
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if verify != nil {
+		if err := verify(tmp); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		return err
+	}
+	tmp = ""
+
+	return nil
+}
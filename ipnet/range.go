@@ -17,6 +17,7 @@
 package ipnet
 
 import (
+	"math/big"
 	"math/bits"
 	"net"
 )
@@ -52,21 +53,29 @@ func (r *Range) Join(r2 *Range) bool {
 
 // CommonRange finds the common IP range between the current range and another range (r2),
 // ensuring that the resulting range contains the specified IP.
+// The IPs are compared in IPv6 length, so IPv4 ranges in either length can be mixed,
+// and the resulting IPs keep the length of the current range.
 func (r *Range) CommonRange(ip net.IP, r2 *Range) bool {
-	if IPLess(r2.End, r.Start) || IPLess(NextIP(r.End), r2.Start) {
+	start, end := r.Start.To16(), r.End.To16()
+	start2, end2 := r2.Start.To16(), r2.End.To16()
+	if IPLess(end2, start) || IPLess(end, start2) {
 		return false
 	}
-	start := r.Start
-	if IPLess(start, r2.Start) {
-		start = r2.Start
+	if IPLess(start, start2) {
+		start = start2
 	}
-	end := r.End
-	if IPLess(r2.End, end) {
-		end = r2.End
+	if IPLess(end2, end) {
+		end = end2
 	}
-	if !Contains(start, end, ip) {
+	if !Contains(start, end, ip.To16()) {
 		return false
 	}
+	if len(r.Start) == net.IPv4len {
+		start = start.To4()
+	}
+	if len(r.End) == net.IPv4len {
+		end = end.To4()
+	}
 	r.Start, r.End = start, end
 	return true
 }
@@ -76,6 +85,12 @@ func (r *Range) Contains(ip net.IP) bool {
 	return Contains(r.Start, r.End, ip)
 }
 
+// Size returns the number of IP addresses in the range.
+func (r *Range) Size() *big.Int {
+	size := new(big.Int).Sub(IPToBigInt(r.End), IPToBigInt(r.Start))
+	return size.Add(size, big.NewInt(1))
+}
+
 // JoinIPNet tries to merge the current IP range with a provided IPNet.
 // It forbids merging if the start IP of IPNet is before the current range or if they aren't adjacent.
 func (r *Range) JoinIPNet(ipNet *net.IPNet) bool {
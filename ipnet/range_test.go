@@ -140,4 +140,43 @@ func TestRange_CommonRange(t *testing.T) {
 	// ipr4:          [      ]
 	// Result: Error ipr4 is not adjacent
 	ast.False(ipr1.CommonRange(ipr1.Start, ipr4))
+
+	// Example5
+	// IP:            *
+	// ipr1: [               ]  (IPv6 length)
+	// ipr5:      [      ]      (IPv4 length)
+	// Result:    [      ]      (IPv6 length)
+	ipr1 = &Range{Start: ipNet1.IP.To16(), End: LastIP(ipNet1).To16()}
+	ipr5 := &Range{Start: net.ParseIP("58.82.202.0").To4(), End: net.ParseIP("58.82.204.255").To4()}
+	ast.True(ipr1.CommonRange(net.ParseIP("58.82.203.1").To4(), ipr5))
+	ast.Equal(net.ParseIP("58.82.202.0"), ipr1.Start)
+	ast.Equal(net.ParseIP("58.82.204.255"), ipr1.End)
+
+	// Example6
+	// the same ranges in the other order keep IPv4 length
+	ipr5 = &Range{Start: net.ParseIP("58.82.202.0").To4(), End: net.ParseIP("58.82.204.255").To4()}
+	ipr1 = &Range{Start: ipNet1.IP.To16(), End: LastIP(ipNet1).To16()}
+	ast.True(ipr5.CommonRange(net.ParseIP("58.82.203.1"), ipr1))
+	ast.Equal(net.ParseIP("58.82.202.0").To4(), ipr5.Start)
+	ast.Equal(net.ParseIP("58.82.204.255").To4(), ipr5.End)
+}
+
+func TestRange_Size(t *testing.T) {
+	ast := assert.New(t)
+
+	ipr := &Range{Start: net.ParseIP("58.82.202.0"), End: net.ParseIP("58.82.204.255")}
+	ast.Equal("768", ipr.Size().String())
+
+	ipr = &Range{Start: net.ParseIP("1.1.1.1").To4(), End: net.ParseIP("1.1.1.1").To4()}
+	ast.Equal("1", ipr.Size().String())
+
+	// full IPv4 space, in either length
+	ipr = &Range{Start: net.IPv4zero.To4(), End: LastIPv4.To4()}
+	ast.Equal("4294967296", ipr.Size().String())
+	ipr = &Range{Start: net.IPv4zero, End: LastIPv4}
+	ast.Equal("4294967296", ipr.Size().String())
+
+	// full IPv6 space
+	ipr = &Range{Start: net.IPv6zero, End: LastIPv6}
+	ast.Equal("340282366920938463463374607431768211456", ipr.Size().String())
 }
@@ -18,6 +18,10 @@ package errors
 
 import (
 	"errors"
+	"fmt"
+	"net"
+
+	"github.com/sjzar/ips/ipnet"
 )
 
 var (
@@ -33,17 +37,32 @@ var (
 	ErrMetaMissing            = errors.New("meta information missing")
 	ErrNilWriter              = errors.New("writer is not initialized")
 	ErrUnsupportedLanguage    = errors.New("unsupported language")
+	ErrUnsupportedOption      = errors.New("unsupported option")
+	ErrUnknownOption          = errors.New("unknown option")
+	ErrInvalidOptionValue     = errors.New("invalid option value")
 
 	// IPio
 
-	ErrNoDatabaseReaders = errors.New("no database readers provided")
-	ErrInvalidIPRange    = errors.New("invalid IP range")
+	ErrNoDatabaseReaders     = errors.New("no database readers provided")
+	ErrInvalidIPRange        = errors.New("invalid IP range")
+	ErrNotFound              = errors.New("IP not found")
+	ErrInvalidHybridMode     = errors.New("invalid hybrid mode")
+	ErrInvalidWeights        = errors.New("invalid hybrid weights")
+	ErrInvalidTieBreak       = errors.New("invalid hybrid tie-break rule")
+	ErrInvalidHybridPriority = errors.New("invalid hybrid priority")
+	ErrInvalidPatch          = errors.New("invalid patch file")
+	ErrChecksumMismatch      = errors.New("checksum mismatch")
+	ErrOverlappingDatabases  = errors.New("databases to merge overlap")
+
+	// Deprecated: use ErrNotFound.
+	ErrDataNotExists = ErrNotFound
 
 	// Operate
 
 	ErrFileEmpty           = errors.New("file is empty")
 	ErrFieldInvalid        = errors.New("invalid field specified")
 	ErrMetaFieldsUndefined = errors.New("no fields defined in meta")
+	ErrInvalidFieldAlias   = errors.New("invalid field alias")
 
 	// Command
 
@@ -52,8 +71,49 @@ var (
 	ErrInvalidDirectory  = errors.New("invalid directory path")
 	ErrMissingConfigName = errors.New("config name not specified")
 	ErrDiscoveryFailed   = errors.New("failed to discover IP address")
+	ErrMissingDatabases  = errors.New("an old and a new database are required")
+	ErrUnsupportedOutput = errors.New("unsupported output type")
+	ErrMissingOutputFile = errors.New("output file not specified")
 
 	// Server
 
 	ErrInvalidIP = errors.New("invalid IP address")
 )
+
+// NotFoundError reports that the database has no data for an IP address.
+// It matches ErrNotFound with Is.
+type NotFoundError struct {
+
+	// IP is the IP address that was looked up.
+	IP net.IP
+
+	// IPNet is the range of unknown space containing IP, nil if the reader cannot tell.
+	IPNet *ipnet.Range
+}
+
+// NewNotFoundError returns a NotFoundError for ip in the unknown range ipr.
+func NewNotFoundError(ip net.IP, ipr *ipnet.Range) error {
+	return &NotFoundError{IP: ip, IPNet: ipr}
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrNotFound, e.IP)
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return ErrNotFound
+}
+
+// AsNotFound returns the NotFoundError in err's chain, if any.
+func AsNotFound(err error) (*NotFoundError, bool) {
+	var e *NotFoundError
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// Is reports whether any error in err's chain matches target, see errors.Is.
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
@@ -0,0 +1,461 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package ips is the public Go API of IPS.
+//
+// It exposes IP geolocation lookups, text queries, dumps and packs of IP databases,
+// with the same behavior as the ips command line tool, so that IPS can be embedded into other programs:
+//
+//	client, err := ips.New(ips.Config{
+//		IPv4File: []string{"qqwry.dat"},
+//		IPv6File: []string{"zxipv6wry.db"},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer client.Close()
+//
+//	info, err := client.Find(net.ParseIP("8.8.8.8"))
+//
+// The exported API of this package follows the semantic versioning of the module:
+// within a major version, exported identifiers are not removed or changed incompatibly.
+package ips
+
+import (
+	"context"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ipsformat "github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/format/plain"
+	"github.com/sjzar/ips/internal/ipio"
+	core "github.com/sjzar/ips/internal/ips"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+const (
+	// OutputTypeText represents the text output format of Query.
+	OutputTypeText = core.OutputTypeText
+
+	// OutputTypeJSON represents the JSON output format of Query.
+	OutputTypeJSON = core.OutputTypeJSON
+
+	// OutputTypeAlfred represents the Alfred output format of Query.
+	OutputTypeAlfred = core.OutputTypeAlfred
+
+	// HybridAggregationMode supplements missing fields from multiple databases. (default)
+	HybridAggregationMode = ipio.HybridAggregationMode
+
+	// HybridComparisonMode outputs the data of all databases for comparison.
+	HybridComparisonMode = ipio.HybridComparisonMode
+
+	// HybridConsensusMode picks, per field, the value agreed on by the most databases,
+	// and outputs the agreement score in the HybridAgreementField field.
+	HybridConsensusMode = ipio.HybridConsensusMode
+
+	// HybridCascadeMode queries the databases one after another and stops once every selected field has a value.
+	HybridCascadeMode = ipio.HybridCascadeMode
+
+	// HybridTieBreakFirst picks the value of the first listed database among tied values in HybridConsensusMode. (default)
+	HybridTieBreakFirst = ipio.HybridTieBreakFirst
+
+	// HybridTieBreakEmpty leaves the fields with tied values empty in HybridConsensusMode.
+	HybridTieBreakEmpty = ipio.HybridTieBreakEmpty
+
+	// HybridAgreementField is the field holding the agreement score in HybridConsensusMode, from 0 to 1.
+	HybridAgreementField = ipio.HybridAgreementField
+
+	// DefaultFields represents the default output fields.
+	DefaultFields = core.DefaultFields
+
+	// DefaultTextFormat represents the default format of text output.
+	DefaultTextFormat = "%origin [%values] "
+
+	// DefaultTextValuesSep represents the default separator of values in text output.
+	DefaultTextValuesSep = " "
+)
+
+// Config represents the configuration of a Client.
+// The options have the same meaning as the configuration of the ips command line tool, see docs/config.md.
+type Config struct {
+
+	// Dir specifies the directory of database files with relative paths.
+	// Missing well-known database files are downloaded into it.
+	Dir string
+
+	// Lang specifies the language for the output.
+	Lang string
+
+	// IPv4File specifies the IPv4 database files, more than one file creates a hybrid reader.
+	IPv4File []string
+
+	// IPv4Format specifies the formats of IPv4File, detected by file name if empty.
+	IPv4Format []string
+
+	// IPv6File specifies the IPv6 database files, more than one file creates a hybrid reader.
+	IPv6File []string
+
+	// IPv6Format specifies the formats of IPv6File, detected by file name if empty.
+	IPv6Format []string
+
+	// HybridMode specifies how multiple databases are combined,
+	// HybridAggregationMode (default), HybridComparisonMode, HybridConsensusMode or HybridCascadeMode.
+	HybridMode string
+
+	// HybridPriority maps fields to the indexes of the databases they are taken from in HybridAggregationMode and HybridCascadeMode,
+	// e.g. {"country": {1, 0}}. A field takes the first non-empty value of the listed databases.
+	HybridPriority map[string][]int
+
+	// HybridWeights lists the voting weights of the databases in HybridConsensusMode,
+	// in the order of the database files. Databases without weight vote with weight 1.
+	HybridWeights []float64
+
+	// HybridTieBreak specifies how ties are broken in HybridConsensusMode,
+	// HybridTieBreakFirst (default) or HybridTieBreakEmpty.
+	HybridTieBreak string
+
+	// HybridTimeout limits the lookup time of each database when multiple databases are combined.
+	// Databases that time out are left out of the result, zero means no limit.
+	HybridTimeout time.Duration
+
+	// Fields lists the output fields of lookups, DefaultFields if empty.
+	Fields string
+
+	// UseDBFields indicates whether to output database field names instead of common field names.
+	UseDBFields bool
+
+	// RewriteFiles lists the files for data rewriting of lookups, separated by commas.
+	RewriteFiles string
+
+	// Overlay specifies a file in plain format with ranges whose values take precedence over the databases of lookups.
+	Overlay string
+
+	// OverlayFields lists the fields of Overlay to apply, separated by commas. All fields if empty.
+	OverlayFields string
+
+	// OutputType specifies the output type of Query, OutputTypeText if empty.
+	OutputType string
+
+	// TextFormat specifies the format of text output, DefaultTextFormat if empty.
+	TextFormat string
+
+	// TextValuesSep specifies the separator of values in text output, DefaultTextValuesSep if empty.
+	TextValuesSep string
+
+	// JSONIndent indicates whether the JSON output should be indented.
+	JSONIndent bool
+
+	// DPFields lists the output fields of dumps and packs, all fields if empty.
+	DPFields string
+
+	// DPRewriteFiles lists the files for data rewriting of dumps and packs, separated by commas.
+	DPRewriteFiles string
+
+	// DPSources indicates whether dumps and packs of multiple databases add the source database
+	// of each field as an extra field "<field>_source".
+	DPSources bool
+
+	// DPOverlay specifies a file in plain format with ranges whose values take precedence in dumps and packs.
+	DPOverlay string
+
+	// DPOverlayFields lists the fields of DPOverlay to apply, separated by commas. All fields if empty.
+	DPOverlayFields string
+
+	// ReaderOption specifies the options for the database readers, in URL query format.
+	ReaderOption string
+
+	// WriterOption specifies the options for the database writers, in URL query format.
+	WriterOption string
+
+	// FieldAliasFile specifies the file mapping database fields to common fields.
+	FieldAliasFile string
+
+	// ReaderJobs specifies the number of concurrent reader jobs of dumps and packs.
+	ReaderJobs int
+
+	// CacheSize specifies the number of IP ranges cached for lookups, zero disables the cache.
+	CacheSize int
+
+	// CacheTTL specifies the lifetime of the cached IP ranges, zero means no expiration.
+	CacheTTL time.Duration
+}
+
+// Client looks up IP information and converts IP databases.
+// A Client is safe for concurrent lookups. Databases are opened on first use.
+type Client struct {
+	manager *core.Manager
+}
+
+// formatPriority formats the databases of each field as in the configuration of the ips command line tool.
+func formatPriority(priority map[string][]int) string {
+	fields := make([]string, 0, len(priority))
+	for field := range priority {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	items := make([]string, 0, len(fields))
+	for _, field := range fields {
+		indexes := make([]string, 0, len(priority[field]))
+		for _, index := range priority[field] {
+			indexes = append(indexes, strconv.Itoa(index))
+		}
+		items = append(items, field+"="+strings.Join(indexes, ","))
+	}
+	return strings.Join(items, ";")
+}
+
+// formatWeights formats the voting weights as in the configuration of the ips command line tool.
+func formatWeights(weights []float64) string {
+	strs := make([]string, 0, len(weights))
+	for _, weight := range weights {
+		strs = append(strs, strconv.FormatFloat(weight, 'f', -1, 64))
+	}
+	return strings.Join(strs, ",")
+}
+
+// New creates a Client from the configuration.
+func New(conf Config) (*Client, error) {
+	if len(conf.IPv4Format) != 0 && len(conf.IPv4Format) != len(conf.IPv4File) {
+		return nil, errors.ErrInvalidFormat
+	}
+	if len(conf.IPv6Format) != 0 && len(conf.IPv6Format) != len(conf.IPv6File) {
+		return nil, errors.ErrInvalidFormat
+	}
+
+	c := &core.Config{
+		IPSDir:          conf.Dir,
+		Lang:            conf.Lang,
+		IPv4File:        conf.IPv4File,
+		IPv4Format:      conf.IPv4Format,
+		IPv6File:        conf.IPv6File,
+		IPv6Format:      conf.IPv6Format,
+		HybridMode:      conf.HybridMode,
+		HybridPriority:  formatPriority(conf.HybridPriority),
+		HybridWeights:   formatWeights(conf.HybridWeights),
+		HybridTieBreak:  conf.HybridTieBreak,
+		HybridTimeoutMs: int(conf.HybridTimeout / time.Millisecond),
+		Fields:          conf.Fields,
+		UseDBFields:     conf.UseDBFields,
+		RewriteFiles:    conf.RewriteFiles,
+		Overlay:         conf.Overlay,
+		OverlayFields:   conf.OverlayFields,
+		OutputType:      conf.OutputType,
+		TextFormat:      conf.TextFormat,
+		TextValuesSep:   conf.TextValuesSep,
+		JsonIndent:      conf.JSONIndent,
+		DPFields:        conf.DPFields,
+		DPRewriterFiles: conf.DPRewriteFiles,
+		DPSources:       conf.DPSources,
+		DPOverlay:       conf.DPOverlay,
+		DPOverlayFields: conf.DPOverlayFields,
+		ReaderOption:    conf.ReaderOption,
+		WriterOption:    conf.WriterOption,
+		FieldAliasFile:  conf.FieldAliasFile,
+		ReaderJobs:      conf.ReaderJobs,
+		CacheSize:       conf.CacheSize,
+		CacheTTLS:       int(conf.CacheTTL / time.Second),
+	}
+	if len(c.IPv4Format) == 0 {
+		c.IPv4Format = make([]string, len(c.IPv4File))
+	}
+	if len(c.IPv6Format) == 0 {
+		c.IPv6Format = make([]string, len(c.IPv6File))
+	}
+	if len(c.Fields) == 0 {
+		c.Fields = DefaultFields
+	}
+	if len(c.TextFormat) == 0 {
+		c.TextFormat = DefaultTextFormat
+	}
+	if len(c.TextValuesSep) == 0 {
+		c.TextValuesSep = DefaultTextValuesSep
+	}
+	if conf.CacheTTL > 0 && c.CacheTTLS == 0 {
+		c.CacheTTLS = 1
+	}
+
+	return &Client{
+		manager: core.NewManager(c),
+	}, nil
+}
+
+// Find retrieves IP information of the given IP address.
+// It returns errors.NotFoundError, matching errors.ErrNotFound, if the database has no data for ip.
+func (c *Client) Find(ip net.IP) (*model.IPInfo, error) {
+	return c.FindContext(context.Background(), ip)
+}
+
+// FindContext is like Find but the lookup honors ctx.
+func (c *Client) FindContext(ctx context.Context, ip net.IP) (*model.IPInfo, error) {
+	infos, err := c.manager.FindBatchContext(ctx, []net.IP{ip})
+	if err != nil {
+		return nil, err
+	}
+	if infos[0].NotFound {
+		return nil, errors.NewNotFoundError(ip, infos[0].IPNet)
+	}
+	return infos[0], nil
+}
+
+// FindBatch retrieves IP information of the given IP addresses, in the same order.
+// IP addresses in the same range are resolved only once, and those without data are marked NotFound.
+func (c *Client) FindBatch(ips []net.IP) ([]*model.IPInfo, error) {
+	return c.manager.FindBatch(ips)
+}
+
+// FindBatchContext is like FindBatch but the lookups honor ctx.
+func (c *Client) FindBatchContext(ctx context.Context, ips []net.IP) ([]*model.IPInfo, error) {
+	return c.manager.FindBatchContext(ctx, ips)
+}
+
+// Query finds the IP addresses and domains in text and returns the text with their information,
+// formatted by OutputType, TextFormat and TextValuesSep like the ips command.
+func (c *Client) Query(text string) (string, error) {
+	return c.manager.ParseText(text)
+}
+
+// QueryContext is like Query but the lookups honor ctx.
+func (c *Client) QueryContext(ctx context.Context, text string) (string, error) {
+	return c.manager.ParseTextContext(ctx, text)
+}
+
+// Dump writes the data of the database files to w in plain text format.
+// Formats are detected by file name if format is empty.
+func (c *Client) Dump(w io.Writer, file []string, format []string) error {
+	return c.manager.PackTo(format, file, plain.DBFormat, w)
+}
+
+// DumpContext is like Dump but stops with ctx.Err() once ctx is done.
+func (c *Client) DumpContext(ctx context.Context, w io.Writer, file []string, format []string) error {
+	return c.manager.PackToContext(ctx, format, file, plain.DBFormat, w)
+}
+
+// Pack converts the database files into outputFile, or standard output if outputFile is empty.
+// Formats are detected by file name if format or outputFormat is empty.
+func (c *Client) Pack(file []string, format []string, outputFile, outputFormat string) error {
+	return c.manager.Pack(format, file, outputFormat, outputFile)
+}
+
+// PackContext is like Pack but stops with ctx.Err() once ctx is done.
+// outputFile is only created if packing succeeds.
+func (c *Client) PackContext(ctx context.Context, file []string, format []string, outputFile, outputFormat string) error {
+	return c.manager.PackContext(ctx, format, file, outputFormat, outputFile)
+}
+
+// PackTo converts the database files and writes the result to w in outputFormat.
+func (c *Client) PackTo(w io.Writer, file []string, format []string, outputFormat string) error {
+	return c.manager.PackTo(format, file, outputFormat, w)
+}
+
+// PackToContext is like PackTo but stops with ctx.Err() once ctx is done.
+func (c *Client) PackToContext(ctx context.Context, w io.Writer, file []string, format []string, outputFormat string) error {
+	return c.manager.PackToContext(ctx, format, file, outputFormat, w)
+}
+
+// Merge combines database files covering different address families or disjoint ranges,
+// e.g. an IPv4 and an IPv6 database, into outputFile, or standard output if outputFile is empty.
+// Fields are unified by their common names, fields a database does not have are left empty.
+// Formats are detected by file name if format or outputFormat is empty.
+func (c *Client) Merge(file []string, format []string, outputFile, outputFormat string) error {
+	return c.manager.Merge(format, file, outputFormat, outputFile)
+}
+
+// MergeContext is like Merge but stops with ctx.Err() once ctx is done.
+// outputFile is only created if merging succeeds.
+func (c *Client) MergeContext(ctx context.Context, file []string, format []string, outputFile, outputFormat string) error {
+	return c.manager.MergeContext(ctx, format, file, outputFormat, outputFile)
+}
+
+// MergeTo merges the database files and writes the result to w in outputFormat.
+func (c *Client) MergeTo(w io.Writer, file []string, format []string, outputFormat string) error {
+	return c.manager.MergeTo(format, file, outputFormat, w)
+}
+
+// MergeToContext is like MergeTo but stops with ctx.Err() once ctx is done.
+func (c *Client) MergeToContext(ctx context.Context, w io.Writer, file []string, format []string, outputFormat string) error {
+	return c.manager.MergeToContext(ctx, format, file, outputFormat, w)
+}
+
+// Diff compares the old and the new database file range by range and writes the differing ranges
+// and the statistics to w. outputType is "text", "csv" or "json", text if empty.
+// Formats are detected by file name if format is empty.
+func (c *Client) Diff(w io.Writer, oldFile, newFile string, format []string, outputType string) error {
+	return c.manager.DiffTo(format, []string{oldFile, newFile}, outputType, w)
+}
+
+// DiffContext is like Diff but stops with ctx.Err() once ctx is done.
+func (c *Client) DiffContext(ctx context.Context, w io.Writer, oldFile, newFile string, format []string, outputType string) error {
+	return c.manager.DiffToContext(ctx, format, []string{oldFile, newFile}, outputType, w)
+}
+
+// Changelog lists the changes between the old and the new release of a database and writes them to w.
+// outputType is "text" or "json", text if empty. If patch is not nil, the changed ranges
+// with the values of the new release are written to it in plain format.
+// Formats are detected by file name if format is empty.
+func (c *Client) Changelog(w, patch io.Writer, oldFile, newFile string, format []string, outputType string) error {
+	return c.manager.ChangelogTo(format, []string{oldFile, newFile}, outputType, w, patch)
+}
+
+// ChangelogContext is like Changelog but stops with ctx.Err() once ctx is done.
+func (c *Client) ChangelogContext(ctx context.Context, w, patch io.Writer, oldFile, newFile string, format []string, outputType string) error {
+	return c.manager.ChangelogToContext(ctx, format, []string{oldFile, newFile}, outputType, w, patch)
+}
+
+// CreatePatch creates a patch from the old to the new release of a database and writes it to w.
+// Formats are detected by file name if format is empty.
+func (c *Client) CreatePatch(w io.Writer, oldFile, newFile string, format []string) error {
+	return c.manager.CreatePatchTo(format, []string{oldFile, newFile}, w)
+}
+
+// CreatePatchContext is like CreatePatch but stops with ctx.Err() once ctx is done.
+func (c *Client) CreatePatchContext(ctx context.Context, w io.Writer, oldFile, newFile string, format []string) error {
+	return c.manager.CreatePatchToContext(ctx, format, []string{oldFile, newFile}, w)
+}
+
+// ApplyPatch applies a patch to the base database file and writes the resulting release to outputFile.
+// The base database and the result are verified with the checksums of the patch.
+// Formats are detected by file name if format or outputFormat is empty.
+func (c *Client) ApplyPatch(file, patchFile, format, outputFile, outputFormat string) error {
+	return c.manager.ApplyPatch(format, file, patchFile, outputFormat, outputFile)
+}
+
+// ApplyPatchContext is like ApplyPatch but stops with ctx.Err() once ctx is done.
+func (c *Client) ApplyPatchContext(ctx context.Context, file, patchFile, format, outputFile, outputFormat string) error {
+	return c.manager.ApplyPatchContext(ctx, format, file, patchFile, outputFormat, outputFile)
+}
+
+// Info returns the metadata of the database files.
+// Multiple files are combined as in Dump. Formats are detected by file name if format is empty.
+func (c *Client) Info(file []string, format []string) (*model.Meta, error) {
+	return c.manager.Info(format, file)
+}
+
+// Formats lists the supported formats of IP databases, with their capabilities and options.
+// Formats registered at runtime with the format package are included.
+func Formats() []ipsformat.Descriptor {
+	return ipsformat.Formats()
+}
+
+// Close closes the databases opened for lookups.
+func (c *Client) Close() error {
+	return c.manager.Close()
+}
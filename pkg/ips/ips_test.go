@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ipsformat "github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+const testPlainData = `# Meta: {"MetaVersion":1,"Format":"plain","IPVersion":1,"Fields":["country","province","city","isp"]}
+0.0.0.0/5	,,,
+8.0.0.0/8	美国,,,Google
+9.0.0.0/8	,,,
+10.0.0.0/7	,,,
+12.0.0.0/6	,,,
+16.0.0.0/4	,,,
+32.0.0.0/3	,,,
+64.0.0.0/2	,,,
+128.0.0.0/1	,,,
+`
+
+func TestClient(t *testing.T) {
+	ast := assert.New(t)
+
+	dir := t.TempDir()
+	ast.Nil(os.WriteFile(filepath.Join(dir, "test.txt"), []byte(testPlainData), 0644))
+
+	client, err := New(Config{
+		Dir:      dir,
+		IPv4File: []string{"test.txt"},
+		Fields:   "country,isp",
+	})
+	ast.Nil(err)
+	defer client.Close()
+
+	info, err := client.Find(net.ParseIP("8.8.8.8"))
+	ast.Nil(err)
+	ast.Equal([]string{"美国", "Google"}, info.Values())
+
+	infos, err := client.FindBatch([]net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("8.8.4.4")})
+	ast.Nil(err)
+	ast.Equal([]string{"", ""}, infos[0].Values())
+	ast.Equal([]string{"美国", "Google"}, infos[1].Values())
+
+	ret, err := client.Query("dns 8.8.8.8")
+	ast.Nil(err)
+	ast.Equal("dns 8.8.8.8 [美国 Google] ", ret)
+
+	buf := &bytes.Buffer{}
+	ast.Nil(client.Dump(buf, []string{filepath.Join(dir, "test.txt")}, nil))
+	ast.Contains(buf.String(), "8.0.0.0/8\t美国,,,Google\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cancelled := filepath.Join(dir, "cancelled.ipdb")
+	ast.Equal(context.Canceled, client.PackContext(ctx, []string{filepath.Join(dir, "test.txt")}, nil, cancelled, ""))
+	entries, err := os.ReadDir(dir)
+	ast.Nil(err)
+	ast.Len(entries, 1)
+
+	output := filepath.Join(dir, "test.ipdb")
+	ast.Nil(client.Pack([]string{filepath.Join(dir, "test.txt")}, nil, output, ""))
+	ast.Nil(client.Close())
+
+	client, err = New(Config{
+		IPv4File:   []string{output},
+		OutputType: OutputTypeJSON,
+	})
+	ast.Nil(err)
+	ret, err = client.Query("8.8.8.8")
+	ast.Nil(err)
+	ast.True(strings.HasPrefix(ret, `{"items":[{"ip":"8.8.8.8","net":"8.0.0.0/8"`), ret)
+
+	_, err = New(Config{IPv4File: []string{"a", "b"}, IPv4Format: []string{"plain"}})
+	ast.NotNil(err)
+}
+
+func TestClientFieldAlias(t *testing.T) {
+	ast := assert.New(t)
+
+	dir := t.TempDir()
+	data := strings.Replace(testPlainData, `["country","province","city","isp"]`, `["nation","prov","town","carrier"]`, 1)
+	ast.Nil(os.WriteFile(filepath.Join(dir, "test.txt"), []byte(data), 0644))
+	aliasFile := filepath.Join(dir, "alias.txt")
+	ast.Nil(os.WriteFile(aliasFile, []byte("# test\nplain\tnation=country\ntest.txt\tcarrier=isp\nother.txt\tprov=isp\n"), 0644))
+
+	client, err := New(Config{
+		Dir:            dir,
+		IPv4File:       []string{"test.txt"},
+		Fields:         "country,isp",
+		FieldAliasFile: aliasFile,
+	})
+	ast.Nil(err)
+	defer client.Close()
+
+	info, err := client.Find(net.ParseIP("8.8.8.8"))
+	ast.Nil(err)
+	ast.Equal([]string{"美国", "Google"}, info.Values())
+	ast.Equal(map[string]string{"country": "美国", "isp": "Google"}, info.Output(false).Data)
+
+	// aliases are carried into writers
+	output := filepath.Join(dir, "test.ipdb")
+	ast.Nil(client.Pack([]string{filepath.Join(dir, "test.txt")}, nil, output, ""))
+	meta, err := client.Info([]string{output}, nil)
+	ast.Nil(err)
+	ast.Equal([]string{"country_name", "prov", "town", "isp_domain"}, meta.Fields)
+
+	// invalid common field
+	ast.Nil(os.WriteFile(aliasFile, []byte("plain\tnation=nation\n"), 0644))
+	_, err = client.Info([]string{filepath.Join(dir, "test.txt")}, nil)
+	ast.NotNil(err)
+}
+
+func TestFormats(t *testing.T) {
+	ast := assert.New(t)
+
+	find := func(name, ext string) *ipsformat.Descriptor {
+		for _, d := range Formats() {
+			if d.Name == name && (ext == "" || len(d.Exts) > 0 && d.Exts[0] == ext) {
+				return &d
+			}
+		}
+		return nil
+	}
+
+	qqwry := find("qqwry", "")
+	ast.NotNil(qqwry)
+	ast.True(qqwry.Read)
+	ast.False(qqwry.Write)
+	ast.Equal([]string{".dat"}, qqwry.Exts)
+	ast.Equal(model.IPv4, qqwry.IPVersion)
+	ast.Equal("country", qqwry.FieldAlias[model.Country])
+	ast.NotEmpty(qqwry.ReaderOptions)
+
+	mmdb := find("mmdb", "")
+	ast.NotNil(mmdb)
+	ast.True(mmdb.Read && mmdb.Write)
+	ast.Zero(mmdb.IPVersion)
+	ast.NotEmpty(mmdb.WriterOptions)
+
+	// formats registered at runtime
+	newReader := func(file string) (ipsformat.Reader, error) { return nil, errors.ErrUnsupportedFormat }
+	t.Cleanup(func() {
+		delete(ipsformat.ReaderFormats, "test")
+		delete(ipsformat.ReaderExts, ".test")
+		delete(ipsformat.Descriptors, "test")
+	})
+	ipsformat.RegisterReaderFormat("test", newReader)
+	ipsformat.RegisterReaderExt(".test", newReader)
+	test := find("test", "")
+	ast.NotNil(test)
+	ast.True(test.Read)
+	ast.Empty(test.Exts)
+	unnamed := find("", ".test")
+	ast.NotNil(unnamed)
+	ast.True(unnamed.Read)
+
+	ipsformat.RegisterDescriptor(ipsformat.Descriptor{Name: "test", Exts: []string{".test"}, IPVersion: model.IPv6})
+	test = find("test", "")
+	ast.Equal([]string{".test"}, test.Exts)
+	ast.Equal(model.IPv6, test.IPVersion)
+	ast.Nil(find("", ".test"))
+
+	// descriptors are copies of the registered formats
+	qqwry.FieldAlias[model.Country] = "test"
+	qqwry.Fields[0] = "test"
+	qqwry = find("qqwry", "")
+	ast.Equal("country", qqwry.FieldAlias[model.Country])
+	ast.NotEqual("test", qqwry.Fields[0])
+}
@@ -46,16 +46,50 @@ const (
 
 	// ChinaAdminCode 中国行政区划代码
 	ChinaAdminCode = "chinaAdminCode"
+
+	// CountryCode 国家代码 (ISO 3166-1 alpha-2)
+	CountryCode = "countryCode"
+
+	// ProvinceCode 省份代码 (ISO 3166-2)
+	ProvinceCode = "provinceCode"
+
+	// TimeZone 时区 (IANA)
+	TimeZone = "timezone"
+
+	// PostalCode 邮政编码
+	PostalCode = "postalCode"
+
+	// AccuracyRadius 定位精度半径
+	AccuracyRadius = "accuracyRadius"
+
+	// ASOrganization 自治域所属组织
+	ASOrganization = "asOrganization"
+
+	// ConnectionType 连接类型
+	ConnectionType = "connectionType"
+
+	// Domain 域名
+	Domain = "domain"
+
+	// UsageType 使用类型
+	UsageType = "usageType"
 )
 
+// CommonFields lists the common fields.
+var CommonFields = []string{
+	Country, Province, City, ISP, ASN, Continent, UTCOffset, Latitude, Longitude, ChinaAdminCode,
+	CountryCode, ProvinceCode, TimeZone, PostalCode, AccuracyRadius, ASOrganization, ConnectionType, Domain, UsageType,
+}
+
+// DerivedFields lists the common fields that can be derived from other fields
+// when the database lacks them, see operate.FieldDeriver.
+var DerivedFields = []string{CountryCode, ProvinceCode, TimeZone}
+
 // ConvertToDBFields converts field names from a reader database's alias to a writer database's alias.
 func ConvertToDBFields(fields []string, readerFieldAlias, writerFieldAlias map[string]string) []string {
 
 	// Create a reverse mapping for the reader's alias for quick lookup.
-	readerFieldAliasReverse := make(map[string]string)
-	for commonField, dbField := range readerFieldAlias {
-		readerFieldAliasReverse[dbField] = commonField
-	}
+	readerFieldAliasReverse := ReverseFieldAlias(readerFieldAlias)
 
 	// Iterate through the fields and perform the conversion.
 	convertedFields := make([]string, len(fields))
@@ -73,3 +107,28 @@ func ConvertToDBFields(fields []string, readerFieldAlias, writerFieldAlias map[s
 
 	return convertedFields
 }
+
+// ReverseFieldAlias maps the database fields of fieldAlias to their common fields.
+// If several common fields are aliases of the same database field, the first of them in CommonFields is picked,
+// or the first by name if none of them is in CommonFields, so the result does not depend on the order of the map.
+func ReverseFieldAlias(fieldAlias map[string]string) map[string]string {
+	rank := func(field string) int {
+		for i, commonField := range CommonFields {
+			if commonField == field {
+				return i
+			}
+		}
+		return len(CommonFields)
+	}
+
+	ret := make(map[string]string, len(fieldAlias))
+	for commonField, dbField := range fieldAlias {
+		if picked, ok := ret[dbField]; ok {
+			if r, p := rank(commonField), rank(picked); r > p || (r == p && commonField > picked) {
+				continue
+			}
+		}
+		ret[dbField] = commonField
+	}
+	return ret
+}
@@ -17,6 +17,7 @@
 package model
 
 import (
+	"fmt"
 	"net"
 	"strings"
 
@@ -35,6 +36,11 @@ type IPInfo struct {
 	// Data holds the actual information related to the IP in a key-value format.
 	Data map[string]string
 
+	// Typed holds the typed values of the fields in Data that are not plain strings.
+	// A typed value is only used while Data still holds its string view,
+	// so fields rewritten as strings fall back to string values.
+	Typed map[string]Value
+
 	// FieldAlias maps common field names to their corresponding database field names.
 	FieldAlias map[string]string
 
@@ -44,6 +50,31 @@ type IPInfo struct {
 	// ReplaceFields specifies fields that should be replaced with specific values.
 	// operate.FieldSelector use this to replace the value of the field.
 	ReplaceFields map[string]string
+
+	// NotFound reports that the database has no data for IP, IPNet is then the unknown range containing it.
+	// Find returns errors.NotFoundError instead, batch lookups mark the result with NotFound.
+	NotFound bool
+
+	// Sources maps fields to the databases their values come from, set by readers combining several databases.
+	Sources map[string]Source
+}
+
+// Source describes the database a value comes from.
+type Source struct {
+
+	// Index is the position of the database among the combined databases.
+	Index int `json:"index"`
+
+	// Format is the format of the database.
+	Format string `json:"format"`
+
+	// File is the file name of the database, if known.
+	File string `json:"file,omitempty"`
+}
+
+// String returns the source as "<index>:<format>:<file>".
+func (s Source) String() string {
+	return fmt.Sprintf("%d:%s:%s", s.Index, s.Format, s.File)
 }
 
 // GetData retrieves the data for the given field.
@@ -62,6 +93,47 @@ func (i *IPInfo) GetData(field string) (string, bool) {
 	return "", false
 }
 
+// SetValue sets the value of a field, Data holds its string view.
+func (i *IPInfo) SetValue(field string, v Value) {
+	if i.Data == nil {
+		i.Data = make(map[string]string)
+	}
+	i.Data[field] = v.String()
+
+	if v.Type() == TypeString {
+		delete(i.Typed, field)
+		return
+	}
+	if i.Typed == nil {
+		i.Typed = make(map[string]Value)
+	}
+	i.Typed[field] = v
+}
+
+// GetValue retrieves the typed value for the given field like GetData.
+// Fields without a typed value are returned as string values.
+func (i *IPInfo) GetValue(field string) (Value, bool) {
+	str, ok := i.GetData(field)
+	if !ok {
+		return Value{}, false
+	}
+	return i.value(field, str), true
+}
+
+// value returns the typed value of the field if its string view is str, or a string value of str.
+func (i *IPInfo) value(field, str string) Value {
+	v, ok := i.Typed[field]
+	if !ok {
+		if dbField, ok2 := i.FieldAlias[field]; ok2 {
+			v, ok = i.Typed[dbField]
+		}
+	}
+	if ok && v.String() == str {
+		return v
+	}
+	return StringValue(str)
+}
+
 // AddCommonFieldAlias adds common field aliases to the FieldAlias map.
 // It maps a database-specific field name to a common field name.
 func (i *IPInfo) AddCommonFieldAlias(fieldAlias map[string]string) {
@@ -89,45 +161,82 @@ func (i *IPInfo) Values() []string {
 	return ret
 }
 
+// TypedValues is like Values but returns typed values.
+func (i *IPInfo) TypedValues() []Value {
+	values := i.Values()
+	ret := make([]Value, len(values))
+	for index, field := range i.Fields {
+		ret[index] = i.value(field, values[index])
+	}
+	return ret
+}
+
 // IPInfoOutput represents the structure for outputting IP information.
 type IPInfoOutput struct {
 	IP   string            `json:"ip"`
 	Net  string            `json:"net"`
 	Data map[string]string `json:"data"`
+
+	// TypedData holds the values of the fields in Data that are not plain strings,
+	// such as numbers, booleans and lists, with their JSON types.
+	TypedData map[string]interface{} `json:"typed_data,omitempty"`
+
+	// NotFound is set if the database has no data for the IP.
+	NotFound bool `json:"not_found,omitempty"`
+
+	// Sources maps the fields in Data to the databases their values come from.
+	Sources map[string]Source `json:"sources,omitempty"`
 }
 
 // Output constructs and returns an IPInfoOutput based on the current IPInfo.
 // It decides whether to use the database field or the common field based on the dbFiled flag.
 func (i *IPInfo) Output(dbFiled bool) *IPInfoOutput {
 	data := make(map[string]string, len(i.Fields))
-	values := i.Values()
+	values := i.TypedValues()
 
-	fieldAliasReverse := make(map[string]string, len(i.FieldAlias))
-	for commonField, dbField := range i.FieldAlias {
-		fieldAliasReverse[dbField] = commonField
-	}
+	fieldAliasReverse := ReverseFieldAlias(i.FieldAlias)
+	var typedData map[string]interface{}
+	var sources map[string]Source
 	for index, field := range i.Fields {
-		if dbFiled {
-			data[field] = values[index]
-			continue
+		name := field
+		if commonField, ok := fieldAliasReverse[field]; ok && !dbFiled {
+			name = commonField
 		}
+		data[name] = values[index].String()
 
-		if commonField, ok := fieldAliasReverse[field]; ok {
-			data[commonField] = values[index]
-			continue
+		if values[index].Type() != TypeString {
+			if typedData == nil {
+				typedData = make(map[string]interface{})
+			}
+			typedData[name] = values[index].Interface()
+		}
+
+		if source, ok := i.Sources[field]; ok {
+			if sources == nil {
+				sources = make(map[string]Source)
+			}
+			sources[name] = source
 		}
-		data[field] = values[index]
 	}
 
 	ipNet := ""
-	ipNets := i.IPNet.IPNets()
-	if len(ipNets) > 0 {
-		ipNet = ipNets[0].String()
+	if i.IPNet != nil {
+		if ipNets := i.IPNet.IPNets(); len(ipNets) > 0 {
+			ipNet = ipNets[0].String()
+		}
 	}
 
 	return &IPInfoOutput{
-		IP:   i.IP.String(),
-		Net:  ipNet,
-		Data: data,
+		IP:        i.IP.String(),
+		Net:       ipNet,
+		Data:      data,
+		TypedData: typedData,
+		NotFound:  i.NotFound,
+		Sources:   sources,
 	}
 }
+
+// IPInfoSeq is an iterator over IP information, shaped like iter.Seq2[*IPInfo, error].
+// The iterator calls yield for each item in order, and stops early when yield returns false.
+// An error is reported with a nil IPInfo and ends the iteration.
+type IPInfoSeq = func(yield func(*IPInfo, error) bool)
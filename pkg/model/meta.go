@@ -16,6 +16,13 @@
 
 package model
 
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
 const (
 	// IPv4 represents the version for IPv4 IP database.
 	IPv4 = 1
@@ -44,6 +51,90 @@ type Meta struct {
 
 	// FieldAlias maps common field names to database-specific field names.
 	FieldAlias map[string]string
+
+	// BuildTime is the time the IP database was built, zero if unknown.
+	// It is omitted from JSON if unknown.
+	BuildTime time.Time
+
+	// Version is the version string of the IP database.
+	Version string `json:",omitempty"`
+
+	// Description describes the IP database.
+	Description string `json:",omitempty"`
+
+	// Languages lists the languages of the data in the IP database.
+	Languages []string `json:",omitempty"`
+
+	// Source names the provider of the data.
+	Source string `json:",omitempty"`
+
+	// License is the license of the data.
+	License string `json:",omitempty"`
+}
+
+// MarshalJSON encodes the metadata as JSON, omitting BuildTime if it is unknown.
+func (m Meta) MarshalJSON() ([]byte, error) {
+	type meta Meta
+	ret := struct {
+		meta
+		BuildTime *time.Time `json:",omitempty"`
+	}{meta: meta(m)}
+	if !m.BuildTime.IsZero() {
+		ret.BuildTime = &m.BuildTime
+	}
+	return json.Marshal(ret)
+}
+
+// CopyInfo copies the descriptive information (build time, version, description,
+// languages, source and license) of src to the metadata.
+func (m *Meta) CopyInfo(src *Meta) {
+	m.BuildTime = src.BuildTime
+	m.Version = src.Version
+	m.Description = src.Description
+	m.Languages = src.Languages
+	m.Source = src.Source
+	m.License = src.License
+}
+
+// MergeInfo merges the descriptive information of src into the metadata.
+// The newest build time is kept, distinct version, description, source and license
+// strings are joined with " | ", and languages are merged.
+func (m *Meta) MergeInfo(src *Meta) {
+	if src.BuildTime.After(m.BuildTime) {
+		m.BuildTime = src.BuildTime
+	}
+	m.Version = mergeInfoString(m.Version, src.Version)
+	m.Description = mergeInfoString(m.Description, src.Description)
+	m.Source = mergeInfoString(m.Source, src.Source)
+	m.License = mergeInfoString(m.License, src.License)
+	for _, lang := range src.Languages {
+		found := false
+		for _, l := range m.Languages {
+			if l == lang {
+				found = true
+				break
+			}
+		}
+		if !found {
+			m.Languages = append(m.Languages, lang)
+		}
+	}
+}
+
+// mergeInfoString appends s to the " | " separated list dst if it is not already there.
+func mergeInfoString(dst, s string) string {
+	if len(s) == 0 {
+		return dst
+	}
+	if len(dst) == 0 {
+		return s
+	}
+	for _, part := range strings.Split(dst, " | ") {
+		if part == s {
+			return dst
+		}
+	}
+	return dst + " | " + s
 }
 
 // IsIPv4Support checks if the metadata supports IPv4.
@@ -58,6 +149,7 @@ func (m *Meta) IsIPv6Support() bool {
 
 // AddCommonFieldAlias adds aliases for common fields to the metadata.
 // It checks if the provided database field exists in the metadata's fields.
+// Several common fields may be aliases of the same database field.
 func (m *Meta) AddCommonFieldAlias(fieldAlias map[string]string) {
 	if m.FieldAlias == nil {
 		m.FieldAlias = make(map[string]string)
@@ -90,3 +182,25 @@ func (m *Meta) SupportFields() map[string]bool {
 
 	return fields
 }
+
+// PickDescription picks a description from descriptions by language, preferring English,
+// and falls back to def if there is none.
+func PickDescription(descriptions map[string]string, def string) string {
+	for _, lang := range []string{"en", "zh-CN"} {
+		if desc := descriptions[lang]; desc != "" {
+			return desc
+		}
+	}
+
+	langs := make([]string, 0, len(descriptions))
+	for lang := range descriptions {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		if desc := descriptions[lang]; desc != "" {
+			return desc
+		}
+	}
+	return def
+}
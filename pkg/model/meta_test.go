@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddCommonFieldAlias(t *testing.T) {
+	ast := assert.New(t)
+
+	// two common fields on one database field
+	for i := 0; i < 20; i++ {
+		meta := &Meta{Fields: []string{"country", "owner"}, FieldAlias: map[string]string{ASOrganization: "owner"}}
+		meta.AddCommonFieldAlias(map[string]string{ISP: "owner", "unknown": "missing"})
+		ast.Equal(map[string]string{ASOrganization: "owner", ISP: "owner"}, meta.FieldAlias)
+		ast.True(meta.SupportFields()[ISP])
+		ast.True(meta.SupportFields()[ASOrganization])
+
+		info := &IPInfo{Data: map[string]string{"owner": "x"}, Fields: []string{"owner"}}
+		info.AddCommonFieldAlias(meta.FieldAlias)
+		v1, ok1 := info.GetData(ISP)
+		v2, ok2 := info.GetData(ASOrganization)
+		ast.True(ok1 && ok2)
+		ast.Equal("x", v1)
+		ast.Equal("x", v2)
+
+		ast.Equal(map[string]string{"owner": ISP}, ReverseFieldAlias(meta.FieldAlias))
+		ast.Equal([]string{"country", "isp_domain"}, ConvertToDBFields(meta.Fields, meta.FieldAlias, map[string]string{ISP: "isp_domain"}))
+		ast.Equal(map[string]string{ISP: "x"}, info.Output(false).Data)
+	}
+
+	ast.Equal(map[string]string{"c": "a"}, ReverseFieldAlias(map[string]string{"b": "c", "a": "c"}))
+}
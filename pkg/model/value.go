@@ -0,0 +1,236 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ValueType is the type of a field value.
+type ValueType uint8
+
+const (
+	TypeString ValueType = iota
+	TypeInt
+	TypeFloat
+	TypeBool
+	TypeList
+	TypeMap
+)
+
+// Value is a typed field value.
+// Besides the typed data, a Value has a string view, which is what IPInfo.Data holds for the field,
+// so code that works with strings keeps working with typed data.
+type Value struct {
+	typ ValueType
+	str string
+	raw interface{} // int64, float64, bool, []Value or map[string]Value
+}
+
+// StringValue returns a string Value.
+func StringValue(s string) Value {
+	return Value{typ: TypeString, str: s}
+}
+
+// IntValue returns an integer Value.
+func IntValue(i int64) Value {
+	return Value{typ: TypeInt, str: strconv.FormatInt(i, 10), raw: i}
+}
+
+// FloatValue returns a float Value.
+func FloatValue(f float64) Value {
+	return Value{typ: TypeFloat, str: strconv.FormatFloat(f, 'f', -1, 64), raw: f}
+}
+
+// BoolValue returns a bool Value.
+func BoolValue(b bool) Value {
+	return Value{typ: TypeBool, str: strconv.FormatBool(b), raw: b}
+}
+
+// ListValue returns a list Value, its string view joins the string views of the items with commas.
+func ListValue(list []Value) Value {
+	views := make([]string, len(list))
+	for i := range list {
+		views[i] = list[i].str
+	}
+	return Value{typ: TypeList, str: strings.Join(views, ","), raw: list}
+}
+
+// MapValue returns a map Value, its string view is the JSON object of the string views of the items.
+func MapValue(m map[string]Value) Value {
+	views := make(map[string]string, len(m))
+	for k, v := range m {
+		views[k] = v.str
+	}
+	str, _ := json.Marshal(views)
+	return Value{typ: TypeMap, str: string(str), raw: m}
+}
+
+// NewValue converts a Go value into a Value. It supports strings and byte slices, integers, floats,
+// bools, and slices and string-keyed maps of them. Other values are converted to strings with fmt.
+func NewValue(v interface{}) Value {
+	if v == nil {
+		return StringValue("")
+	}
+	switch v := v.(type) {
+	case Value:
+		return v
+	case string:
+		return StringValue(v)
+	case []byte:
+		return StringValue(string(v))
+	}
+
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if val.IsNil() {
+			return StringValue("")
+		}
+		return NewValue(val.Elem().Interface())
+	case reflect.String:
+		return StringValue(val.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return IntValue(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if val.Uint() > math.MaxInt64 {
+			return StringValue(strconv.FormatUint(val.Uint(), 10))
+		}
+		return IntValue(int64(val.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return FloatValue(val.Float())
+	case reflect.Bool:
+		return BoolValue(val.Bool())
+	case reflect.Slice, reflect.Array:
+		list := make([]Value, val.Len())
+		for i := range list {
+			list[i] = NewValue(val.Index(i).Interface())
+		}
+		return ListValue(list)
+	case reflect.Map:
+		if val.Type().Key().Kind() == reflect.String {
+			m := make(map[string]Value, val.Len())
+			for _, key := range val.MapKeys() {
+				m[key.String()] = NewValue(val.MapIndex(key).Interface())
+			}
+			return MapValue(m)
+		}
+	}
+
+	return StringValue(fmt.Sprint(v))
+}
+
+// WithString returns a copy of the Value with another string view,
+// for data sources that format their values in a particular way.
+func (v Value) WithString(s string) Value {
+	v.str = s
+	return v
+}
+
+// Type returns the type of the Value.
+func (v Value) Type() ValueType {
+	return v.typ
+}
+
+// String returns the string view of the Value.
+func (v Value) String() string {
+	return v.str
+}
+
+// Int returns the Value as an integer, string values are parsed.
+func (v Value) Int() (int64, bool) {
+	switch v.typ {
+	case TypeInt:
+		return v.raw.(int64), true
+	case TypeString:
+		i, err := strconv.ParseInt(v.str, 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// Float returns the Value as a float, integer values are converted and string values are parsed.
+func (v Value) Float() (float64, bool) {
+	switch v.typ {
+	case TypeFloat:
+		return v.raw.(float64), true
+	case TypeInt:
+		return float64(v.raw.(int64)), true
+	case TypeString:
+		f, err := strconv.ParseFloat(v.str, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// Bool returns the Value as a bool, string values are parsed.
+func (v Value) Bool() (bool, bool) {
+	switch v.typ {
+	case TypeBool:
+		return v.raw.(bool), true
+	case TypeString:
+		b, err := strconv.ParseBool(v.str)
+		return b, err == nil
+	}
+	return false, false
+}
+
+// List returns the items of a list Value.
+func (v Value) List() ([]Value, bool) {
+	list, ok := v.raw.([]Value)
+	return list, ok
+}
+
+// Map returns the items of a map Value.
+func (v Value) Map() (map[string]Value, bool) {
+	m, ok := v.raw.(map[string]Value)
+	return m, ok
+}
+
+// Interface returns the Value as a plain Go value:
+// string, int64, float64, bool, []interface{} or map[string]interface{}.
+func (v Value) Interface() interface{} {
+	switch v.typ {
+	case TypeList:
+		list := v.raw.([]Value)
+		ret := make([]interface{}, len(list))
+		for i := range list {
+			ret[i] = list[i].Interface()
+		}
+		return ret
+	case TypeMap:
+		m := v.raw.(map[string]Value)
+		ret := make(map[string]interface{}, len(m))
+		for k := range m {
+			ret[k] = m[k].Interface()
+		}
+		return ret
+	case TypeString:
+		return v.str
+	}
+	return v.raw
+}
+
+// MarshalJSON encodes the Value as its plain Go value.
+func (v Value) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Interface())
+}